@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"adb-kit-go/pkg/adb"
+)
+
+func main() {
+	var rootCmd = &cobra.Command{Use: "adbkit"}
+	rootCmd.PersistentFlags().StringP("host", "H", "", "ADB server host")
+	rootCmd.PersistentFlags().IntP("port", "P", 5037, "ADB server port")
+	rootCmd.PersistentFlags().String("select", "", "select devices by query, e.g. 'sdk>=30 && model~\"Pixel\"'")
+
+	newClient := func(cmd *cobra.Command) *adb.Client {
+		host, _ := cmd.Flags().GetString("host")
+		options := &adb.Options{Host: host}
+		// 只有显式指定-P时才覆盖ADB_SERVER_SOCKET
+		if cmd.Flags().Changed("port") {
+			options.Port, _ = cmd.Flags().GetInt("port")
+		}
+		return adb.NewClient(options)
+	}
+
+	var pubkeyConvertCmd = &cobra.Command{
+		Use:   "pubkey-convert <file>",
+		Short: "Converts an ADB-generated public key into PEM format.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, _ := cmd.Flags().GetString("format")
+			key, err := readPublicKey(args[0])
+			if err != nil {
+				log.Fatalf("解析公钥失败: %v", err)
+			}
+
+			switch format {
+			case "pem":
+				encoded, err := adb.PublicKeyToPem(key.PublicKey)
+				if err != nil {
+					log.Fatalf("编码公钥失败: %v", err)
+				}
+				fmt.Print(encoded)
+			case "openssh":
+				fmt.Println(adb.PublicKeyToOpenSSH(key.PublicKey, "adbkey"))
+			default:
+				log.Fatalf("不支持的格式 '%s'", format)
+			}
+		},
+	}
+	pubkeyConvertCmd.Flags().StringP("format", "f", "pem", "format (pem or openssh)")
+
+	var pubkeyFingerprintCmd = &cobra.Command{
+		Use:   "pubkey-fingerprint <file>",
+		Short: "Outputs the fingerprint of an ADB-generated public key.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			key, err := readPublicKey(args[0])
+			if err != nil {
+				log.Fatalf("解析公钥失败: %v", err)
+			}
+			fmt.Printf("%s %s\n", key.Fingerprint, key.Comment)
+		},
+	}
+
+	var devicesCmd = &cobra.Command{
+		Use:   "devices",
+		Short: "Lists devices, optionally filtered by --select.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			query, _ := cmd.Flags().GetString("select")
+			devices, err := newClient(cmd).SelectDevicesContext(cmd.Context(), query)
+			if err != nil {
+				log.Fatalf("列出设备失败: %v", err)
+			}
+
+			for _, device := range devices {
+				keys := make([]string, 0, len(device.Props))
+				for key := range device.Props {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+
+				fields := []string{device.ID, device.State}
+				if device.Path != "" {
+					fields = append(fields, device.Path)
+				}
+				for _, field := range []struct{ key, value string }{
+					{"product", device.Product},
+					{"model", device.Model},
+					{"device", device.DeviceName},
+				} {
+					if field.value != "" {
+						fields = append(fields, field.key+":"+field.value)
+					}
+				}
+				if device.TransportID != 0 {
+					fields = append(fields, fmt.Sprintf("transport_id:%d", device.TransportID))
+				}
+				for _, key := range keys {
+					fields = append(fields, key+":"+device.Props[key])
+				}
+				fmt.Println(strings.Join(fields, "\t"))
+			}
+		},
+	}
+
+	var shellCmd = &cobra.Command{
+		Use:   "shell [command]...",
+		Short: "Runs a shell command on every device matching --select, or an interactive shell without a command.",
+		Run: func(cmd *cobra.Command, args []string) {
+			query, _ := cmd.Flags().GetString("select")
+			tty, _ := cmd.Flags().GetBool("tty")
+			if tty || len(args) == 0 {
+				exitCode, err := interactiveShell(cmd.Context(), newClient(cmd), query, strings.Join(args, " "))
+				if err != nil {
+					log.Fatalf("打开交互式shell失败: %v", err)
+				}
+				os.Exit(exitCode)
+			}
+
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+
+			results, err := newClient(cmd).ShellAllContext(cmd.Context(), &adb.FanOutOptions{
+				Concurrency: concurrency,
+				Timeout:     timeout,
+				Query:       query,
+			}, strings.Join(args, " "))
+			if err != nil {
+				log.Fatalf("执行命令失败: %v", err)
+			}
+
+			for _, serial := range results.Serials() {
+				result := results[serial]
+				if result.Err != nil {
+					fmt.Fprintf(os.Stderr, "[%s] error: %v\n", serial, result.Err)
+					continue
+				}
+				for _, line := range strings.Split(strings.TrimRight(result.Value.Output, "\n"), "\n") {
+					fmt.Printf("[%s] %s\n", serial, line)
+				}
+			}
+			if len(results.Failed()) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	shellCmd.Flags().IntP("concurrency", "j", 0, "maximum number of devices to run on at once (0 = unlimited)")
+	shellCmd.Flags().Duration("timeout", 0, "per-device timeout (0 = none)")
+	shellCmd.Flags().BoolP("tty", "t", false, "run the command in a PTY on the single device matching --select")
+	shellCmd.Flags().SetInterspersed(false)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rootCmd.AddCommand(pubkeyConvertCmd, pubkeyFingerprintCmd, devicesCmd, shellCmd)
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		os.Exit(1)
+	}
+}
+
+// readPublicKey 读取并解析adbkey.pub文件
+func readPublicKey(file string) (*adb.ExtendedRSAPublicKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return adb.ParsePublicKeyInfo(data)
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+// notifyResize Windows没有SIGWINCH，不跟踪窗口大小变化
+func notifyResize(fn func()) func() {
+	return func() {}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+
+	"adb-kit-go/pkg/adb"
+)
+
+// interactiveShell 在唯一匹配query的设备上打开PTY shell，本地终端切换到原始模式
+// 返回设备上命令的退出码，退出码未知时返回0
+func interactiveShell(ctx context.Context, client *adb.Client, query string, command string) (int, error) {
+	devices, err := client.SelectDevicesContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	var serials []string
+	for _, device := range devices {
+		if device.State == "device" {
+			serials = append(serials, device.ID)
+		}
+	}
+	switch len(serials) {
+	case 0:
+		return 0, fmt.Errorf("没有可用的设备")
+	case 1:
+	default:
+		return 0, fmt.Errorf("匹配到%d台设备，请使用--select选择一台", len(serials))
+	}
+	serial := serials[0]
+
+	options := &adb.PtyOptions{Term: os.Getenv("TERM")}
+	stdin := int(os.Stdin.Fd())
+	if term.IsTerminal(stdin) {
+		if cols, rows, err := term.GetSize(stdin); err == nil {
+			options.Rows, options.Cols = rows, cols
+		}
+	}
+
+	stream, err := client.OpenPtyShellContext(ctx, serial, command, options)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+
+	if term.IsTerminal(stdin) {
+		state, err := term.MakeRaw(stdin)
+		if err != nil {
+			return 0, fmt.Errorf("切换终端到原始模式失败: %w", err)
+		}
+		defer term.Restore(stdin, state)
+
+		stopResize := notifyResize(func() {
+			if cols, rows, err := term.GetSize(stdin); err == nil {
+				stream.Resize(rows, cols, 0, 0)
+			}
+		})
+		defer stopResize()
+	}
+
+	go func() {
+		// 原始模式下Ctrl-C等控制字符作为输入发送给设备，由设备的终端处理
+		if _, err := io.Copy(stream, os.Stdin); err == nil {
+			stream.CloseStdin()
+		}
+	}()
+	go io.Copy(os.Stderr, stream.Stderr())
+
+	if _, err := io.Copy(os.Stdout, stream.Stdout()); err != nil {
+		return 0, err
+	}
+	exitCode, err := stream.Wait()
+	if err != nil {
+		return 0, err
+	}
+	if exitCode == adb.ExitCodeUnknown {
+		exitCode = 0
+	}
+	return exitCode, nil
+}
@@ -1,24 +1,19 @@
 package adb
 
-// 导出主要的类型和函数
-type Client struct {
-	host string
-	port int
-}
+import (
+	adbkit "adb-kit-go/pkg/adb"
+)
+
+// Client 导出主要的客户端类型
+type Client = adbkit.Client
 
 // NewClient 创建一个新的 ADB 客户端实例
-// 这个函数作为包的主要入口点
+// 这个函数作为包的主要入口点，host 和 port 指定 ADB 服务器地址
 func NewClient(host string, port int) *Client {
-	if host == "" {
-		host = "localhost"
-	}
-	if port == 0 {
-		port = 5037
-	}
-	return &Client{
-		host: host,
-		port: port,
-	}
+	return adbkit.NewClient(&adbkit.Options{
+		Host: host,
+		Port: port,
+	})
 }
 
 // CreateClient 是 NewClient 的别名，保持与原始 API 的兼容性
@@ -5,9 +5,11 @@ go 1.23.3
 require (
 	github.com/nanxin/gadb v0.0.19
 	github.com/spf13/cobra v1.8.1
+	golang.org/x/term v0.27.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 )
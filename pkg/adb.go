@@ -1,4 +1,7 @@
-package pkg
+//go:build ignore
+
+// 从AOSP的KeyEvent.java生成按键码列表，用法: go run pkg/adb.go
+package main
 
 import (
 	"fmt"
@@ -0,0 +1,146 @@
+// Package adberr 定义ADB客户端的错误类型
+// 调用方通过 errors.Is / errors.As 判断错误种类，不需要匹配服务器返回的文本
+package adberr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// 哨兵错误
+var (
+	// ErrDeviceNotFound 设备不存在或没有连接任何设备
+	ErrDeviceNotFound = errors.New("device not found")
+	// ErrDeviceOffline 设备离线
+	ErrDeviceOffline = errors.New("device offline")
+	// ErrDeviceUnauthorized 设备未授权当前主机调试
+	ErrDeviceUnauthorized = errors.New("device unauthorized")
+	// ErrMoreThanOneDevice 连接了多个设备，需要指定目标设备
+	ErrMoreThanOneDevice = errors.New("more than one device")
+	// ErrServerUnreachable 无法连接ADB服务器
+	ErrServerUnreachable = errors.New("adb server unreachable")
+	// ErrPermissionDenied 权限不足
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrUnknownService 服务器或设备不支持请求的服务
+	ErrUnknownService = errors.New("unknown service")
+	// ErrInstallFailed 应用安装失败，具体原因见 InstallError.Code
+	ErrInstallFailed = errors.New("install failed")
+)
+
+// 常见的安装失败代码，对应 pm install 输出的 Failure [CODE]
+const (
+	InstallFailedAlreadyExists          = "INSTALL_FAILED_ALREADY_EXISTS"
+	InstallFailedInvalidApk             = "INSTALL_FAILED_INVALID_APK"
+	InstallFailedInvalidUri             = "INSTALL_FAILED_INVALID_URI"
+	InstallFailedInsufficientStorage    = "INSTALL_FAILED_INSUFFICIENT_STORAGE"
+	InstallFailedDuplicatePackage       = "INSTALL_FAILED_DUPLICATE_PACKAGE"
+	InstallFailedUpdateIncompatible     = "INSTALL_FAILED_UPDATE_INCOMPATIBLE"
+	InstallFailedVersionDowngrade       = "INSTALL_FAILED_VERSION_DOWNGRADE"
+	InstallFailedOlderSdk               = "INSTALL_FAILED_OLDER_SDK"
+	InstallFailedNoMatchingAbis         = "INSTALL_FAILED_NO_MATCHING_ABIS"
+	InstallFailedTestOnly               = "INSTALL_FAILED_TEST_ONLY"
+	InstallFailedUserRestricted         = "INSTALL_FAILED_USER_RESTRICTED"
+	InstallFailedVerificationFailure    = "INSTALL_FAILED_VERIFICATION_FAILURE"
+	InstallParseFailedNoCertificates    = "INSTALL_PARSE_FAILED_NO_CERTIFICATES"
+	InstallParseFailedNotApk            = "INSTALL_PARSE_FAILED_NOT_APK"
+	InstallParseFailedInconsistentCerts = "INSTALL_PARSE_FAILED_INCONSISTENT_CERTIFICATES"
+)
+
+// classifyRules 服务器错误信息与哨兵错误的对应关系，按顺序匹配小写后的信息
+var classifyRules = []struct {
+	patterns []string
+	err      error
+}{
+	{[]string{"unknown host service", "unknown service"}, ErrUnknownService},
+	{[]string{"unauthorized", "still authorizing"}, ErrDeviceUnauthorized},
+	{[]string{"more than one"}, ErrMoreThanOneDevice},
+	{[]string{"offline", "still connecting"}, ErrDeviceOffline},
+	{[]string{"insufficient permissions", "permission denied", "operation not permitted"}, ErrPermissionDenied},
+	{[]string{"no such file or directory"}, fs.ErrNotExist},
+}
+
+// deviceNotFound 匹配服务器找不到设备时的完整错误信息，见adb源码中的acquire_one_transport
+// 其他包含not found的信息（如包、文件、转发规则不存在）不是设备错误
+var deviceNotFound = regexp.MustCompile(`^(device '.*' not found|device not found|no devices/emulators found|no devices found|no emulators found|no device with transport id '\d+')$`)
+
+// Classify 将服务器返回的错误信息归类为哨兵错误，无法归类时返回nil
+func Classify(message string) error {
+	lower := strings.ToLower(strings.TrimSpace(message))
+	if deviceNotFound.MatchString(lower) {
+		return ErrDeviceNotFound
+	}
+	for _, rule := range classifyRules {
+		for _, pattern := range rule.patterns {
+			if strings.Contains(lower, pattern) {
+				return rule.err
+			}
+		}
+	}
+	return nil
+}
+
+// FailError ADB服务器或设备返回的FAIL响应
+type FailError struct {
+	Message string
+}
+
+// NewFailError 创建FAIL响应错误
+func NewFailError(message string) *FailError {
+	return &FailError{Message: message}
+}
+
+func (e *FailError) Error() string {
+	return fmt.Sprintf("Failure: '%s'", e.Message)
+}
+
+// Unwrap 返回错误信息对应的哨兵错误
+func (e *FailError) Unwrap() error {
+	return Classify(e.Message)
+}
+
+// PrematureEOFError 数据未读完时流已结束
+type PrematureEOFError struct {
+	MissingBytes int
+}
+
+func (e *PrematureEOFError) Error() string {
+	return fmt.Sprintf("Premature end of stream, needed %d more bytes", e.MissingBytes)
+}
+
+// Unwrap 返回 io.ErrUnexpectedEOF
+func (e *PrematureEOFError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// UnexpectedDataError 收到了与协议不符的数据
+type UnexpectedDataError struct {
+	Unexpected string
+	Expected   string
+}
+
+func (e *UnexpectedDataError) Error() string {
+	return fmt.Sprintf("Unexpected '%s', was expecting %s", e.Unexpected, e.Expected)
+}
+
+// InstallError 应用安装失败
+type InstallError struct {
+	Apk  string
+	Code string
+}
+
+func (e *InstallError) Error() string {
+	return fmt.Sprintf("%s could not be installed [%s]", e.Apk, e.Code)
+}
+
+// Is 匹配 ErrInstallFailed，或代码相同的 *InstallError
+func (e *InstallError) Is(target error) bool {
+	if target == ErrInstallFailed {
+		return true
+	}
+	other, ok := target.(*InstallError)
+	return ok && other.Code != "" && other.Code == e.Code
+}
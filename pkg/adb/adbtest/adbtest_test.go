@@ -0,0 +1,98 @@
+package adbtest
+
+import (
+	"testing"
+
+	"adb-kit-go/pkg/adb/shellcmd"
+)
+
+func TestUnquote(t *testing.T) {
+	args := []string{"", "plain", "a b", "it's", `$HOME`, "`id`", `say "hi"`, `back\slash`, `\$`}
+	for _, dialect := range []shellcmd.Dialect{shellcmd.Modern, shellcmd.Legacy} {
+		for _, arg := range args {
+			quoted, err := dialect.Quote(arg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := unquote(quoted); got != arg {
+				t.Errorf("unquote(%s) = %q, want %q", quoted, got, arg)
+			}
+		}
+	}
+}
+
+func TestFSResolve(t *testing.T) {
+	fs := NewFS()
+	fs.WriteFile("/storage/emulated/0/x/a.txt", []byte("A"), 0o644)
+	fs.Symlink("/storage/emulated/0", "/sdcard")
+	fs.Symlink("x/a.txt", "/storage/emulated/0/link.txt")
+	fs.Symlink("../emulated/0", "/storage/emulated/self")
+
+	tests := []struct {
+		name   string
+		follow bool
+		want   string
+	}{
+		{"/sdcard", false, "/sdcard"},
+		{"/sdcard", true, "/storage/emulated/0"},
+		{"/sdcard/x/a.txt", false, "/storage/emulated/0/x/a.txt"},
+		{"sdcard/./x/../x/a.txt", false, "/storage/emulated/0/x/a.txt"},
+		{"/sdcard/link.txt", false, "/storage/emulated/0/link.txt"},
+		{"/sdcard/link.txt", true, "/storage/emulated/0/x/a.txt"},
+		{"/storage/emulated/self/x", false, "/storage/emulated/0/x"},
+	}
+
+	for _, tt := range tests {
+		if got := fs.resolve(tt.name, tt.follow); got != tt.want {
+			t.Errorf("resolve(%s, %v) = %s, want %s", tt.name, tt.follow, got, tt.want)
+		}
+	}
+}
+
+func TestFSStat(t *testing.T) {
+	fs := NewFS()
+	fs.WriteFile("/data/a.txt", []byte("hello"), 0o600)
+	fs.Symlink("/data", "/d")
+
+	tests := []struct {
+		name     string
+		follow   bool
+		wantMode uint32
+		wantSize uint32
+	}{
+		{"/data/a.txt", false, modeFile | 0o600, 5},
+		{"/data", false, modeDir | 0o755, 4096},
+		{"/d", false, modeLink | 0o777, 5},
+		{"/d", true, modeDir | 0o755, 4096},
+		{"/d/", false, modeDir | 0o755, 4096}, // 末尾的/跟随链接
+		{"/missing", false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		mode, size, _ := fs.stat(tt.name)
+		if tt.follow {
+			mode, size, _ = fs.statFollow(tt.name)
+		}
+		if mode != tt.wantMode || size != tt.wantSize {
+			t.Errorf("stat(%s, %v) = %o %d, want %o %d", tt.name, tt.follow, mode, size, tt.wantMode, tt.wantSize)
+		}
+	}
+}
+
+func TestDeviceList(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	server.AddDevice("b")
+	server.AddDevice("a").SetState("offline")
+	if got, want := server.deviceList(false), "b\tdevice\na\toffline\n"; got != want {
+		t.Errorf("deviceList = %q, want %q", got, want)
+	}
+	server.RemoveDevice("b")
+	if got, want := server.deviceList(false), "a\toffline\n"; got != want {
+		t.Errorf("deviceList after RemoveDevice = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package adbtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// Conn 假服务器上的一个客户端连接，供自定义处理函数应答请求
+type Conn struct {
+	net.Conn
+}
+
+// ReadRequest 读取带4字节十六进制长度前缀的请求
+func (c *Conn) ReadRequest() (string, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c, header); err != nil {
+		return "", err
+	}
+	length, err := strconv.ParseUint(string(header), 16, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid length %q", header)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c, payload); err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// Okay 发送OKAY
+func (c *Conn) Okay() error {
+	_, err := io.WriteString(c, "OKAY")
+	return err
+}
+
+// Fail 发送FAIL及错误信息
+func (c *Conn) Fail(message string) error {
+	_, err := fmt.Fprintf(c, "FAIL%04x%s", len(message), message)
+	return err
+}
+
+// WriteValue 发送带4字节十六进制长度前缀的值
+func (c *Conn) WriteValue(value string) error {
+	_, err := fmt.Fprintf(c, "%04x%s", len(value), value)
+	return err
+}
+
+// OkayValue 发送OKAY和带长度前缀的值，大多数host服务的应答格式
+func (c *Conn) OkayValue(value string) error {
+	if err := c.Okay(); err != nil {
+		return err
+	}
+	return c.WriteValue(value)
+}
+
+// writeSync 发送同步协议的消息：4字节ID和4字节小端数值
+func (c *Conn) writeSync(id string, value uint32) error {
+	buf := make([]byte, 8)
+	copy(buf, id)
+	binary.LittleEndian.PutUint32(buf[4:], value)
+	_, err := c.Write(buf)
+	return err
+}
+
+// readSync 读取同步协议的请求：4字节ID和4字节小端数值
+func (c *Conn) readSync() (string, uint32, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(c, buf); err != nil {
+		return "", 0, err
+	}
+	return string(buf[:4]), binary.LittleEndian.Uint32(buf[4:]), nil
+}
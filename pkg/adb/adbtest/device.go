@@ -0,0 +1,529 @@
+package adbtest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+	"adb-kit-go/pkg/adb/shellproto"
+)
+
+// Device 假服务器上的设备
+type Device struct {
+	server      *Server
+	serial      string
+	transportID uint64
+	mu          sync.Mutex
+	state       string
+	usb         bool
+	legacy      bool
+	pid         int
+	props       map[string]string
+	features    []string
+	shell       map[string]shellResult
+	shellFunc   func(command string) (string, bool)
+	handlers    []handler
+	reverses    map[string]string
+	width       int
+	height      int
+	pixels      []byte
+
+	// FS 设备的内存文件系统
+	FS *FS
+}
+
+// newDevice 创建设备，序列号包含冒号或以emulator-开头的设备视为TCP/IP设备
+func newDevice(server *Server, serial string, transportID uint64) *Device {
+	return &Device{
+		server:      server,
+		serial:      serial,
+		transportID: transportID,
+		state:       "device",
+		usb:         !strings.Contains(serial, ":") && !strings.HasPrefix(serial, "emulator-"),
+		props: map[string]string{
+			"ro.serialno":          serial,
+			"ro.product.model":     "adbtest",
+			"sys.boot_completed":   "1",
+			"ro.build.version.sdk": "34",
+			// 就绪检查使用的属性
+			"sys.user.0.ce_available": "true",
+		},
+		features: []string{"cmd"},
+		shell:    make(map[string]shellResult),
+		reverses: make(map[string]string),
+		width:    2,
+		height:   2,
+		pixels:   make([]byte, 2*2*4),
+		FS:       NewFS(),
+	}
+}
+
+// Serial 返回序列号
+func (d *Device) Serial() string {
+	return d.serial
+}
+
+// TransportID 返回传输ID
+func (d *Device) TransportID() uint64 {
+	return d.transportID
+}
+
+// State 返回设备状态
+func (d *Device) State() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state
+}
+
+// SetState 设置设备状态，如device、offline、unauthorized，跟踪设备的客户端会收到更新
+func (d *Device) SetState(state string) {
+	d.mu.Lock()
+	d.state = state
+	d.mu.Unlock()
+	d.server.notify()
+}
+
+// SetUsb 设置设备是否通过USB连接，影响host:transport-usb和host:transport-local的选择
+func (d *Device) SetUsb(usb bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.usb = usb
+}
+
+// SetLegacy 模拟Android 5.0之前的设备：不支持exec:，shell:输出中的LF被转换为CRLF
+func (d *Device) SetLegacy(legacy bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.legacy = legacy
+}
+
+// SetProp 设置getprop返回的系统属性
+func (d *Device) SetProp(key, value string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.props[key] = value
+}
+
+// SetFeatures 设置设备支持的特性
+func (d *Device) SetFeatures(features ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.features = features
+}
+
+// OnShell 设置shell命令的输出
+func (d *Device) OnShell(command string, output string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.shell[command] = shellResult{stdout: output}
+}
+
+// OnShellResult 设置shell命令的stdout、stderr和退出码
+// 通过shell:执行时stderr接在stdout之后返回，退出码只能通过shell,v2:得到
+func (d *Device) OnShellResult(command string, stdout string, stderr string, exitCode int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.shell[command] = shellResult{stdout: stdout, stderr: stderr, exitCode: exitCode}
+}
+
+// HandleShell 设置处理shell命令的函数，返回false时继续使用OnShell设置的输出和内置命令
+func (d *Device) HandleShell(fn func(command string) (output string, ok bool)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.shellFunc = fn
+}
+
+// Handle 为设备上的服务注册处理函数，优先于内置服务
+// pattern以*结尾时按前缀匹配，如 "shell:monkey*"
+func (d *Device) Handle(pattern string, fn HandlerFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers = append(d.handlers, handler{pattern: pattern, fn: fn})
+}
+
+// SetFramebuffer 设置framebuffer:返回的图像，pixels为RGBA格式
+func (d *Device) SetFramebuffer(width, height int, pixels []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.width, d.height = width, height
+	d.pixels = append([]byte(nil), pixels...)
+}
+
+// Reverses 返回当前的反向端口转发，键为设备端地址
+func (d *Device) Reverses() map[string]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	reverses := make(map[string]string, len(d.reverses))
+	for remote, local := range d.reverses {
+		reverses[remote] = local
+	}
+	return reverses
+}
+
+// handler 返回匹配服务的处理函数
+func (d *Device) handler(service string) HandlerFunc {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return matchHandler(d.handlers, service)
+}
+
+// serve 应答设备上的服务
+func (d *Device) serve(conn *Conn, service string) error {
+	name, arg, _ := strings.Cut(service, ":")
+	if strings.HasPrefix(name, "shell,v2,") && strings.HasSuffix(name, ",pty") {
+		name = "shell,v2,pty" // 忽略TERM等参数
+	}
+	switch name {
+	case "shell":
+		if err := conn.Okay(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(arg, "while getprop sys.boot_completed") {
+			return d.waitBootCompleted(conn)
+		}
+		if arg == "sh" {
+			return d.serveShellSession(conn)
+		}
+		command, sentinel, wrapped := hosttransport.UnwrapExitCode(arg)
+		result := d.runShell(command)
+		output := result.stdout + result.stderr
+		if wrapped {
+			// 模拟shell输出进程ID和命令结束后输出的退出码
+			output = fmt.Sprintf("%s:%d\n%s%s:%d\n", sentinel, d.nextPid(), output, sentinel, result.exitCode)
+		}
+		if d.isLegacy() {
+			output = strings.ReplaceAll(output, "\n", "\r\n")
+		}
+		_, err := conn.Write([]byte(output))
+		return err
+	case "exec":
+		if d.isLegacy() {
+			return conn.Fail("closed")
+		}
+		if err := conn.Okay(); err != nil {
+			return err
+		}
+		result := d.runShell(arg)
+		_, err := conn.Write([]byte(result.stdout + result.stderr))
+		return err
+	case "shell,v2,raw":
+		if err := conn.Okay(); err != nil {
+			return err
+		}
+		return serveShellV2(conn, d.runShell(arg))
+	case "shell,v2,pty":
+		// PTY中stderr和stdout是同一个终端
+		if err := conn.Okay(); err != nil {
+			return err
+		}
+		result := d.runShell(arg)
+		return serveShellV2(conn, shellResult{stdout: result.stdout + result.stderr, exitCode: result.exitCode})
+	case "sync":
+		if err := conn.Okay(); err != nil {
+			return err
+		}
+		return serveSync(conn, d.FS)
+	case "framebuffer":
+		return d.serveFramebuffer(conn)
+	case "reverse":
+		return d.serveReverse(conn, arg)
+	}
+	return conn.Fail(fmt.Sprintf("unknown service '%s'", service))
+}
+
+// shellResult shell命令的结果
+type shellResult struct {
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// runShell 返回shell命令的结果
+func (d *Device) runShell(command string) shellResult {
+	d.mu.Lock()
+	fn := d.shellFunc
+	output, ok := d.shell[command]
+	if rest, isCmd := strings.CutPrefix(command, "cmd package "); isCmd && !ok {
+		// 与真实设备一样，pm和cmd package是同一个服务，OnShell只设置其中一种写法即可
+		output, ok = d.shell["pm "+rest]
+	}
+	d.mu.Unlock()
+
+	if fn != nil {
+		if output, ok := fn(command); ok {
+			return shellResult{stdout: output}
+		}
+	}
+	if ok {
+		return output
+	}
+	if rest, isEcho := strings.CutPrefix(command, "echo && "); isEcho && !ok {
+		// Client.Exec在不支持exec:的设备上通过shell:执行，命令前的echo用于检测行结束符转换
+		result := d.runShell(rest)
+		result.stdout = "\n" + result.stdout
+		return result
+	}
+	if result, ok := d.builtinShell(command); ok {
+		return result
+	}
+
+	name, _, _ := strings.Cut(command, " ")
+	return shellResult{stderr: fmt.Sprintf("/system/bin/sh: %s: not found\n", name), exitCode: 127}
+}
+
+// builtinShell 返回内置命令的结果
+func (d *Device) builtinShell(command string) (shellResult, bool) {
+	switch {
+	case command == "getprop":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		keys := make([]string, 0, len(d.props))
+		for key := range d.props {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		for _, key := range keys {
+			fmt.Fprintf(&b, "[%s]: [%s]\n", key, d.props[key])
+		}
+		return shellResult{stdout: b.String()}, true
+	case strings.HasPrefix(command, "getprop "):
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return shellResult{stdout: d.props[strings.TrimSpace(strings.TrimPrefix(command, "getprop "))] + "\n"}, true
+	case command == "pm path android":
+		return shellResult{stdout: "package:/system/framework/framework-res.apk\n"}, true
+	case command == "echo" || strings.HasPrefix(command, "echo "):
+		return shellResult{stdout: strings.TrimPrefix(strings.TrimPrefix(command, "echo"), " ") + "\n"}, true
+	case strings.HasPrefix(command, "cat "):
+		// Client.Cat只引用包含特殊字符的路径
+		name := unquote(strings.TrimPrefix(command, "cat "))
+		data, ok := d.FS.ReadFile(name)
+		if !ok {
+			return shellResult{stderr: fmt.Sprintf("cat: %s: No such file or directory\n", name), exitCode: 1}, true
+		}
+		return shellResult{stdout: string(data)}, true
+	}
+	return shellResult{}, false
+}
+
+// serveShellV2 按shell协议v2发送结果：stdout、stderr和退出码
+func serveShellV2(conn *Conn, result shellResult) error {
+	for _, packet := range []struct {
+		id   byte
+		data string
+	}{
+		{shellproto.IDStdout, result.stdout},
+		{shellproto.IDStderr, result.stderr},
+	} {
+		if packet.data == "" {
+			continue
+		}
+		if err := shellproto.WritePacket(conn, packet.id, []byte(packet.data)); err != nil {
+			return err
+		}
+	}
+	return shellproto.WritePacket(conn, shellproto.IDExit, []byte{byte(result.exitCode)})
+}
+
+// sessionCommand 匹配ShellSession.Run发送的命令行，哨兵中间插入了空的引号
+var sessionCommand = regexp.MustCompile(`^eval (.*) </dev/null; echo ([A-Za-z0-9_]+)''([A-Za-z0-9_]+:)\$\?$`)
+
+// sessionSetup 匹配打开ShellSession时关闭回显和提示符的命令行
+var sessionSetup = regexp.MustCompile(`^stty -echo 2>/dev/null; PS1=; PS2=; echo ([A-Za-z0-9_]+)''([A-Za-z0-9_]+:)$`)
+
+// unquote 还原shellcmd按Modern或Legacy方式引用的单个参数
+func unquote(arg string) string {
+	if len(arg) >= 2 && strings.HasPrefix(arg, "'") && strings.HasSuffix(arg, "'") {
+		return strings.ReplaceAll(arg[1:len(arg)-1], `'\''`, "'")
+	}
+	if len(arg) >= 2 && strings.HasPrefix(arg, `"`) && strings.HasSuffix(arg, `"`) {
+		return legacyUnescaper.Replace(arg[1 : len(arg)-1])
+	}
+	return arg
+}
+
+// legacyUnescaper 还原Legacy方式在双引号中转义的字符
+var legacyUnescaper = strings.NewReplacer(`\\`, `\`, `\$`, `$`, "\\`", "`", `\"`, `"`)
+
+// serveShellSession 模拟在PTY上从标准输入逐行读取命令的sh，用于ShellSession
+// 与真实设备一样先输出提示符并回显收到的行，直到ShellSession关闭回显和提示符；
+// ShellSession.Run发送的命令输出结果和退出码，其他行作为普通命令执行，exit结束会话
+func (d *Device) serveShellSession(conn *Conn) error {
+	reader := bufio.NewReader(conn)
+	interactive := true
+	for {
+		if interactive {
+			if _, err := conn.Write([]byte("$ ")); err != nil {
+				return err
+			}
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if interactive {
+			if _, err := conn.Write([]byte(line + "\r\n")); err != nil {
+				return err
+			}
+		}
+
+		if match := sessionSetup.FindStringSubmatch(line); match != nil {
+			interactive = false
+			if _, err := conn.Write([]byte(match[1] + match[2] + "\n")); err != nil {
+				return err
+			}
+			continue
+		}
+
+		command, sentinel := line, ""
+		if match := sessionCommand.FindStringSubmatch(line); match != nil {
+			command, sentinel = unquote(match[1]), match[2]+match[3]
+		}
+		switch command {
+		case "":
+			continue
+		case "exit":
+			return nil
+		}
+
+		result := d.runShell(command)
+		output := result.stdout + result.stderr
+		if sentinel != "" {
+			output += fmt.Sprintf("%s%d\n", sentinel, result.exitCode)
+		}
+		if _, err := conn.Write([]byte(output)); err != nil {
+			return err
+		}
+	}
+}
+
+// waitBootCompleted 模拟WaitBootCompleteCommand的循环：
+// 定期输出sys.boot_completed的值，直到值为1或连接关闭
+func (d *Device) waitBootCompleted(conn *Conn) error {
+	for {
+		d.mu.Lock()
+		value := d.props["sys.boot_completed"]
+		d.mu.Unlock()
+
+		if _, err := conn.Write([]byte(value + "\n")); err != nil || value == "1" {
+			return err
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// serveFramebuffer 发送版本1的framebuffer头和RGBA像素
+func (d *Device) serveFramebuffer(conn *Conn) error {
+	d.mu.Lock()
+	width, height := d.width, d.height
+	pixels := append([]byte(nil), d.pixels...)
+	d.mu.Unlock()
+
+	header := []uint32{
+		1,                   // version
+		32,                  // bpp
+		uint32(len(pixels)), // size
+		uint32(width), uint32(height),
+		0, 8, // red offset, length
+		16, 8, // blue offset, length
+		8, 8, // green offset, length
+		24, 8, // alpha offset, length
+	}
+	buf := make([]byte, 4*len(header))
+	for i, v := range header {
+		binary.LittleEndian.PutUint32(buf[4*i:], v)
+	}
+
+	if err := conn.Okay(); err != nil {
+		return err
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+	_, err := conn.Write(pixels)
+	return err
+}
+
+// serveReverse 应答reverse:服务
+func (d *Device) serveReverse(conn *Conn, arg string) error {
+	if err := conn.Okay(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case arg == "list-forward":
+		remotes := make([]string, 0, len(d.reverses))
+		for remote := range d.reverses {
+			remotes = append(remotes, remote)
+		}
+		sort.Strings(remotes)
+		var b strings.Builder
+		for _, remote := range remotes {
+			fmt.Fprintf(&b, "%s %s %s\n", d.serial, remote, d.reverses[remote])
+		}
+		return conn.WriteValue(b.String())
+
+	case strings.HasPrefix(arg, "forward:"):
+		spec := strings.TrimPrefix(strings.TrimPrefix(arg, "forward:"), "norebind:")
+		remote, local, ok := strings.Cut(spec, ";")
+		if !ok {
+			return conn.Fail("malformed forward spec")
+		}
+		d.reverses[remote] = local
+		return conn.Okay()
+
+	case arg == "killforward-all":
+		d.reverses = make(map[string]string)
+		return conn.Okay()
+
+	case strings.HasPrefix(arg, "killforward:"):
+		remote := strings.TrimPrefix(arg, "killforward:")
+		if _, ok := d.reverses[remote]; !ok {
+			return conn.Fail(fmt.Sprintf("listener '%s' not found", remote))
+		}
+		delete(d.reverses, remote)
+		return conn.Okay()
+	}
+	return conn.Fail(fmt.Sprintf("unknown reverse service '%s'", arg))
+}
+
+// featureList 返回逗号分隔的特性列表
+func (d *Device) featureList() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return strings.Join(d.features, ",")
+}
+
+// nextPid 返回模拟的shell进程ID
+func (d *Device) nextPid() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pid++
+	return 1000 + d.pid
+}
+
+// isLegacy 检查是否模拟旧设备
+func (d *Device) isLegacy() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.legacy
+}
+
+// isUsb 检查设备是否通过USB连接
+func (d *Device) isUsb() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.usb
+}
@@ -0,0 +1,216 @@
+package adbtest
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文件类型位，与Linux的st_mode一致
+const (
+	modeDir  = 0040000
+	modeFile = 0100000
+	modeLink = 0120000
+)
+
+// maxLinks 解析路径时最多跟随的符号链接数量，与Linux的限制相同
+const maxLinks = 40
+
+// File 内存文件系统中的文件
+type File struct {
+	Data  []byte
+	Mode  os.FileMode
+	MTime time.Time
+}
+
+// FS 设备的内存文件系统，sync:服务的读写都作用于它
+// 目录是隐式的：任何文件或符号链接的上级路径都视为存在的目录。
+// 与adbd一样，STAT和LST2不跟随最后一级的符号链接，STA2、LIST和RECV跟随
+type FS struct {
+	mu    sync.Mutex
+	files map[string]*File
+	links map[string]string
+}
+
+// NewFS 创建空的内存文件系统
+func NewFS() *FS {
+	return &FS{files: make(map[string]*File), links: make(map[string]string)}
+}
+
+// Symlink 创建指向target的符号链接name，target为相对路径时相对于链接所在的目录
+func (fs *FS) Symlink(target string, name string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.links[path.Clean(name)] = target
+}
+
+// WriteFile 写入文件
+func (fs *FS) WriteFile(name string, data []byte, mode os.FileMode) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.files[path.Clean(name)] = &File{
+		Data:  append([]byte(nil), data...),
+		Mode:  mode.Perm(),
+		MTime: time.Now().Truncate(time.Second),
+	}
+}
+
+// ReadFile 读取文件，文件不存在时返回false
+func (fs *FS) ReadFile(name string) ([]byte, bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	file, ok := fs.files[fs.resolve(name, true)]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), file.Data...), true
+}
+
+// Remove 删除文件
+func (fs *FS) Remove(name string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.files, path.Clean(name))
+}
+
+// put 保存通过sync:推送的文件
+func (fs *FS) put(name string, data []byte, mode uint32, mtime uint32) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.files[path.Clean(name)] = &File{
+		Data:  data,
+		Mode:  os.FileMode(mode).Perm(),
+		MTime: time.Unix(int64(mtime), 0),
+	}
+}
+
+// stat 与lstat相同，返回文件的st_mode、大小和修改时间，不存在时mode为0
+// 与lstat一样，以/结尾的路径会跟随最后一级的符号链接
+func (fs *FS) stat(name string) (mode uint32, size uint32, mtime uint32) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.statLocked(fs.resolve(name, strings.HasSuffix(name, "/")))
+}
+
+// statFollow 与stat相同，但跟随最后一级的符号链接
+func (fs *FS) statFollow(name string) (mode uint32, size uint32, mtime uint32) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.statLocked(fs.resolve(name, true))
+}
+
+// statLocked 返回已解析路径的状态，调用方需持有锁
+func (fs *FS) statLocked(name string) (mode uint32, size uint32, mtime uint32) {
+	if target, ok := fs.links[name]; ok {
+		return modeLink | 0777, uint32(len(target)), 0
+	}
+	if file, ok := fs.files[name]; ok {
+		return modeFile | uint32(file.Mode), uint32(len(file.Data)), uint32(file.MTime.Unix())
+	}
+	if fs.isDir(name) {
+		return modeDir | 0755, 4096, 0
+	}
+	return 0, 0, 0
+}
+
+// dirEntry 目录项
+type dirEntry struct {
+	name  string
+	mode  uint32
+	size  uint32
+	mtime uint32
+}
+
+// list 列出目录中的直接子项，按名称排序
+func (fs *FS) list(name string) ([]dirEntry, bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	name = fs.resolve(name, true)
+	if !fs.isDir(name) {
+		return nil, false
+	}
+
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	entries := make(map[string]dirEntry)
+	for filePath, file := range fs.files {
+		if !strings.HasPrefix(filePath, prefix) {
+			continue
+		}
+		child, rest, nested := strings.Cut(filePath[len(prefix):], "/")
+		if nested && rest != "" {
+			entries[child] = dirEntry{name: child, mode: modeDir | 0755, size: 4096}
+			continue
+		}
+		entries[child] = dirEntry{
+			name:  child,
+			mode:  modeFile | uint32(file.Mode),
+			size:  uint32(len(file.Data)),
+			mtime: uint32(file.MTime.Unix()),
+		}
+	}
+	for linkPath, target := range fs.links {
+		if linkPath != "/" && path.Dir(linkPath) == name {
+			child := path.Base(linkPath)
+			entries[child] = dirEntry{name: child, mode: modeLink | 0777, size: uint32(len(target))}
+		}
+	}
+
+	list := make([]dirEntry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list, true
+}
+
+// isDir 检查路径是否是目录，调用方需持有锁
+func (fs *FS) isDir(name string) bool {
+	if name == "/" {
+		return true
+	}
+	prefix := name + "/"
+	for filePath := range fs.files {
+		if strings.HasPrefix(filePath, prefix) {
+			return true
+		}
+	}
+	for linkPath := range fs.links {
+		if strings.HasPrefix(linkPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve 清理路径并解析其中的符号链接，follow为false时保留最后一级的符号链接，调用方需持有锁
+func (fs *FS) resolve(name string, follow bool) string {
+	name = path.Clean("/" + name)
+	for i := 0; i < maxLinks; i++ {
+		parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+		dir, resolved := "/", false
+		for j, part := range parts {
+			current := path.Join(dir, part)
+			target, ok := fs.links[current]
+			if ok && (follow || j < len(parts)-1) {
+				if !path.IsAbs(target) {
+					target = path.Join(dir, target)
+				}
+				name = path.Join(append([]string{target}, parts[j+1:]...)...)
+				resolved = true
+				break
+			}
+			dir = current
+		}
+		if !resolved {
+			return name
+		}
+	}
+	return name
+}
@@ -0,0 +1,621 @@
+// Package adbtest 提供进程内的假ADB服务器，用于在没有设备的环境中测试基于adb包的代码
+//
+// 假服务器实现了常用的host服务和设备服务：
+// host:version、host:devices、host:devices-l、host:track-devices、host:transport*、host:tport、
+// host-serial类查询，以及设备上的shell:、shell,v2:、exec:、sync:（基于内存文件系统）、framebuffer:和reverse:。
+// 通过Handle注册的处理函数可以替换任意服务的应答，通过InjectFault可以注入失败、断开和延迟。
+//
+//	server, err := adbtest.NewServer()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer server.Close()
+//
+//	device := server.AddDevice("emulator-5554")
+//	device.OnShell("pm path com.example", "package:/data/app/base.apk\n")
+//
+//	client := server.Client()
+package adbtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"adb-kit-go/pkg/adb"
+)
+
+// DefaultVersion host:version返回的默认版本
+const DefaultVersion = 41
+
+// DefaultHostFeatures host:host-features返回的默认特性，与较新的ADB服务器一致
+var DefaultHostFeatures = []string{
+	"shell_v2", "cmd", "stat_v2", "ls_v2", "fixed_push_mkdir", "apex", "abb",
+	"fixed_push_symlink_timestamp", "abb_exec", "remount_shell", "track_app",
+	"sendrecv_v2", "sendrecv_v2_brotli", "sendrecv_v2_lz4", "sendrecv_v2_zstd",
+	"sendrecv_v2_dry_run_send", "openscreen_mdns", "push_sync",
+}
+
+// HandlerFunc 自定义服务处理函数，返回后连接被关闭
+type HandlerFunc func(conn *Conn, service string) error
+
+// handler 注册的处理函数
+type handler struct {
+	pattern string
+	fn      HandlerFunc
+}
+
+// Fault 注入的故障，对匹配的服务在应答前生效
+type Fault struct {
+	Service string        // 匹配的服务，为空时匹配所有服务，以*结尾时按前缀匹配
+	Fail    string        // 非空时以该信息应答FAIL
+	Close   bool          // 不应答直接关闭连接
+	Delay   time.Duration // 应答前等待的时间
+	Times   int           // 生效的次数，0表示一直生效
+}
+
+// Server 进程内的假ADB服务器
+type Server struct {
+	listener net.Listener
+	mu       sync.Mutex
+	version  int
+	features []string
+	devices  []*Device
+	nextID   uint64
+	handlers []handler
+	faults   []*Fault
+	requests []string
+	watchers map[chan struct{}]struct{}
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer 创建假服务器并开始在127.0.0.1的随机端口上监听
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("监听失败: %w", err)
+	}
+
+	s := &Server{
+		listener: listener,
+		version:  DefaultVersion,
+		features: DefaultHostFeatures,
+		watchers: make(map[chan struct{}]struct{}),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+	return s, nil
+}
+
+// Port 返回监听端口
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Options 返回连接到假服务器的客户端选项
+// Bin指向无法执行的文件，避免假服务器关闭后客户端启动真实的ADB服务器
+func (s *Server) Options() *adb.Options {
+	return &adb.Options{
+		Host: "127.0.0.1",
+		Port: s.Port(),
+		Bin:  os.DevNull,
+	}
+}
+
+// Client 返回连接到假服务器的客户端
+func (s *Server) Client() *adb.Client {
+	return adb.NewClient(s.Options())
+}
+
+// Close 停止服务器并关闭所有连接
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// DropConnections 关闭所有已建立的连接但继续接受新连接，用于模拟ADB服务器重启
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// SetVersion 设置host:version返回的版本
+func (s *Server) SetVersion(version int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// SetHostFeatures 设置host:host-features返回的特性，不设置任何特性时该服务返回未知服务错误，与旧服务器一致
+func (s *Server) SetHostFeatures(features ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.features = features
+}
+
+// AddDevice 添加状态为device的设备，跟踪设备的客户端会收到更新
+func (s *Server) AddDevice(serial string) *Device {
+	s.mu.Lock()
+	s.nextID++
+	device := newDevice(s, serial, s.nextID)
+	s.devices = append(s.devices, device)
+	s.mu.Unlock()
+
+	s.notify()
+	return device
+}
+
+// RemoveDevice 移除设备
+func (s *Server) RemoveDevice(serial string) {
+	s.mu.Lock()
+	for i, device := range s.devices {
+		if device.serial == serial {
+			s.devices = append(s.devices[:i], s.devices[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Device 按序列号查找设备
+func (s *Server) Device(serial string) *Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, device := range s.devices {
+		if device.serial == serial {
+			return device
+		}
+	}
+	return nil
+}
+
+// Handle 为host服务注册处理函数，优先于内置服务
+// pattern以*结尾时按前缀匹配，如 "host:track-*"；设备上的服务见Device.Handle
+func (s *Server) Handle(pattern string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler{pattern: pattern, fn: fn})
+}
+
+// InjectFault 注入故障，对host服务和设备服务都生效
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// Requests 返回服务器收到的全部请求，按接收顺序排列
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// acceptLoop 持续接受新连接
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			s.serve(&Conn{Conn: conn})
+		}()
+	}
+}
+
+// serve 处理一个连接上的请求，切换到设备传输后由设备应答
+func (s *Server) serve(conn *Conn) {
+	var device *Device
+	for {
+		service, err := conn.ReadRequest()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, service)
+		s.mu.Unlock()
+
+		if !s.applyFault(conn, service) {
+			return
+		}
+
+		if device != nil {
+			if fn := device.handler(service); fn != nil {
+				fn(conn, service)
+				return
+			}
+			device.serve(conn, service)
+			return
+		}
+
+		if fn := s.handler(service); fn != nil {
+			fn(conn, service)
+			return
+		}
+
+		next, keep := s.serveHost(conn, service)
+		if !keep {
+			return
+		}
+		device = next
+	}
+}
+
+// applyFault 应用匹配的故障，返回false时连接应被关闭
+func (s *Server) applyFault(conn *Conn, service string) bool {
+	s.mu.Lock()
+	var fault *Fault
+	for i, f := range s.faults {
+		if f.Service != "" && !matchPattern(f.Service, service) {
+			continue
+		}
+		copied := *f
+		fault = &copied
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		break
+	}
+	s.mu.Unlock()
+
+	if fault == nil {
+		return true
+	}
+	if fault.Delay > 0 {
+		time.Sleep(fault.Delay)
+	}
+	if fault.Close {
+		return false
+	}
+	if fault.Fail != "" {
+		conn.Fail(fault.Fail)
+		return false
+	}
+	return true
+}
+
+// handler 返回匹配host服务的处理函数
+func (s *Server) handler(service string) HandlerFunc {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return matchHandler(s.handlers, service)
+}
+
+// serveHost 应答host服务
+// 切换到设备传输时返回该设备且keep为true，其余服务应答后连接关闭
+func (s *Server) serveHost(conn *Conn, service string) (device *Device, keep bool) {
+	switch {
+	case service == "host:version":
+		s.mu.Lock()
+		version := s.version
+		s.mu.Unlock()
+		conn.OkayValue(fmt.Sprintf("%04x", version))
+
+	case service == "host:devices":
+		conn.OkayValue(s.deviceList(false))
+
+	case service == "host:devices-l":
+		conn.OkayValue(s.deviceList(true))
+
+	case service == "host:track-devices" || service == "host:track-devices-l":
+		s.trackDevices(conn, service == "host:track-devices-l")
+
+	case service == "host:host-features":
+		s.mu.Lock()
+		features := strings.Join(s.features, ",")
+		s.mu.Unlock()
+		if features == "" {
+			conn.Fail(fmt.Sprintf("unknown host service '%s'", service))
+		} else {
+			conn.OkayValue(features)
+		}
+
+	case service == "host:kill":
+		conn.Okay()
+
+	case strings.HasPrefix(service, "host:connect:"):
+		conn.OkayValue("connected to " + strings.TrimPrefix(service, "host:connect:"))
+
+	case strings.HasPrefix(service, "host:disconnect:"):
+		conn.OkayValue("disconnected " + strings.TrimPrefix(service, "host:disconnect:"))
+
+	case strings.HasPrefix(service, "host:transport"):
+		device, err := s.selectDevice(strings.TrimPrefix(service, "host:"))
+		if err != nil {
+			conn.Fail(err.Error())
+			return nil, false
+		}
+		conn.Okay()
+		return device, true
+
+	case strings.HasPrefix(service, "host:tport:"):
+		device, err := s.selectDevice(tportSelector(strings.TrimPrefix(service, "host:tport:")))
+		if err != nil {
+			conn.Fail(err.Error())
+			return nil, false
+		}
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, device.transportID)
+		conn.Okay()
+		conn.Write(buf)
+		return device, true
+
+	default:
+		s.serveHostSerial(conn, service)
+	}
+	return nil, false
+}
+
+// serveHostSerial 应答host-serial类查询，如 host-serial:<serial>:get-state
+func (s *Server) serveHostSerial(conn *Conn, service string) {
+	selector, command, ok := splitHostSerial(service)
+	if !ok {
+		conn.Fail(fmt.Sprintf("unknown host service '%s'", service))
+		return
+	}
+
+	device, err := s.selectDevice(selector)
+	if err != nil {
+		conn.Fail(err.Error())
+		return
+	}
+
+	switch command {
+	case "get-serialno":
+		conn.OkayValue(device.serial)
+	case "get-state":
+		conn.OkayValue(device.State())
+	case "get-devpath":
+		conn.OkayValue(devicePath(device))
+	case "features":
+		conn.OkayValue(device.featureList())
+	default:
+		if strings.HasPrefix(command, "wait-for-") {
+			conn.Okay()
+			conn.Okay()
+			return
+		}
+		conn.Fail(fmt.Sprintf("unknown host service '%s'", service))
+	}
+}
+
+// splitHostSerial 将host-serial类服务拆分为选择方式和命令，选择方式使用transport服务的写法
+func splitHostSerial(service string) (selector string, command string, ok bool) {
+	switch {
+	case strings.HasPrefix(service, "host-serial:"):
+		rest := strings.TrimPrefix(service, "host-serial:")
+		// 序列号可能包含冒号，如 192.168.1.2:5555
+		i := strings.LastIndex(rest, ":")
+		if i < 0 {
+			return "", "", false
+		}
+		return "transport:" + rest[:i], rest[i+1:], true
+	case strings.HasPrefix(service, "host-transport-id:"):
+		id, command, ok := strings.Cut(strings.TrimPrefix(service, "host-transport-id:"), ":")
+		return "transport-id:" + id, command, ok
+	case strings.HasPrefix(service, "host-usb:"):
+		return "transport-usb", strings.TrimPrefix(service, "host-usb:"), true
+	case strings.HasPrefix(service, "host-local:"):
+		return "transport-local", strings.TrimPrefix(service, "host-local:"), true
+	case strings.HasPrefix(service, "host:"):
+		return "transport-any", strings.TrimPrefix(service, "host:"), true
+	}
+	return "", "", false
+}
+
+// tportSelector 将tport的参数转换为transport服务的写法
+func tportSelector(arg string) string {
+	if serial, ok := strings.CutPrefix(arg, "serial:"); ok {
+		return "transport:" + serial
+	}
+	return "transport-" + arg
+}
+
+// selectDevice 按transport服务的写法选择设备，错误信息与真实服务器一致
+func (s *Server) selectDevice(selector string) (*Device, error) {
+	s.mu.Lock()
+	devices := append([]*Device(nil), s.devices...)
+	s.mu.Unlock()
+
+	var candidates []*Device
+	switch {
+	case strings.HasPrefix(selector, "transport:"):
+		serial := strings.TrimPrefix(selector, "transport:")
+		for _, device := range devices {
+			if device.serial == serial {
+				return checkState(device)
+			}
+		}
+		return nil, fmt.Errorf("device '%s' not found", serial)
+
+	case strings.HasPrefix(selector, "transport-id:"):
+		id, err := strconv.ParseUint(strings.TrimPrefix(selector, "transport-id:"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport id")
+		}
+		for _, device := range devices {
+			if device.transportID == id {
+				return checkState(device)
+			}
+		}
+		return nil, fmt.Errorf("no device with transport id '%d'", id)
+
+	case selector == "transport-usb":
+		for _, device := range devices {
+			if device.isUsb() {
+				candidates = append(candidates, device)
+			}
+		}
+	case selector == "transport-local":
+		for _, device := range devices {
+			if !device.isUsb() {
+				candidates = append(candidates, device)
+			}
+		}
+	case selector == "transport-any":
+		candidates = devices
+	default:
+		return nil, fmt.Errorf("unknown host service")
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no devices/emulators found")
+	case 1:
+		return checkState(candidates[0])
+	}
+	return nil, fmt.Errorf("more than one device/emulator")
+}
+
+// checkState 检查设备是否可以建立传输
+func checkState(device *Device) (*Device, error) {
+	switch state := device.State(); state {
+	case "device", "recovery", "sideload", "rescue", "bootloader":
+		return device, nil
+	case "unauthorized":
+		return nil, fmt.Errorf("device unauthorized.\nThis adb server's $ADB_VENDOR_KEYS is not set")
+	default:
+		return nil, fmt.Errorf("device %s", state)
+	}
+}
+
+// deviceList 返回host:devices或host:devices-l格式的设备列表
+func (s *Server) deviceList(long bool) string {
+	s.mu.Lock()
+	devices := append([]*Device(nil), s.devices...)
+	s.mu.Unlock()
+
+	var b strings.Builder
+	for _, device := range devices {
+		if !long {
+			fmt.Fprintf(&b, "%s\t%s\n", device.serial, device.State())
+			continue
+		}
+
+		device.mu.Lock()
+		model := strings.ReplaceAll(device.props["ro.product.model"], " ", "_")
+		device.mu.Unlock()
+		fmt.Fprintf(&b, "%-22s %s %s model:%s transport_id:%d\n",
+			device.serial, device.State(), devicePath(device), model, device.transportID)
+	}
+	return b.String()
+}
+
+// devicePath 返回设备路径
+func devicePath(device *Device) string {
+	if device.isUsb() {
+		return fmt.Sprintf("usb:1-%d", device.transportID)
+	}
+	return "product:sdk_gphone64"
+}
+
+// trackDevices 发送当前设备列表，之后在设备变化时发送新的列表，直到连接关闭
+func (s *Server) trackDevices(conn *Conn, long bool) {
+	changed := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.watchers[changed] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, changed)
+		s.mu.Unlock()
+	}()
+
+	// 客户端关闭连接时结束跟踪
+	closed := make(chan struct{})
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				close(closed)
+				return
+			}
+		}
+	}()
+
+	if err := conn.OkayValue(s.deviceList(long)); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-changed:
+			if err := conn.WriteValue(s.deviceList(long)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// notify 通知正在跟踪设备的连接
+func (s *Server) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for watcher := range s.watchers {
+		select {
+		case watcher <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// matchHandler 返回第一个匹配服务的处理函数
+func matchHandler(handlers []handler, service string) HandlerFunc {
+	for _, h := range handlers {
+		if matchPattern(h.pattern, service) {
+			return h.fn
+		}
+	}
+	return nil
+}
+
+// matchPattern 检查服务是否匹配，pattern以*结尾时按前缀匹配
+func matchPattern(pattern, service string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(service, prefix)
+	}
+	return pattern == service
+}
@@ -0,0 +1,203 @@
+package adbtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 同步协议单个DATA块的最大长度
+const syncDataMax = 64 * 1024
+
+// serveSync 在内存文件系统上应答同步协议请求，直到收到QUIT或连接关闭
+func serveSync(conn *Conn, fs *FS) error {
+	for {
+		id, length, err := conn.readSync()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		arg := make([]byte, length)
+		if _, err := io.ReadFull(conn, arg); err != nil {
+			return err
+		}
+		name := string(arg)
+
+		switch id {
+		case "STAT":
+			mode, size, mtime := fs.stat(name)
+			buf := make([]byte, 16)
+			copy(buf, "STAT")
+			binary.LittleEndian.PutUint32(buf[4:], mode)
+			binary.LittleEndian.PutUint32(buf[8:], size)
+			binary.LittleEndian.PutUint32(buf[12:], mtime)
+			if _, err := conn.Write(buf); err != nil {
+				return err
+			}
+
+		case "LST2", "STA2":
+			if _, err := conn.Write(syncStatV2(id, fs, name)); err != nil {
+				return err
+			}
+
+		case "LIST":
+			if err := syncList(conn, fs, name); err != nil {
+				return err
+			}
+
+		case "LIS2":
+			if err := syncListV2(conn, fs, name); err != nil {
+				return err
+			}
+
+		case "SEND":
+			if err := syncReceive(conn, fs, name); err != nil {
+				return err
+			}
+
+		case "RECV":
+			if err := syncSend(conn, fs, name); err != nil {
+				return err
+			}
+
+		case "QUIT":
+			return nil
+
+		default:
+			return syncFail(conn, fmt.Sprintf("unknown sync command '%s'", id))
+		}
+	}
+}
+
+// syncStatV2 返回stat_v2格式的应答，文件不存在时error为ENOENT
+func syncStatV2(id string, fs *FS, name string) []byte {
+	mode, size, mtime := fs.stat(name)
+	if id == "STA2" {
+		mode, size, mtime = fs.statFollow(name)
+	}
+	buf := make([]byte, 72)
+	copy(buf, id)
+	if mode == 0 {
+		binary.LittleEndian.PutUint32(buf[4:], 2) // ENOENT
+		return buf
+	}
+	binary.LittleEndian.PutUint32(buf[24:], mode)
+	binary.LittleEndian.PutUint32(buf[28:], 1) // nlink
+	binary.LittleEndian.PutUint64(buf[40:], uint64(size))
+	for _, offset := range []int{48, 56, 64} { // atime、mtime、ctime
+		binary.LittleEndian.PutUint64(buf[offset:], uint64(mtime))
+	}
+	return buf
+}
+
+// syncList 发送目录项，以DONE结束
+func syncList(conn *Conn, fs *FS, name string) error {
+	entries, _ := fs.list(name)
+	for _, entry := range entries {
+		buf := make([]byte, 20, 20+len(entry.name))
+		copy(buf, "DENT")
+		binary.LittleEndian.PutUint32(buf[4:], entry.mode)
+		binary.LittleEndian.PutUint32(buf[8:], entry.size)
+		binary.LittleEndian.PutUint32(buf[12:], entry.mtime)
+		binary.LittleEndian.PutUint32(buf[16:], uint32(len(entry.name)))
+		if _, err := conn.Write(append(buf, entry.name...)); err != nil {
+			return err
+		}
+	}
+
+	done := make([]byte, 20)
+	copy(done, "DONE")
+	_, err := conn.Write(done)
+	return err
+}
+
+// syncListV2 发送ls_v2格式的目录项，以DONE结束，DONE与目录项的长度相同
+func syncListV2(conn *Conn, fs *FS, name string) error {
+	entries, _ := fs.list(name)
+	for _, entry := range entries {
+		buf := make([]byte, 76, 76+len(entry.name))
+		copy(buf, "DNT2")
+		binary.LittleEndian.PutUint32(buf[24:], entry.mode)
+		binary.LittleEndian.PutUint32(buf[28:], 1) // nlink
+		binary.LittleEndian.PutUint64(buf[40:], uint64(entry.size))
+		for _, offset := range []int{48, 56, 64} { // atime、mtime、ctime
+			binary.LittleEndian.PutUint64(buf[offset:], uint64(entry.mtime))
+		}
+		binary.LittleEndian.PutUint32(buf[72:], uint32(len(entry.name)))
+		if _, err := conn.Write(append(buf, entry.name...)); err != nil {
+			return err
+		}
+	}
+
+	done := make([]byte, 76)
+	copy(done, "DONE")
+	_, err := conn.Write(done)
+	return err
+}
+
+// syncReceive 接收客户端推送的文件，参数格式为 "path,mode"
+func syncReceive(conn *Conn, fs *FS, arg string) error {
+	name, mode := arg, uint64(0644)
+	if i := strings.LastIndex(arg, ","); i >= 0 {
+		name = arg[:i]
+		mode, _ = strconv.ParseUint(arg[i+1:], 10, 32)
+	}
+
+	var data bytes.Buffer
+	for {
+		id, length, err := conn.readSync()
+		if err != nil {
+			return err
+		}
+
+		switch id {
+		case "DATA":
+			if _, err := io.CopyN(&data, conn, int64(length)); err != nil {
+				return err
+			}
+		case "DONE":
+			fs.put(name, data.Bytes(), uint32(mode), length)
+			return conn.writeSync("OKAY", 0)
+		default:
+			return syncFail(conn, fmt.Sprintf("unexpected '%s' during SEND", id))
+		}
+	}
+}
+
+// syncSend 发送文件内容，文件不存在时返回FAIL
+func syncSend(conn *Conn, fs *FS, name string) error {
+	data, ok := fs.ReadFile(name)
+	if !ok {
+		return syncFail(conn, "No such file or directory")
+	}
+
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > syncDataMax {
+			chunk = chunk[:syncDataMax]
+		}
+		data = data[len(chunk):]
+
+		if err := conn.writeSync("DATA", uint32(len(chunk))); err != nil {
+			return err
+		}
+		if _, err := conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return conn.writeSync("DONE", 0)
+}
+
+// syncFail 发送同步协议的FAIL，长度为4字节小端
+func syncFail(conn *Conn, message string) error {
+	if err := conn.writeSync("FAIL", uint32(len(message))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(conn, message)
+	return err
+}
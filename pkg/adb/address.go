@@ -0,0 +1,153 @@
+package adb
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ServerSocketEnv 与官方adb一致的服务器地址环境变量
+const ServerSocketEnv = "ADB_SERVER_SOCKET"
+
+// ServerAddress ADB服务器地址
+type ServerAddress struct {
+	Network string // "tcp" 或 "unix"
+	Address string // tcp为host:port，unix为socket路径（抽象socket以@开头）
+}
+
+// ParseServerAddress 解析ADB服务器地址
+// 支持的格式:
+// - tcp:<port>
+// - tcp:<host>:<port>
+// - <host>:<port>
+// - localabstract:<name>
+// - localfilesystem:<path> 或 unix:<path>
+// - 以/开头的unix socket路径
+func ParseServerAddress(spec string) (*ServerAddress, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty server address")
+	}
+
+	scheme, rest, found := strings.Cut(spec, ":")
+	switch {
+	case strings.HasPrefix(spec, "/"):
+		return &ServerAddress{Network: "unix", Address: spec}, nil
+
+	case found && scheme == "tcp":
+		if port, err := strconv.Atoi(rest); err == nil {
+			return newTCPServerAddress("127.0.0.1", port)
+		}
+		host, portStr, err := net.SplitHostPort(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server address '%s': %v", spec, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server port '%s'", portStr)
+		}
+		return newTCPServerAddress(host, port)
+
+	case found && scheme == "localabstract":
+		if rest == "" {
+			return nil, fmt.Errorf("invalid server address '%s': empty socket name", spec)
+		}
+		return &ServerAddress{Network: "unix", Address: "@" + rest}, nil
+
+	case found && (scheme == "localfilesystem" || scheme == "unix"):
+		if rest == "" {
+			return nil, fmt.Errorf("invalid server address '%s': empty socket path", spec)
+		}
+		return &ServerAddress{Network: "unix", Address: rest}, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(spec)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported server address '%s'", spec)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server port '%s'", portStr)
+	}
+	return newTCPServerAddress(host, port)
+}
+
+// newTCPServerAddress 创建TCP服务器地址
+func newTCPServerAddress(host string, port int) (*ServerAddress, error) {
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid server port %d", port)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return &ServerAddress{
+		Network: "tcp",
+		Address: net.JoinHostPort(host, strconv.Itoa(port)),
+	}, nil
+}
+
+// IsLocal 检查服务器是否运行在本机，只有本机服务器才会被自动启动
+func (a *ServerAddress) IsLocal() bool {
+	if a.Network == "unix" {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(a.Address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ServerArgs 返回启动该地址上的服务器时需要传给adb的参数
+func (a *ServerAddress) ServerArgs() []string {
+	if a.Network == "unix" {
+		if strings.HasPrefix(a.Address, "@") {
+			return []string{"-L", "localabstract:" + a.Address[1:]}
+		}
+		return []string{"-L", "localfilesystem:" + a.Address}
+	}
+
+	_, port, err := net.SplitHostPort(a.Address)
+	if err != nil || port == "5037" {
+		return nil
+	}
+	return []string{"-P", port}
+}
+
+// String 返回adb格式的服务器地址
+func (a *ServerAddress) String() string {
+	if a.Network == "unix" {
+		if strings.HasPrefix(a.Address, "@") {
+			return "localabstract:" + a.Address[1:]
+		}
+		return "localfilesystem:" + a.Address
+	}
+	return "tcp:" + a.Address
+}
+
+// ServerAddress 解析客户端选项中的服务器地址
+// 优先级: Options.ServerSocket > 显式设置的Options.Host:Options.Port > ADB_SERVER_SOCKET 环境变量 > 默认地址。
+// Host为空且Port为0时视为未设置，此时才使用环境变量；显式设置的5037同样优先于环境变量
+func (o *Options) ServerAddress() (*ServerAddress, error) {
+	if o.ServerSocket != "" {
+		return ParseServerAddress(o.ServerSocket)
+	}
+	if o.Host == "" && o.Port == 0 {
+		if spec := os.Getenv(ServerSocketEnv); spec != "" {
+			return ParseServerAddress(spec)
+		}
+	}
+
+	port := o.Port
+	if port == 0 {
+		port = 5037
+	}
+	return newTCPServerAddress(o.Host, port)
+}
@@ -0,0 +1,51 @@
+package adb_test
+
+import (
+	"testing"
+
+	"adb-kit-go/pkg/adb"
+)
+
+func TestServerAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		options adb.Options
+		env     string
+		want    string
+	}{
+		{"default", adb.Options{}, "", "tcp:127.0.0.1:5037"},
+		{"env", adb.Options{}, "tcp:10.0.0.2:5038", "tcp:10.0.0.2:5038"},
+		{"env unix", adb.Options{}, "localabstract:adb", "localabstract:adb"},
+		{"port over env", adb.Options{Port: 6000}, "tcp:10.0.0.2:5038", "tcp:127.0.0.1:6000"},
+		{"default port over env", adb.Options{Port: 5037}, "tcp:10.0.0.2:5038", "tcp:127.0.0.1:5037"},
+		{"host over env", adb.Options{Host: "10.0.0.3"}, "tcp:10.0.0.2:5038", "tcp:10.0.0.3:5037"},
+		{"server socket over all", adb.Options{ServerSocket: "tcp:7000", Host: "10.0.0.3", Port: 6000}, "tcp:10.0.0.2:5038", "tcp:127.0.0.1:7000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(adb.ServerSocketEnv, tt.env)
+			address, err := tt.options.ServerAddress()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := address.String(); got != tt.want {
+				t.Errorf("ServerAddress = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewClientKeepsPortUnset NewClient不填充默认端口，之后仍能使用环境变量
+func TestNewClientKeepsPortUnset(t *testing.T) {
+	t.Setenv(adb.ServerSocketEnv, "tcp:10.0.0.2:5038")
+	options := &adb.Options{}
+	adb.NewClient(options)
+	address, err := options.ServerAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := address.String(), "tcp:10.0.0.2:5038"; got != want {
+		t.Errorf("ServerAddress = %s, want %s", got, want)
+	}
+}
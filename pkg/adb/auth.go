@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"crypto/md5"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"math/big"
 	"strings"
@@ -35,30 +37,93 @@ func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
 		return nil, fmt.Errorf("invalid public key: empty data")
 	}
 
-	// 解析base64数据和注释
-	parts := bytes.Split(data, []byte{0})
-	if len(parts) < 1 {
-		return nil, fmt.Errorf("invalid public key format")
+	keyData, comment, err := splitPublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePublicKeyFromStruct(keyData, comment)
+}
+
+// ParsePublicKeyInfo 解析公钥数据，同时返回指纹和注释
+// 指纹为公钥结构的MD5，以冒号分隔的十六进制表示，与adbkit一致
+func ParsePublicKeyInfo(data []byte) (*ExtendedRSAPublicKey, error) {
+	keyData, comment, err := splitPublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parsePublicKeyFromStruct(keyData, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := md5.Sum(keyData)
+	pairs := make([]string, len(sum))
+	for i, b := range sum {
+		pairs[i] = hex.EncodeToString([]byte{b})
+	}
+	return NewExtendedRSAPublicKey(key, strings.Join(pairs, ":"), comment), nil
+}
+
+// splitPublicKey 拆分adbkey.pub的base64数据和注释
+// 两者之间以空格分隔，base64数据之后可能带有NUL字符
+func splitPublicKey(data []byte) ([]byte, string, error) {
+	encoded, comment, _ := bytes.Cut(bytes.TrimSpace(data), []byte{' '})
+	encoded = bytes.TrimRight(encoded, "\x00")
+	if i := bytes.IndexByte(encoded, 0); i >= 0 {
+		// 旧格式以NUL分隔注释
+		comment = encoded[i+1:]
+		encoded = encoded[:i]
 	}
 
 	// 解码base64数据
-	keyData, err := base64.StdEncoding.DecodeString(string(parts[0]))
+	keyData, err := base64.StdEncoding.DecodeString(string(encoded))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode base64: %v", err)
+		return nil, "", fmt.Errorf("failed to decode base64: %w", err)
 	}
+	return keyData, strings.TrimSpace(string(comment)), nil
+}
 
-	// 获取注释（如果存在）
-	comment := ""
-	if len(parts) > 1 {
-		comment = strings.TrimSpace(string(parts[1]))
+// PublicKeyToPem 将公钥编码为PEM格式的PKIX公钥
+func PublicKeyToPem(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
 	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
 
-	return parsePublicKeyFromStruct(keyData, comment)
+// PublicKeyToOpenSSH 将公钥编码为OpenSSH authorized_keys格式
+func PublicKeyToOpenSSH(key *rsa.PublicKey, comment string) string {
+	var buf bytes.Buffer
+	writeString := func(b []byte) {
+		binary.Write(&buf, binary.BigEndian, uint32(len(b)))
+		buf.Write(b)
+	}
+	// mpint以大端表示，最高位为1时需要补零以表示正数
+	writeMpint := func(n *big.Int) {
+		b := n.Bytes()
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		writeString(b)
+	}
+
+	writeString([]byte("ssh-rsa"))
+	writeMpint(big.NewInt(int64(key.E)))
+	writeMpint(key.N)
+
+	line := "ssh-rsa " + base64.StdEncoding.EncodeToString(buf.Bytes())
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
 }
 
 // parsePublicKeyFromStruct 从二进制结构解析公钥
 func parsePublicKeyFromStruct(data []byte, comment string) (*rsa.PublicKey, error) {
-	if len(data) == 0 {
+	if len(data) < 4 {
 		return nil, fmt.Errorf("invalid public key")
 	}
 
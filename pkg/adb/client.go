@@ -1,21 +1,87 @@
 package adb
 
 import (
-	"adb-kit-go/pkg/adb/command/host"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
+	"time"
+
+	"adb-kit-go/pkg/adb/command/host"
+	hostserial "adb-kit-go/pkg/adb/command/host-serial"
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+	"adb-kit-go/pkg/adb/retry"
+	"adb-kit-go/pkg/adb/session"
+	"adb-kit-go/pkg/adb/shellcmd"
+	adbsync "adb-kit-go/pkg/adb/sync"
+	"adb-kit-go/pkg/adb/tcpusb"
 )
 
 // Client ADB客户端
 type Client struct {
 	options *Options
 	mu      sync.Mutex
+	device  *DeviceClient  // 非空时所有传输都固定到该设备，见Client.Device
+	devices *deviceList    // TrackDeviceList开启时维护的设备列表
+	slots   chan struct{}  // 限制同时打开传输的数量，为空时不限制
+	props   *propertyCache // 设备查询缓存的系统属性
+	// features 服务器和设备支持的特性，用于选择协议版本
+	features *featureCache
 }
 
 // Options 客户端配置选项
 type Options struct {
-	Port int    // ADB服务器端口
-	Bin  string // ADB可执行文件路径
+	Host         string // ADB服务器主机，默认为127.0.0.1
+	Port         int    // ADB服务器端口，为0时使用ADB_SERVER_SOCKET或默认的5037
+	ServerSocket string // 完整的服务器地址，如tcp:host:port、localabstract:name，优先于Host和Port
+	Bin          string // ADB可执行文件路径
+	// Retry 连接服务器、打开传输和幂等命令的重试策略，为空时不重试
+	// 本机服务器停止后，重试时会自动重新启动服务器
+	Retry *RetryPolicy
+	// MaxConcurrentTransports 同时打开设备传输的最大数量，0表示不限制
+	// 大量设备同时轮询时可避免瞬间向服务器发起过多连接
+	MaxConcurrentTransports int
+	// TrackDeviceList 通过一个常驻的host:track-devices-l连接维护设备列表，
+	// ListDevices直接返回最新列表而不再每次新建连接，使用完毕后需调用Client.Close；
+	// 跟踪连接断开到重新同步之前改用单次查询
+	TrackDeviceList bool
+	// Logger 记录发送的命令、应答状态、流量和耗时，为空时不记录
+	// 每个客户端使用自己的Logger，同一进程中的多个客户端互不影响
+	Logger *slog.Logger
+	// LogPayloads 在Debug级别以十六进制记录收发的全部数据，需要同时设置Logger
+	LogPayloads bool
+	// Recorder 将与服务器之间的全部通信录制到文件，可以用session.Server回放
+	// 回放要求客户端发送与录制时相同的数据，录制和回放时应设置同样的Clock和Sentinel
+	Recorder *session.Recorder
+	// Clock 返回推送文件时写入的修改时间，为空时使用time.Now
+	Clock func() time.Time
+	// Sentinel 生成不支持shell_v2时包装命令所用的哨兵，为空时随机生成，
+	// 录制和回放时可以使用hosttransport.NewSentinelSequence
+	Sentinel func() string
+	// Metrics 统计命令、失败、传输字节数与吞吐量、打开的传输和活动的跟踪器，为空时不统计
+	// 可使用 NewMetricsRegistry 创建内置实现
+	Metrics Metrics
+}
+
+// now 返回Clock给出的当前时间
+func (o *Options) now() time.Time {
+	if o.Clock != nil {
+		return o.Clock()
+	}
+	return time.Now()
+}
+
+// sentinel 返回新的哨兵
+func (o *Options) sentinel() string {
+	if o.Sentinel != nil {
+		return o.Sentinel()
+	}
+	return hosttransport.NewSentinel()
 }
 
 // NewClient 创建新的ADB客户端
@@ -23,180 +89,690 @@ func NewClient(options *Options) *Client {
 	if options == nil {
 		options = &Options{}
 	}
-	if options.Port == 0 {
-		options.Port = 5037
-	}
 	if options.Bin == "" {
 		options.Bin = "adb"
 	}
 
-	return &Client{
-		options: options,
+	client := &Client{
+		options:  options,
+		devices:  &deviceList{},
+		props:    &propertyCache{},
+		features: &featureCache{},
+	}
+	if options.MaxConcurrentTransports > 0 {
+		client.slots = make(chan struct{}, options.MaxConcurrentTransports)
 	}
+	return client
+}
+
+// Close 关闭客户端维护的常驻连接
+func (c *Client) Close() error {
+	return c.devices.close()
 }
 
 // CreateConnection 创建新的连接
 func (c *Client) CreateConnection() (*Connection, error) {
-	conn := NewConnection(c.options)
+	return c.CreateConnectionContext(context.Background())
+}
 
-	err := conn.Connect()
+// CreateConnectionContext 创建新的连接，上下文结束时取消拨号
+func (c *Client) CreateConnectionContext(ctx context.Context) (*Connection, error) {
+	var conn *Connection
+	err := c.retry(ctx, func(ctx context.Context) error {
+		conn = NewConnection(c.options)
+		return conn.ConnectContext(ctx)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("connection failed: %v", err)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("connection failed: %w", err)
 	}
 
 	return conn, nil
 }
 
-// Version 获取ADB服务器版本
-func (c *Client) Version() (int, error) {
-	conn, err := c.CreateConnection()
+// retry 按客户端的重试策略执行操作，嵌套调用时只有最外层重试
+func (c *Client) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	policy := c.options.Retry
+	if policy != nil && c.options.Logger != nil {
+		logged := *policy
+		logged.OnRetry = func(attempt int, err error, delay time.Duration) {
+			c.options.Logger.Warn("adb retry", "attempt", attempt, "error", err, "delay", delay)
+			if policy.OnRetry != nil {
+				policy.OnRetry(attempt, err, delay)
+			}
+		}
+		policy = &logged
+	}
+	return policy.Do(ctx, fn)
+}
+
+// withConnection 在新的连接上执行操作，上下文结束时关闭连接
+func (c *Client) withConnection(ctx context.Context, fn func(conn *Connection) error) error {
+	conn, err := c.CreateConnectionContext(ctx)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer conn.Close()
 
-	cmd := host.NewVersionCommand(conn.reader, conn.writer)
-	return cmd.Execute()
+	stop := conn.Watch(ctx)
+	defer stop()
+
+	return contextError(ctx, fn(conn))
+}
+
+// retryConnection 与withConnection相同，用于幂等操作，失败时按重试策略重新执行
+func (c *Client) retryConnection(ctx context.Context, fn func(conn *Connection) error) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		return c.withConnection(ctx, fn)
+	})
+}
+
+// withTransport 在设备传输上执行操作，上下文结束时关闭传输
+func (c *Client) withTransport(ctx context.Context, serial string, fn func(conn *Connection) error) error {
+	transport, err := c.TransportContext(ctx, serial)
+	if err != nil {
+		return err
+	}
+	defer transport.Close()
+
+	stop := transport.conn.Watch(ctx)
+	defer stop()
+
+	return contextError(ctx, fn(transport.conn))
+}
+
+// retryTransport 与withTransport相同，用于幂等操作，失败时按重试策略重新执行
+func (c *Client) retryTransport(ctx context.Context, serial string, fn func(conn *Connection) error) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		return c.withTransport(ctx, serial, fn)
+	})
+}
+
+// Version 获取ADB服务器版本
+func (c *Client) Version() (int, error) {
+	return c.VersionContext(context.Background())
+}
+
+// VersionContext 获取ADB服务器版本
+func (c *Client) VersionContext(ctx context.Context) (int, error) {
+	var version int
+	err := c.retryConnection(ctx, func(conn *Connection) error {
+		value, err := host.NewVersionCommand(conn.Send, conn.ReadString).Execute()
+		if err != nil {
+			return err
+		}
+		if v, ok := value.(int64); ok {
+			version = int(v)
+		}
+		return nil
+	})
+	return version, err
 }
 
 // Connect 连接到设备
 func (c *Client) Connect(host string, port int) error {
+	return c.ConnectContext(context.Background(), host, port)
+}
+
+// ConnectContext 连接到设备
+func (c *Client) ConnectContext(ctx context.Context, hostname string, port int) error {
 	if port == 0 {
 		port = 5555
 	}
 
-	conn, err := c.CreateConnection()
-	if err != nil {
+	return c.withConnection(ctx, func(conn *Connection) error {
+		_, err := host.NewConnectCommand(conn.Send, conn.ReadString).Execute(hostname, strconv.Itoa(port))
 		return err
-	}
-	defer conn.Close()
-
-	cmd := NewHostConnectCommand(conn)
-	return cmd.Execute(host, port)
+	})
 }
 
 // Disconnect 断开设备连接
 func (c *Client) Disconnect(host string, port int) error {
+	return c.DisconnectContext(context.Background(), host, port)
+}
+
+// DisconnectContext 断开设备连接
+func (c *Client) DisconnectContext(ctx context.Context, hostname string, port int) error {
 	if port == 0 {
 		port = 5555
 	}
 
-	conn, err := c.CreateConnection()
-	if err != nil {
+	return c.withConnection(ctx, func(conn *Connection) error {
+		_, err := host.NewDisconnectCommand(conn.Send, conn.ReadString).Execute(hostname, strconv.Itoa(port))
 		return err
-	}
-	defer conn.Close()
-
-	cmd := NewHostDisconnectCommand(conn)
-	return cmd.Execute(host, port)
+	})
 }
 
 // ListDevices 列出所有设备
 func (c *Client) ListDevices() ([]Device, error) {
-	conn, err := c.CreateConnection()
+	return c.ListDevicesContext(context.Background())
+}
+
+// ListDevicesContext 列出所有设备，与跟踪器一样通过host:devices-l读取USB路径、型号和传输ID等字段
+func (c *Client) ListDevicesContext(ctx context.Context) ([]Device, error) {
+	if c.options.TrackDeviceList {
+		// 跟踪连接不可用或断开后尚未重新同步时退回到单次查询
+		if devices, err := c.devices.get(ctx, c); err == nil || ctx.Err() != nil {
+			return devices, err
+		}
+	}
+
+	devices, err := c.queryDevices(ctx, true)
+	if errors.Is(err, ErrUnknownService) {
+		// 旧版本的服务器不支持host:devices-l，此时设备只有序列号和状态
+		return c.queryDevices(ctx, false)
+	}
+	return devices, err
+}
+
+// queryDevices 通过单次的host:devices-l或host:devices查询设备列表
+func (c *Client) queryDevices(ctx context.Context, long bool) ([]Device, error) {
+	var devices []Device
+	err := c.retryConnection(ctx, func(conn *Connection) error {
+		var value interface{}
+		var err error
+		if long {
+			value, err = host.NewDevicesWithPathsCommand(conn.Send, conn.ReadString).Execute()
+		} else {
+			value, err = host.NewDevicesCommand(conn.Send, conn.ReadString).Execute()
+		}
+		if err != nil {
+			return err
+		}
+		list, _ := value.([]host.Device)
+		devices = make([]Device, 0, len(list))
+		for _, d := range list {
+			devices = append(devices, *newDeviceFromHost(d))
+		}
+		return nil
+	})
+	return devices, err
+}
+
+// TrackDevices 跟踪设备变化
+// 与ADB服务器的连接断开（如服务器重启）时自动按退避策略重新连接，调用Stop结束跟踪
+func (c *Client) TrackDevices() (*Tracker, error) {
+	return c.TrackDevicesContext(context.Background())
+}
+
+// TrackDevicesContext 跟踪设备变化，上下文结束时停止跟踪
+func (c *Client) TrackDevicesContext(ctx context.Context) (*Tracker, error) {
+	return c.openTracker(ctx, ctx)
+}
+
+// openTracker 建立断开后自动重连的设备跟踪器，ctx用于取消建立过程，lifetime结束时停止跟踪
+func (c *Client) openTracker(ctx, lifetime context.Context) (*Tracker, error) {
+	conn, err := c.openTrackerConn(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
 
-	cmd := NewHostDevicesCommand(conn)
-	return cmd.Execute()
+	// 重新连接使用不限次数的退避，等待时间沿用客户端的重试策略
+	backoff := retry.Default()
+	if c.options.Retry != nil {
+		backoff = c.options.Retry
+	}
+	return newTracker(lifetime, conn, c.openTrackerConn, backoff), nil
 }
 
-// Transport 创建设备传输
-func (c *Client) Transport(serial string) (*Transport, error) {
-	conn, err := c.CreateConnection()
+// openTrackerConn 建立设备跟踪连接，优先使用host:track-devices-l
+func (c *Client) openTrackerConn(ctx context.Context) (*Connection, error) {
+	conn, err := c.trackDevices(ctx, host.NewTrackDevicesLongCommand)
+	if errors.Is(err, ErrUnknownService) {
+		// 旧版本的服务器不支持host:track-devices-l，此时设备只有序列号和状态
+		return c.trackDevices(ctx, host.NewTrackDevicesCommand)
+	}
+	return conn, err
+}
+
+// trackDevices 在新的连接上发送跟踪命令
+func (c *Client) trackDevices(ctx context.Context, newCommand func(func(string) error, func(int) (string, error), func([]host.Device)) *host.TrackDevicesCommand) (*Connection, error) {
+	conn, err := c.CreateConnectionContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := NewHostTransportCommand(conn)
-	err = cmd.Execute(serial)
+	stop := conn.Watch(ctx)
+	_, err = newCommand(conn.Send, conn.ReadString, nil).Execute()
+	stop()
 	if err != nil {
 		conn.Close()
+		return nil, contextError(ctx, err)
+	}
+
+	return conn, nil
+}
+
+// Transport 创建设备传输
+func (c *Client) Transport(serial string) (*Transport, error) {
+	return c.TransportContext(context.Background(), serial)
+}
+
+// TransportContext 创建设备传输，上下文结束时取消建立过程
+func (c *Client) TransportContext(ctx context.Context, serial string) (*Transport, error) {
+	if c.device != nil {
+		return c.device.transport(ctx)
+	}
+
+	transport, err := c.TransportSelectorContext(ctx, host.SelectSerial(serial))
+	if errors.Is(err, ErrDeviceNotFound) || errors.Is(err, ErrDeviceOffline) {
+		// 设备重新连接后特性可能不同，如系统升级之后
+		c.features.forget(serial)
+	}
+	return transport, err
+}
+
+// TransportSelector 按选择方式创建设备传输，如按传输ID、仅USB设备或仅模拟器
+func (c *Client) TransportSelector(selector host.Selector) (*Transport, error) {
+	return c.TransportSelectorContext(context.Background(), selector)
+}
+
+// TransportSelectorContext 按选择方式创建设备传输，上下文结束时取消建立过程
+func (c *Client) TransportSelectorContext(ctx context.Context, selector host.Selector) (*Transport, error) {
+	return c.openTransport(ctx, selector, func(conn *Connection) error {
+		_, err := host.NewTransportCommand(conn.Send, conn.ReadString).ExecuteSelector(selector)
+		return err
+	})
+}
+
+// TransportID 查询设备当前的传输ID
+func (c *Client) TransportID(selector host.Selector) (uint64, error) {
+	return c.TransportIDContext(context.Background(), selector)
+}
+
+// TransportIDContext 通过host:tport查询设备当前的传输ID
+func (c *Client) TransportIDContext(ctx context.Context, selector host.Selector) (uint64, error) {
+	var id uint64
+	transport, err := c.openTransport(ctx, selector, func(conn *Connection) error {
+		var err error
+		id, err = host.NewTportCommand(conn.Send, conn.ReadString).Execute(selector)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	transport.Close()
+
+	return id, nil
+}
+
+// openTransport 在新的连接上执行传输切换命令
+// 设备离线或服务器断开等暂时性错误按重试策略重新打开
+func (c *Client) openTransport(ctx context.Context, selector host.Selector, switchTo func(conn *Connection) error) (*Transport, error) {
+	var transport *Transport
+	err := c.retry(ctx, func(ctx context.Context) error {
+		release, err := c.acquireSlot(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		conn, err := c.CreateConnectionContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		if c.options.Logger != nil {
+			conn.logWith(c.transportAttrs(ctx, selector)...)
+		}
+		stop := conn.Watch(ctx)
+		err = switchTo(conn)
+		stop()
+		if err != nil {
+			conn.Close()
+			return contextError(ctx, err)
+		}
+
+		conn.markTransport()
+		transport = NewTransport(conn)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return NewTransport(conn), nil
+	return transport, nil
+}
+
+// acquireSlot 等待打开传输的名额，返回释放名额的函数
+func (c *Client) acquireSlot(ctx context.Context) (func(), error) {
+	if c.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return func() { <-c.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 // Shell 执行Shell命令
 func (c *Client) Shell(serial string, command string) (*ShellResponse, error) {
-	transport, err := c.Transport(serial)
+	return c.ShellContext(context.Background(), serial, command)
+}
+
+// ShellContext 执行Shell命令，上下文结束时关闭传输
+// 关闭传输不会结束设备上的命令，需要超时后结束命令或需要退出码时使用RunShellContext
+// command按原样交给设备shell，不会引用；其中含有外部数据时应先用shellcmd构建命令行
+func (c *Client) ShellContext(ctx context.Context, serial string, command string) (*ShellResponse, error) {
+	response := &ShellResponse{}
+	err := c.withTransport(ctx, serial, func(conn *Connection) error {
+		reader, err := hosttransport.NewShellCommand(conn.Send, conn.ReadString).Execute(command)
+		if err != nil {
+			return err
+		}
+
+		output, err := io.ReadAll(reader)
+		response.Output = string(output)
+		response.Error = err
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	if response.Error != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 
-	cmd := NewShellCommand(transport)
-	return cmd.Execute(command)
+	return response, nil
 }
 
 // Install 安装APK
 func (c *Client) Install(serial string, apkPath string) error {
-	transport, err := c.Transport(serial)
+	return c.InstallContext(context.Background(), serial, apkPath)
+}
+
+// InstallContext 推送本地APK到临时目录并安装
+func (c *Client) InstallContext(ctx context.Context, serial string, apkPath string) error {
+	dialect := c.dialect(ctx, serial)
+	temp := TEMP_PATH + "/" + filepath.Base(apkPath)
+	if err := c.PushContext(ctx, serial, apkPath, temp); err != nil {
+		return err
+	}
+
+	useCmd := c.supports(ctx, serial, FeatureCmd)
+	err := c.withTransport(ctx, serial, func(conn *Connection) error {
+		command := withDialect(hosttransport.NewInstallCommand(conn.Send, conn.ReadString), dialect)
+		if useCmd {
+			command.UseCmd()
+		}
+		return command.Execute(temp)
+	})
 	if err != nil {
 		return err
 	}
 
-	cmd := NewInstallCommand(transport)
-	return cmd.Execute(apkPath)
+	remove, err := shellcmd.New("rm", "-f", temp).Dialect(dialect).Build()
+	if err != nil {
+		return err
+	}
+	_, err = c.ShellContext(ctx, serial, remove)
+	return err
 }
 
 // Uninstall 卸载应用
 func (c *Client) Uninstall(serial string, packageName string) error {
-	transport, err := c.Transport(serial)
+	return c.UninstallContext(context.Background(), serial, packageName)
+}
+
+// UninstallContext 卸载应用
+func (c *Client) UninstallContext(ctx context.Context, serial string, packageName string) error {
+	dialect := c.dialect(ctx, serial)
+	useCmd := c.supports(ctx, serial, FeatureCmd)
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		command := withDialect(hosttransport.NewUninstallCommand(conn.Send, conn.ReadString), dialect)
+		if useCmd {
+			command.UseCmd()
+		}
+		return command.Execute(packageName)
+	})
+}
+
+// SyncService 打开设备的同步服务
+func (c *Client) SyncService(serial string) (*Sync, error) {
+	return c.SyncServiceContext(context.Background(), serial)
+}
+
+// SyncServiceContext 打开设备的同步服务，上下文结束时取消建立过程
+func (c *Client) SyncServiceContext(ctx context.Context, serial string) (*Sync, error) {
+	transport, err := c.TransportContext(ctx, serial)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	stop := transport.conn.Watch(ctx)
+	err = transport.conn.Send("sync:")
+	if err == nil {
+		err = readStatus(transport.conn)
+	}
+	stop()
+	if err != nil {
+		transport.Close()
+		return nil, contextError(ctx, err)
 	}
 
-	cmd := NewUninstallCommand(transport)
-	return cmd.Execute(packageName)
+	syncService := NewSync(transport.conn)
+	syncService.statV2 = c.supports(ctx, serial, FeatureStat2)
+	syncService.lsV2 = c.supports(ctx, serial, FeatureLs2)
+	return syncService, nil
 }
 
 // Push 推送文件到设备
 func (c *Client) Push(serial string, local string, remote string) error {
-	transport, err := c.Transport(serial)
+	return c.PushContext(context.Background(), serial, local, remote)
+}
+
+// PushContext 推送文件到设备，上下文结束时中止传输
+func (c *Client) PushContext(ctx context.Context, serial string, local string, remote string) error {
+	syncService, err := c.SyncServiceContext(ctx, serial)
 	if err != nil {
 		return err
 	}
+	defer syncService.End()
+
+	transfer, err := syncService.PushFileContext(ctx, local, remote, DEFAULT_CHMOD)
+	if err != nil {
+		return contextError(ctx, err)
+	}
 
-	cmd := NewSyncCommand(transport)
-	return cmd.Push(local, remote)
+	return transfer.Wait()
 }
 
 // Pull 从设备拉取文件
 func (c *Client) Pull(serial string, remote string, local string) error {
-	transport, err := c.Transport(serial)
+	return c.PullContext(context.Background(), serial, remote, local)
+}
+
+// PullContext 从设备拉取文件，上下文结束时中止传输
+func (c *Client) PullContext(ctx context.Context, serial string, remote string, local string) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		return c.pull(ctx, serial, remote, local)
+	})
+}
+
+// pull 拉取文件到本地，每次调用都会重新创建本地文件
+func (c *Client) pull(ctx context.Context, serial string, remote string, local string) error {
+	syncService, err := c.SyncServiceContext(ctx, serial)
+	if err != nil {
+		return err
+	}
+	defer syncService.End()
+
+	transfer, err := syncService.PullContext(ctx, remote)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	file, err := os.Create(local)
 	if err != nil {
+		transfer.Cancel()
 		return err
 	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, transfer); err != nil {
+		return contextError(ctx, err)
+	}
+
+	return transfer.Wait()
+}
+
+// Stat 获取设备上文件的状态
+func (c *Client) Stat(serial string, path string) (*adbsync.Stats, error) {
+	return c.StatContext(context.Background(), serial, path)
+}
+
+// StatContext 获取设备上文件的状态，文件不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist)
+func (c *Client) StatContext(ctx context.Context, serial string, path string) (*adbsync.Stats, error) {
+	var stats *adbsync.Stats
+	err := c.withSync(ctx, serial, func(syncService *Sync) (err error) {
+		stats, err = syncService.Stat(path)
+		return err
+	})
+	return stats, err
+}
+
+// ReadDir 列出设备上目录中的条目
+func (c *Client) ReadDir(serial string, path string) ([]*adbsync.Entry, error) {
+	return c.ReadDirContext(context.Background(), serial, path)
+}
 
-	cmd := NewSyncCommand(transport)
-	return cmd.Pull(remote, local)
+// ReadDirContext 通过同步协议列出设备上目录中的条目，不包含.和..
+// 目录不存在或不是目录时返回空列表，与adb ls相同
+func (c *Client) ReadDirContext(ctx context.Context, serial string, path string) ([]*adbsync.Entry, error) {
+	var entries []*adbsync.Entry
+	err := c.withSync(ctx, serial, func(syncService *Sync) (err error) {
+		entries, err = syncService.ReadDir(path)
+		return err
+	})
+	return entries, err
+}
+
+// withSync 打开同步服务执行一组请求，上下文结束时断开连接，连接错误时按重试策略重新执行
+func (c *Client) withSync(ctx context.Context, serial string, fn func(syncService *Sync) error) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		syncService, err := c.SyncServiceContext(ctx, serial)
+		if err != nil {
+			return err
+		}
+		defer syncService.End()
+
+		stop := syncService.conn.Watch(ctx)
+		defer stop()
+
+		return contextError(ctx, fn(syncService))
+	})
 }
 
 // Forward 端口转发
 func (c *Client) Forward(serial string, local string, remote string) error {
-	conn, err := c.CreateConnection()
+	return c.ForwardContext(context.Background(), serial, local, remote)
+}
+
+// ForwardContext 端口转发
+func (c *Client) ForwardContext(ctx context.Context, serial string, local string, remote string) error {
+	selector, err := c.selectorOf(ctx, serial)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
 
-	cmd := NewForwardCommand(conn)
-	return cmd.Execute(serial, local, remote)
+	return c.withConnection(ctx, func(conn *Connection) error {
+		_, err := hostserial.NewForwardCommand(conn.Send, conn.ReadString).ExecuteSelector(selector, local, remote)
+		return err
+	})
 }
 
-// CreateTcpUsbBridge 创建TCP/USB桥接
-func (c *Client) CreateTcpUsbBridge(serial string, options map[string]interface{}) (*TcpUsbServer, error) {
-	return NewTcpUsbServer(c, serial, options), nil
+// OpenLogcat 打开logcat日志流
+func (c *Client) OpenLogcat(serial string, options *hosttransport.LogcatOptions) (io.ReadCloser, error) {
+	return c.OpenLogcatContext(context.Background(), serial, options)
+}
+
+// OpenLogcatContext 打开logcat日志流，上下文结束或关闭流时断开传输
+func (c *Client) OpenLogcatContext(ctx context.Context, serial string, options *hosttransport.LogcatOptions) (io.ReadCloser, error) {
+	dialect := c.dialect(ctx, serial)
+	return c.openStream(ctx, serial, func(conn *Connection) (io.Reader, error) {
+		if _, err := withDialect(hosttransport.NewLogcatCommand(conn.Send, conn.ReadString), dialect).Execute(options); err != nil {
+			return nil, err
+		}
+		// 直接读取连接以避免按块读取带来的延迟，并去掉echo输出的换行
+		return NewTransformReader(conn, map[string]interface{}{"autoDetect": true}), nil
+	})
+}
+
+// OpenMonkey 在设备上启动monkey并返回其输出流
+func (c *Client) OpenMonkey(serial string, port int) (io.ReadCloser, error) {
+	return c.OpenMonkeyContext(context.Background(), serial, port)
+}
+
+// OpenMonkeyContext 在设备上启动monkey，上下文结束或关闭流时断开传输
+func (c *Client) OpenMonkeyContext(ctx context.Context, serial string, port int) (io.ReadCloser, error) {
+	dialect := c.dialect(ctx, serial)
+	if port == 0 {
+		port = 1080
+	}
+
+	return c.openStream(ctx, serial, func(conn *Connection) (io.Reader, error) {
+		return withDialect(hosttransport.NewMonkeyCommand(conn.Send, conn.ReadString), dialect).Execute(port)
+	})
+}
+
+// TrackJdwp 跟踪设备上可调试的进程
+func (c *Client) TrackJdwp(serial string) (*hosttransport.JdwpTracker, error) {
+	return c.TrackJdwpContext(context.Background(), serial)
+}
+
+// TrackJdwpContext 跟踪设备上可调试的进程，上下文结束时停止跟踪
+func (c *Client) TrackJdwpContext(ctx context.Context, serial string) (*hosttransport.JdwpTracker, error) {
+	transport, err := c.TransportContext(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := transport.conn.Watch(ctx)
+	tracker, err := hosttransport.NewTrackJdwpCommand(transport.conn.Send, transport.conn.ReadString).Execute()
+	stop()
+	if err != nil {
+		transport.Close()
+		return nil, contextError(ctx, err)
+	}
+
+	endOnDone(ctx, tracker.End, func(onEnd func()) {
+		tracker.On("end", func(string) { onEnd() })
+	})
+	tracker.On("end", func(string) { transport.Close() })
+
+	return tracker, nil
+}
+
+// openStream 在设备传输上打开一个长时间运行的输出流
+func (c *Client) openStream(ctx context.Context, serial string, open func(conn *Connection) (io.Reader, error)) (io.ReadWriteCloser, error) {
+	transport, err := c.TransportContext(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := transport.conn.Watch(ctx)
+	reader, err := open(transport.conn)
+	if err != nil {
+		stop()
+		transport.Close()
+		return nil, contextError(ctx, err)
+	}
+
+	return &transportStream{
+		Reader: reader,
+		conn:   transport.conn,
+		stop:   stop,
+	}, nil
 }
 
-// Device 设备信息
-type Device struct {
-	Serial string
-	State  string
-	Path   string
+// CreateTcpUsbBridge 创建TCP/USB桥接
+func (c *Client) CreateTcpUsbBridge(serial string, options map[string]interface{}) (*tcpusb.Server, error) {
+	return tcpusb.NewServer(c, serial, options), nil
 }
 
 // ShellResponse Shell命令响应
@@ -217,3 +793,92 @@ func NewTransport(conn *Connection) *Transport {
 func (t *Transport) Close() error {
 	return t.conn.Close()
 }
+
+// transportStream 将命令数据流与其传输连接绑定，关闭时释放连接
+type transportStream struct {
+	io.Reader
+	conn *Connection
+	stop func()
+}
+
+// Read 实现io.Reader接口，上下文结束导致的错误返回上下文错误
+func (s *transportStream) Read(p []byte) (int, error) {
+	n, err := s.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		if ctxErr := s.conn.Err(); ctxErr != nil {
+			return n, ctxErr
+		}
+	}
+	return n, err
+}
+
+// Write 向传输连接写入数据，用于双向的数据流
+func (s *transportStream) Write(p []byte) (int, error) {
+	return s.conn.Write(p)
+}
+
+// Close 关闭输出流及其传输连接
+func (s *transportStream) Close() error {
+	s.stop()
+	return s.conn.Close()
+}
+
+// readStatus 读取OKAY/FAIL状态
+func readStatus(conn *Connection) error {
+	reply, err := conn.ReadString(4)
+	if err != nil {
+		return err
+	}
+
+	switch reply {
+	case OKAY:
+		return nil
+	case FAIL:
+		message, err := conn.ReadString(0)
+		if err != nil {
+			return err
+		}
+		return &FailError{Message: message}
+	default:
+		return &UnexpectedDataError{Unexpected: reply, Expected: "OKAY or FAIL"}
+	}
+}
+
+// endOnDone 在上下文结束时调用end，跟踪结束后停止监听
+func endOnDone(ctx context.Context, end func(), onEnd func(func())) {
+	if ctx.Done() == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	onEnd(func() {
+		once.Do(func() { close(done) })
+	})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			end()
+		case <-done:
+		}
+	}()
+}
+
+// selectorOf 返回host-serial命令使用的设备选择方式，绑定到设备的客户端会先解析设备
+func (c *Client) selectorOf(ctx context.Context, serial string) (host.Selector, error) {
+	if c.device != nil {
+		return c.device.hostSelector(ctx)
+	}
+	return host.SelectSerial(serial), nil
+}
+
+// contextError 上下文结束导致的错误统一返回上下文错误
+func contextError(ctx context.Context, err error) error {
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
@@ -3,7 +3,6 @@ package adb
 import (
 	"fmt"
 	"strconv"
-	"strings"
 )
 
 // Command ADB命令基类
@@ -17,7 +16,7 @@ type Command struct {
 func NewCommand(conn *Connection) *Command {
 	return &Command{
 		conn:     conn,
-		parser:   NewParser(),
+		parser:   conn.GetParser(),
 		protocol: NewProtocol(),
 	}
 }
@@ -34,42 +33,11 @@ func (c *Command) send(data []byte) error {
 	return err
 }
 
-// escape 转义参数（用于安全性）
-func (c *Command) escape(arg interface{}) string {
-	switch v := arg.(type) {
-	case int:
-		return strconv.Itoa(v)
-	default:
-		// 使用单引号包裹并转义内部的单引号
-		str := fmt.Sprintf("%v", v)
-		return "'" + strings.ReplaceAll(str, "'", "'\"'\"'") + "'"
-	}
-}
-
-// escapeCompat 兼容性转义（用于特定设备）
-func (c *Command) escapeCompat(arg interface{}) string {
-	switch v := arg.(type) {
-	case int:
-		return strconv.Itoa(v)
-	default:
-		// 使用双引号包裹并转义特殊字符
-		str := fmt.Sprintf("%v", v)
-		escaped := strings.NewReplacer(
-			"$", "\\$",
-			"`", "\\`",
-			"\\", "\\\\",
-			"!", "\\!",
-			"\"", "\\\"",
-		).Replace(str)
-		return "\"" + escaped + "\""
-	}
-}
-
 // checkResponse 检查响应
 func (c *Command) checkResponse(expected string) error {
-	response, err := c.parser.ReadString(4)
+	response, err := c.parser.ReadAscii(4)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+		return fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if response != expected {
@@ -81,14 +49,14 @@ func (c *Command) checkResponse(expected string) error {
 
 // readLength 读取长度
 func (c *Command) readLength() (int, error) {
-	lenStr, err := c.parser.ReadString(4)
+	lenStr, err := c.parser.ReadAscii(4)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read length: %v", err)
+		return 0, fmt.Errorf("failed to read length: %w", err)
 	}
 
 	length, err := strconv.ParseInt(lenStr, 16, 32)
 	if err != nil {
-		return 0, fmt.Errorf("invalid length format: %v", err)
+		return 0, fmt.Errorf("invalid length format: %w", err)
 	}
 
 	return int(length), nil
@@ -98,29 +66,11 @@ func (c *Command) readLength() (int, error) {
 func (c *Command) readData(length int) ([]byte, error) {
 	data, err := c.parser.ReadBytes(length)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read data: %v", err)
+		return nil, fmt.Errorf("failed to read data: %w", err)
 	}
 	return data, nil
 }
 
-// formatCmd 格式化命令
-func (c *Command) formatCmd(cmd string, args ...interface{}) string {
-	escapedArgs := make([]string, len(args))
-	for i, arg := range args {
-		escapedArgs[i] = c.escape(arg)
-	}
-	return fmt.Sprintf(cmd, escapedArgs...)
-}
-
-// formatCmdCompat 格式化兼容命令
-func (c *Command) formatCmdCompat(cmd string, args ...interface{}) string {
-	escapedArgs := make([]string, len(args))
-	for i, arg := range args {
-		escapedArgs[i] = c.escapeCompat(arg)
-	}
-	return fmt.Sprintf(cmd, escapedArgs...)
-}
-
 // HostCommand host命令基类
 type HostCommand struct {
 	*Command
@@ -178,7 +128,7 @@ func (c *ShellCommand) Execute(command string) error {
 	// 发送shell命令
 	err := c.send([]byte("shell:" + command))
 	if err != nil {
-		return fmt.Errorf("failed to send shell command: %v", err)
+		return fmt.Errorf("failed to send shell command: %w", err)
 	}
 
 	// 检查响应
@@ -202,7 +152,7 @@ func (c *InstallCommand) Execute(path string) error {
 	// 发送安装命令
 	err := c.send([]byte("install:" + path))
 	if err != nil {
-		return fmt.Errorf("failed to send install command: %v", err)
+		return fmt.Errorf("failed to send install command: %w", err)
 	}
 
 	// 检查响应
@@ -3,6 +3,9 @@ package hostserial
 import (
 	"fmt"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/command/host"
 )
 
 const (
@@ -39,6 +42,12 @@ type WaitForDeviceCommand struct {
 	BaseCommand
 }
 
+// FeaturesCommand 实现查询设备特性命令
+// 特性由设备连接时的banner提供，查询只由ADB服务器应答，不会与设备通信
+type FeaturesCommand struct {
+	BaseCommand
+}
+
 // Forward 表示一个端口转发配置
 type Forward struct {
 	Serial string
@@ -92,31 +101,45 @@ func NewWaitForDeviceCommand(sender func(string) error, reader func(int) (string
 	}
 }
 
+func NewFeaturesCommand(sender func(string) error, reader func(int) (string, error)) *FeaturesCommand {
+	return &FeaturesCommand{
+		BaseCommand: BaseCommand{
+			sender: sender,
+			reader: reader,
+		},
+	}
+}
+
 // Execute 执行获取设备路径命令
 func (c *GetDevicePathCommand) Execute(serial string) (string, error) {
-	cmd := fmt.Sprintf("host-serial:%s:get-devpath", serial)
+	return c.ExecuteSelector(host.SelectSerial(serial))
+}
+
+// ExecuteSelector 按选择方式执行获取设备路径命令
+func (c *GetDevicePathCommand) ExecuteSelector(selector host.Selector) (string, error) {
+	cmd := selector.HostPrefix() + "get-devpath"
 	if err := c.sender(cmd); err != nil {
-		return "", fmt.Errorf("发送获取设备路径命令失败: %v", err)
+		return "", fmt.Errorf("发送获取设备路径命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %v", err)
+		return "", fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		value, err := c.reader(0)
 		if err != nil {
-			return "", fmt.Errorf("读取设备路径失败: %v", err)
+			return "", fmt.Errorf("读取设备路径失败: %w", err)
 		}
 		return value, nil
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return "", fmt.Errorf("读取错误信息失败: %v", err)
+			return "", fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return "", fmt.Errorf(errMsg)
+		return "", adberr.NewFailError(errMsg)
 	default:
 		return "", fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
@@ -124,15 +147,20 @@ func (c *GetDevicePathCommand) Execute(serial string) (string, error) {
 
 // Execute 执行端口转发命令
 func (c *ForwardCommand) Execute(serial, local, remote string) (bool, error) {
-	cmd := fmt.Sprintf("host-serial:%s:forward:%s;%s", serial, local, remote)
+	return c.ExecuteSelector(host.SelectSerial(serial), local, remote)
+}
+
+// ExecuteSelector 按选择方式执行端口转发命令
+func (c *ForwardCommand) ExecuteSelector(selector host.Selector, local, remote string) (bool, error) {
+	cmd := selector.HostPrefix() + fmt.Sprintf("forward:%s;%s", local, remote)
 	if err := c.sender(cmd); err != nil {
-		return false, fmt.Errorf("发送端口转发命令失败: %v", err)
+		return false, fmt.Errorf("发送端口转发命令失败: %w", err)
 	}
 
 	// 第一次读取响应
 	reply, err := c.reader(4)
 	if err != nil {
-		return false, fmt.Errorf("读取第一次响应失败: %v", err)
+		return false, fmt.Errorf("读取第一次响应失败: %w", err)
 	}
 
 	switch reply {
@@ -140,7 +168,7 @@ func (c *ForwardCommand) Execute(serial, local, remote string) (bool, error) {
 		// 第二次读取响应
 		reply, err = c.reader(4)
 		if err != nil {
-			return false, fmt.Errorf("读取第二次响应失败: %v", err)
+			return false, fmt.Errorf("读取第二次响应失败: %w", err)
 		}
 
 		switch reply {
@@ -149,47 +177,53 @@ func (c *ForwardCommand) Execute(serial, local, remote string) (bool, error) {
 		case FAIL:
 			errMsg, err := c.reader(0)
 			if err != nil {
-				return false, fmt.Errorf("读取错误信息失败: %v", err)
+				return false, fmt.Errorf("读取错误信息失败: %w", err)
 			}
-			return false, fmt.Errorf(errMsg)
+			return false, adberr.NewFailError(errMsg)
 		default:
 			return false, fmt.Errorf("unexpected second response: %s, expected OKAY or FAIL", reply)
 		}
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return false, fmt.Errorf("读取错误信息失败: %v", err)
+			return false, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return false, fmt.Errorf(errMsg)
+		return false, adberr.NewFailError(errMsg)
 	default:
 		return false, fmt.Errorf("unexpected first response: %s, expected OKAY or FAIL", reply)
 	}
 }
 
+// Execute 执行获取序列号命令
 func (c *GetSerialNoCommand) Execute(serial string) (string, error) {
-	cmd := fmt.Sprintf("host-serial:%s:get-serialno", serial)
+	return c.ExecuteSelector(host.SelectSerial(serial))
+}
+
+// ExecuteSelector 按选择方式执行获取序列号命令，可用于查询传输ID对应的序列号
+func (c *GetSerialNoCommand) ExecuteSelector(selector host.Selector) (string, error) {
+	cmd := selector.HostPrefix() + "get-serialno"
 	if err := c.sender(cmd); err != nil {
-		return "", fmt.Errorf("发送获取序列号命令失败: %v", err)
+		return "", fmt.Errorf("发送获取序列号命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %v", err)
+		return "", fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		value, err := c.reader(0)
 		if err != nil {
-			return "", fmt.Errorf("读取序列号失败: %v", err)
+			return "", fmt.Errorf("读取序列号失败: %w", err)
 		}
 		return value, nil
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return "", fmt.Errorf("读取错误信息失败: %v", err)
+			return "", fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return "", fmt.Errorf(errMsg)
+		return "", adberr.NewFailError(errMsg)
 	default:
 		return "", fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
@@ -197,29 +231,34 @@ func (c *GetSerialNoCommand) Execute(serial string) (string, error) {
 
 // Execute 执行列出转发配置命令
 func (c *ListForwardsCommand) Execute(serial string) ([]Forward, error) {
-	cmd := fmt.Sprintf("host-serial:%s:list-forward", serial)
+	return c.ExecuteSelector(host.SelectSerial(serial))
+}
+
+// ExecuteSelector 按选择方式执行列出转发配置命令
+func (c *ListForwardsCommand) ExecuteSelector(selector host.Selector) ([]Forward, error) {
+	cmd := selector.HostPrefix() + "list-forward"
 	if err := c.sender(cmd); err != nil {
-		return nil, fmt.Errorf("发送列出转发配置命令失败: %v", err)
+		return nil, fmt.Errorf("发送列出转发配置命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		value, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取转发配置失败: %v", err)
+			return nil, fmt.Errorf("读取转发配置失败: %w", err)
 		}
 		return c.parseForwards(value)
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
@@ -252,15 +291,20 @@ func (c *ListForwardsCommand) parseForwards(value string) ([]Forward, error) {
 
 // Execute 执行等待设备命令
 func (c *WaitForDeviceCommand) Execute(serial string) (string, error) {
-	cmd := fmt.Sprintf("host-serial:%s:wait-for-any", serial)
+	return c.ExecuteSelector(host.SelectSerial(serial))
+}
+
+// ExecuteSelector 按选择方式执行等待设备命令，设备进入device状态后返回
+func (c *WaitForDeviceCommand) ExecuteSelector(selector host.Selector) (string, error) {
+	cmd := selector.HostPrefix() + fmt.Sprintf("wait-for-%s-device", selector.WaitForType())
 	if err := c.sender(cmd); err != nil {
-		return "", fmt.Errorf("发送等待设备命令失败: %v", err)
+		return "", fmt.Errorf("发送等待设备命令失败: %w", err)
 	}
 
 	// 第一次读取响应
 	reply, err := c.reader(4)
 	if err != nil {
-		return "", fmt.Errorf("读取第一次响应失败: %v", err)
+		return "", fmt.Errorf("读取第一次响应失败: %w", err)
 	}
 
 	switch reply {
@@ -268,28 +312,63 @@ func (c *WaitForDeviceCommand) Execute(serial string) (string, error) {
 		// 第二次读取响应
 		reply, err = c.reader(4)
 		if err != nil {
-			return "", fmt.Errorf("读取第二次响应失败: %v", err)
+			return "", fmt.Errorf("读取第二次响应失败: %w", err)
 		}
 
 		switch reply {
 		case OKAY:
-			return serial, nil
+			return selector.String(), nil
 		case FAIL:
 			errMsg, err := c.reader(0)
 			if err != nil {
-				return "", fmt.Errorf("读取错误信息失败: %v", err)
+				return "", fmt.Errorf("读取错误信息失败: %w", err)
 			}
-			return "", fmt.Errorf(errMsg)
+			return "", adberr.NewFailError(errMsg)
 		default:
 			return "", fmt.Errorf("unexpected second response: %s, expected OKAY or FAIL", reply)
 		}
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return "", fmt.Errorf("读取错误信息失败: %v", err)
+			return "", fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return "", fmt.Errorf(errMsg)
+		return "", adberr.NewFailError(errMsg)
 	default:
 		return "", fmt.Errorf("unexpected first response: %s, expected OKAY or FAIL", reply)
 	}
 }
+
+// Execute 执行查询设备特性命令
+func (c *FeaturesCommand) Execute(serial string) ([]string, error) {
+	return c.ExecuteSelector(host.SelectSerial(serial))
+}
+
+// ExecuteSelector 按选择方式查询设备特性，如shell_v2、cmd、stat_v2
+func (c *FeaturesCommand) ExecuteSelector(selector host.Selector) ([]string, error) {
+	cmd := selector.HostPrefix() + "features"
+	if err := c.sender(cmd); err != nil {
+		return nil, fmt.Errorf("发送特性查询命令失败: %w", err)
+	}
+
+	reply, err := c.reader(4)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	switch reply {
+	case OKAY:
+		value, err := c.reader(0)
+		if err != nil {
+			return nil, fmt.Errorf("读取特性列表失败: %w", err)
+		}
+		return host.ParseFeatures(value), nil
+	case FAIL:
+		errMsg, err := c.reader(0)
+		if err != nil {
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
+		}
+		return nil, adberr.NewFailError(errMsg)
+	default:
+		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
+	}
+}
@@ -0,0 +1,121 @@
+package hosttransport
+
+import (
+	"fmt"
+	"io"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
+)
+
+// ExecCommand 实现exec:命令（adb exec-out/exec-in），命令的输入输出不经过PTY和行结束符转换
+// exec:需要Android 5.0及以上，旧设备只能通过ExecuteLegacy使用shell:
+type ExecCommand struct {
+	BaseCommand
+	stream io.Reader
+}
+
+// NewExecCommand 创建新的exec命令实例
+func NewExecCommand(sender func(string) error, reader func(int) (string, error)) *ExecCommand {
+	return &ExecCommand{
+		BaseCommand: BaseCommand{
+			sender: sender,
+			reader: reader,
+		},
+	}
+}
+
+// WithStream 设置读取命令输出的数据流，如连接本身，数据到达后立即返回，双向使用时必须设置
+// 默认通过reader读取，每次读取填满缓冲区或遇到EOF才返回
+func (c *ExecCommand) WithStream(stream io.Reader) *ExecCommand {
+	c.stream = stream
+	return c
+}
+
+// Execute 通过exec:执行命令，返回原始的输出流，stderr与stdout合并
+// command可以是字符串、字符串数组或*shellcmd.Command，与ShellCommand.Execute相同
+// 应答OKAY之后写入连接的数据作为命令的标准输入；设备不支持exec:时返回FailError
+func (c *ExecCommand) Execute(command interface{}) (io.Reader, error) {
+	cmd, err := c.commandString(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.sender("exec:" + cmd); err != nil {
+		return nil, fmt.Errorf("发送exec命令失败: %w", err)
+	}
+
+	reply, err := c.reader(4)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	switch reply {
+	case OKAY:
+		if c.stream != nil {
+			return c.stream, nil
+		}
+		return NewLineTransform(c.reader, false), nil
+
+	case FAIL:
+		errMsg, err := c.reader(0)
+		if err != nil {
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
+		}
+		return nil, fmt.Errorf("exec命令失败: %w", adberr.NewFailError(errMsg))
+
+	default:
+		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
+	}
+}
+
+// ExecuteLegacy 通过shell:执行命令并还原被设备转换为CRLF的LF，用于不支持exec:的旧设备
+// 命令之前的echo用于检测设备是否转换行结束符；输出为空时返回的错误包装io.EOF
+// 命令的stderr也会混入输出，二进制输出需要由调用方重定向stderr
+func (c *ExecCommand) ExecuteLegacy(command interface{}) (io.Reader, error) {
+	cmd, err := c.commandString(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.sendShell(shellcmd.New("echo").And(shellcmd.Raw(cmd))); err != nil {
+		return nil, fmt.Errorf("发送shell命令失败: %w", err)
+	}
+
+	reply, err := c.reader(4)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	switch reply {
+	case OKAY:
+		// 第一个字节来自echo，用于检测设备是否会把LF转换为CRLF
+		firstByte, err := c.reader(1)
+		if err == io.EOF || (err == nil && firstByte == "") {
+			return nil, fmt.Errorf("命令没有输出: %w", io.EOF)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取数据失败: %w", err)
+		}
+
+		if firstByte != "\r" {
+			return NewLineTransform(c.reader, false), nil
+		}
+
+		// 跳过CRLF中的LF
+		if _, err := c.reader(1); err != nil {
+			return nil, fmt.Errorf("读取数据失败: %w", err)
+		}
+		return NewLineTransform(c.reader, true), nil
+
+	case FAIL:
+		errMsg, err := c.reader(0)
+		if err != nil {
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
+		}
+		return nil, adberr.NewFailError(errMsg)
+
+	default:
+		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
+	}
+}
@@ -3,7 +3,11 @@ package hosttransport
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // GetFeaturesCommand 实现获取特性命令
@@ -11,11 +15,31 @@ type GetFeaturesCommand struct {
 	BaseCommand
 }
 
-type Feature struct {
-	Name  string
-	Value interface{}
+// SystemFeatures pm list features列出的系统特性，值为特性的版本等，没有值的特性为空字符串
+type SystemFeatures map[string]string
+
+// Has 检查是否有特性
+func (f SystemFeatures) Has(name string) bool {
+	_, ok := f[name]
+	return ok
+}
+
+// Value 返回特性的值，没有值或没有该特性时返回空字符串
+func (f SystemFeatures) Value(name string) string {
+	return f[name]
+}
+
+// List 返回排序后的特性名列表
+func (f SystemFeatures) List() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
+// NewGetFeaturesCommand 创建新的获取特性命令实例
 func NewGetFeaturesCommand(sender func(string) error, reader func(int) (string, error)) *GetFeaturesCommand {
 	return &GetFeaturesCommand{
 		BaseCommand: BaseCommand{
@@ -25,38 +49,40 @@ func NewGetFeaturesCommand(sender func(string) error, reader func(int) (string,
 	}
 }
 
-func (c *GetFeaturesCommand) Execute() (map[string]interface{}, error) {
-	if err := c.sender("shell:pm list features 2>/dev/null"); err != nil {
-		return nil, fmt.Errorf("发送获取特性命令失败: %v", err)
+// Execute 执行pm list features并解析结果
+func (c *GetFeaturesCommand) Execute() (SystemFeatures, error) {
+	if err := c.sendShell(shellcmd.New("pm", "list", "features").Redirect("2>", "/dev/null")); err != nil {
+		return nil, fmt.Errorf("发送获取特性命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
-		data, err := c.reader(0)
+		data, err := c.reader(-1)
 		if err != nil {
-			return nil, fmt.Errorf("读取特性数据失败: %v", err)
+			return nil, fmt.Errorf("读取特性数据失败: %w", err)
 		}
 		return c.parseFeatures(data)
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
 }
 
-func (c *GetFeaturesCommand) parseFeatures(value string) (map[string]interface{}, error) {
-	features := make(map[string]interface{})
+// parseFeatures 解析feature:name或feature:name=value格式的行
+func (c *GetFeaturesCommand) parseFeatures(value string) (SystemFeatures, error) {
+	features := make(SystemFeatures)
 	re := regexp.MustCompile(`^feature:(.*?)(?:=(.*?))?\r?$`)
 
 	lines := strings.Split(value, "\n")
@@ -64,11 +90,7 @@ func (c *GetFeaturesCommand) parseFeatures(value string) (map[string]interface{}
 		matches := re.FindStringSubmatch(line)
 		if len(matches) >= 2 {
 			name := matches[1]
-			if len(matches) == 3 && matches[2] != "" {
-				features[name] = matches[2]
-			} else {
-				features[name] = true
-			}
+			features[name] = matches[2]
 		}
 	}
 
@@ -5,6 +5,11 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"regexp"
+	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // FrameBufferMeta 存储帧缓冲区元数据
@@ -24,9 +29,56 @@ type FrameBufferMeta struct {
 	AlphaLength uint32
 	Format      string
 }
+
+// BaseCommand 提供基础功能
+// reader(n) 的约定: n > 0 读取n个字节（流结束时返回剩余数据），
+// n == 0 读取带4字节长度前缀的值，n < 0 读取直到流结束
 type BaseCommand struct {
-	sender func(string) error
-	reader func(int) (string, error)
+	sender  func(string) error
+	reader  func(int) (string, error)
+	dialect shellcmd.Dialect
+}
+
+// SetDialect 设置构建shell命令时的引用方式，默认为shellcmd.Modern，Android 4.0之前的设备使用shellcmd.Legacy
+func (c *BaseCommand) SetDialect(dialect shellcmd.Dialect) {
+	c.dialect = dialect
+}
+
+// sendShell 构建命令行并通过shell:发送，参数中有NUL字节等无法构建时不发送
+func (c *BaseCommand) sendShell(cmd *shellcmd.Command) error {
+	line, err := cmd.Dialect(c.dialect).Build()
+	if err != nil {
+		return err
+	}
+	return c.sender("shell:" + line)
+}
+
+// searchLine 逐行读取输出直到找到匹配的行，返回匹配的子串
+// 流结束时仍未找到则返回io.EOF
+func (c *BaseCommand) searchLine(pattern *regexp.Regexp) ([]string, error) {
+	var line strings.Builder
+	for {
+		b, err := c.reader(1)
+		if err == io.EOF && line.Len() > 0 {
+			// 最后一行可能没有换行符
+			if match := pattern.FindStringSubmatch(strings.TrimRight(line.String(), "\r")); match != nil {
+				return match, nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if b != "\n" {
+			line.WriteString(b)
+			continue
+		}
+
+		if match := pattern.FindStringSubmatch(strings.TrimRight(line.String(), "\r")); match != nil {
+			return match, nil
+		}
+		line.Reset()
+	}
 }
 
 // FrameBufferCommand 实现帧缓冲区命令
@@ -45,12 +97,12 @@ func NewFrameBufferCommand(sender func(string) error, reader func(int) (string,
 
 func (c *FrameBufferCommand) Execute(format string) (io.Reader, *FrameBufferMeta, error) {
 	if err := c.sender("framebuffer:"); err != nil {
-		return nil, nil, fmt.Errorf("发送帧缓冲区命令失败: %v", err)
+		return nil, nil, fmt.Errorf("发送帧缓冲区命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -58,12 +110,12 @@ func (c *FrameBufferCommand) Execute(format string) (io.Reader, *FrameBufferMeta
 		// 读取头部信息
 		headerBytes, err := c.reader(52)
 		if err != nil {
-			return nil, nil, fmt.Errorf("读取头部信息失败: %v", err)
+			return nil, nil, fmt.Errorf("读取头部信息失败: %w", err)
 		}
 
 		meta, err := c.parseHeader([]byte(headerBytes))
 		if err != nil {
-			return nil, nil, fmt.Errorf("解析头部信息失败: %v", err)
+			return nil, nil, fmt.Errorf("解析头部信息失败: %w", err)
 		}
 
 		// 根据格式选择输出
@@ -76,9 +128,9 @@ func (c *FrameBufferCommand) Execute(format string) (io.Reader, *FrameBufferMeta
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, nil, fmt.Errorf(errMsg)
+		return nil, nil, adberr.NewFailError(errMsg)
 
 	default:
 		return nil, nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -170,16 +222,16 @@ func (c *FrameBufferCommand) convertStream(meta *FrameBufferMeta, format string)
 	cmd := exec.Command("gm", args...)
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		return nil, nil, fmt.Errorf("创建输入管道失败: %v", err)
+		return nil, nil, fmt.Errorf("创建输入管道失败: %w", err)
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, nil, fmt.Errorf("创建输出管道失败: %v", err)
+		return nil, nil, fmt.Errorf("创建输出管道失败: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, nil, fmt.Errorf("启动转换进程失败: %v", err)
+		return nil, nil, fmt.Errorf("启动转换进程失败: %w", err)
 	}
 
 	// 启动goroutine来复制数据
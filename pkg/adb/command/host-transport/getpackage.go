@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // GetPackagesCommand 实现获取包列表命令
@@ -24,31 +27,31 @@ func NewGetPackagesCommand(sender func(string) error, reader func(int) (string,
 // Execute 执行获取包列表命令
 func (c *GetPackagesCommand) Execute() ([]string, error) {
 	// 发送命令，重定向stderr到/dev/null以避免错误信息
-	if err := c.sender("shell:pm list packages 2>/dev/null"); err != nil {
-		return nil, fmt.Errorf("发送获取包列表命令失败: %v", err)
+	if err := c.sendShell(shellcmd.New("pm", "list", "packages").Redirect("2>", "/dev/null")); err != nil {
+		return nil, fmt.Errorf("发送获取包列表命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取所有数据
-		data, err := c.reader(0)
+		data, err := c.reader(-1)
 		if err != nil {
-			return nil, fmt.Errorf("读取包列表数据失败: %v", err)
+			return nil, fmt.Errorf("读取包列表数据失败: %w", err)
 		}
 		return c.parsePackages(data)
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // GetPropertiesCommand 实现获取系统属性命令
@@ -23,29 +26,29 @@ func NewGetPropertiesCommand(sender func(string) error, reader func(int) (string
 
 // Execute 执行获取系统属性命令
 func (c *GetPropertiesCommand) Execute() (map[string]string, error) {
-	if err := c.sender("shell:getprop"); err != nil {
-		return nil, fmt.Errorf("发送获取属性命令失败: %v", err)
+	if err := c.sendShell(shellcmd.New("getprop")); err != nil {
+		return nil, fmt.Errorf("发送获取属性命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
-		data, err := c.reader(0)
+		data, err := c.reader(-1)
 		if err != nil {
-			return nil, fmt.Errorf("读取属性数据失败: %v", err)
+			return nil, fmt.Errorf("读取属性数据失败: %w", err)
 		}
 		return c.parseProperties(data)
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -4,23 +4,19 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // InstallCommand 实现APK安装命令
 type InstallCommand struct {
 	BaseCommand
+	useCmd bool
 }
 
-// InstallError 定义安装错误
-type InstallError struct {
-	Apk  string
-	Code string
-	err  string
-}
-
-func (e *InstallError) Error() string {
-	return fmt.Sprintf("%s could not be installed [%s]", e.Apk, e.Code)
-}
+// InstallError 定义安装错误，errors.Is(err, adberr.ErrInstallFailed) 可判断安装失败
+type InstallError = adberr.InstallError
 
 // NewInstallCommand 创建新的安装命令实例
 func NewInstallCommand(sender func(string) error, reader func(int) (string, error)) *InstallCommand {
@@ -32,19 +28,22 @@ func NewInstallCommand(sender func(string) error, reader func(int) (string, erro
 	}
 }
 
+// UseCmd 通过cmd package而不是pm安装，设备支持cmd特性时可以省去启动pm进程的时间
+func (c *InstallCommand) UseCmd() *InstallCommand {
+	c.useCmd = true
+	return c
+}
+
 // Execute 执行APK安装命令
 func (c *InstallCommand) Execute(apk string) error {
-	// 转义路径并构建命令
-	escapedPath := c.escapeCompat(apk)
-	cmd := fmt.Sprintf("shell:pm install -r %s", escapedPath)
-
-	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("发送安装命令失败: %v", err)
+	cmd := packageManager(c.useCmd).Arg("install", "-r", apk)
+	if err := c.sendShell(cmd); err != nil {
+		return fmt.Errorf("发送安装命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -62,15 +61,14 @@ func (c *InstallCommand) Execute(apk string) error {
 		return &InstallError{
 			Apk:  apk,
 			Code: result.code,
-			err:  fmt.Sprintf("%s could not be installed [%s]", apk, result.code),
 		}
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf(errMsg)
+		return adberr.NewFailError(errMsg)
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -96,7 +94,7 @@ func (c *InstallCommand) searchInstallResult() (*installResult, error) {
 		for {
 			data, err := c.reader(1024) // 读取块
 			if err != nil {
-				errChan <- fmt.Errorf("读取安装结果失败: %v", err)
+				errChan <- fmt.Errorf("读取安装结果失败: %w", err)
 				return
 			}
 
@@ -138,23 +136,10 @@ func (c *InstallCommand) searchInstallResult() (*installResult, error) {
 	}
 }
 
-// escapeCompat 转义路径中的特殊字符
-func (c *InstallCommand) escapeCompat(path string) string {
-	// 实现路径转义逻辑
-	// 这里需要根据具体需求实现，例如：
-	escaped := strings.ReplaceAll(path, " ", "\\ ")
-	escaped = strings.ReplaceAll(escaped, "(", "\\(")
-	escaped = strings.ReplaceAll(escaped, ")", "\\)")
-	escaped = strings.ReplaceAll(escaped, "[", "\\[")
-	escaped = strings.ReplaceAll(escaped, "]", "\\]")
-	escaped = strings.ReplaceAll(escaped, "&", "\\&")
-	escaped = strings.ReplaceAll(escaped, "|", "\\|")
-	escaped = strings.ReplaceAll(escaped, ";", "\\;")
-	escaped = strings.ReplaceAll(escaped, "<", "\\<")
-	escaped = strings.ReplaceAll(escaped, ">", "\\>")
-	escaped = strings.ReplaceAll(escaped, "$", "\\$")
-	escaped = strings.ReplaceAll(escaped, "`", "\\`")
-	escaped = strings.ReplaceAll(escaped, "\"", "\\\"")
-	escaped = strings.ReplaceAll(escaped, "'", "\\'")
-	return escaped
+// packageManager 返回包管理命令，useCmd时使用cmd package
+func packageManager(useCmd bool) *shellcmd.Command {
+	if useCmd {
+		return shellcmd.New("cmd", "package")
+	}
+	return shellcmd.New("pm")
 }
@@ -3,6 +3,9 @@ package hosttransport
 import (
 	"fmt"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // IsInstalledCommand 实现检查包是否已安装的命令
@@ -23,15 +26,14 @@ func NewIsInstalledCommand(sender func(string) error, reader func(int) (string,
 // Execute 执行检查包是否已安装命令
 func (c *IsInstalledCommand) Execute(pkg string) (bool, error) {
 	// 发送命令，重定向stderr到/dev/null以避免错误信息
-	cmd := fmt.Sprintf("shell:pm path %s 2>/dev/null", pkg)
-	if err := c.sender(cmd); err != nil {
-		return false, fmt.Errorf("发送检查安装命令失败: %v", err)
+	if err := c.sendShell(shellcmd.New("pm", "path", pkg).Redirect("2>", "/dev/null")); err != nil {
+		return false, fmt.Errorf("发送检查安装命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return false, fmt.Errorf("读取响应失败: %v", err)
+		return false, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -54,9 +56,9 @@ func (c *IsInstalledCommand) Execute(pkg string) (bool, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return false, fmt.Errorf("读取错误信息失败: %v", err)
+			return false, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return false, fmt.Errorf(errMsg)
+		return false, adberr.NewFailError(errMsg)
 
 	default:
 		return false, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
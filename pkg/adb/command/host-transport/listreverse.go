@@ -3,6 +3,8 @@ package hosttransport
 import (
 	"fmt"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // Reverse 表示一个反向端口转发配置
@@ -29,13 +31,13 @@ func NewListReversesCommand(sender func(string) error, reader func(int) (string,
 // Execute 执行列出反向端口转发命令
 func (c *ListReversesCommand) Execute() ([]Reverse, error) {
 	if err := c.sender("reverse:list-forward"); err != nil {
-		return nil, fmt.Errorf("发送列出反向端口转发命令失败: %v", err)
+		return nil, fmt.Errorf("发送列出反向端口转发命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -43,16 +45,16 @@ func (c *ListReversesCommand) Execute() ([]Reverse, error) {
 		// 读取值
 		value, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取反向端口转发列表失败: %v", err)
+			return nil, fmt.Errorf("读取反向端口转发列表失败: %w", err)
 		}
 		return c.parseReverses(value)
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -3,6 +3,8 @@ package hosttransport
 import (
 	"fmt"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // LocalCommand 实现本地文件系统命令
@@ -31,30 +33,30 @@ func (c *LocalCommand) Execute(path string) (string, error) {
 	}
 
 	if err := c.sender(cmd); err != nil {
-		return "", fmt.Errorf("发送本地命令失败: %v", err)
+		return "", fmt.Errorf("发送本地命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %v", err)
+		return "", fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取原始数据
-		data, err := c.reader(0)
+		data, err := c.reader(-1)
 		if err != nil {
-			return "", fmt.Errorf("读取数据失败: %v", err)
+			return "", fmt.Errorf("读取数据失败: %w", err)
 		}
 		return data, nil
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return "", fmt.Errorf("读取错误信息失败: %v", err)
+			return "", fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return "", fmt.Errorf(errMsg)
+		return "", adberr.NewFailError(errMsg)
 
 	default:
 		return "", fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -3,6 +3,8 @@ package hosttransport
 import (
 	"fmt"
 	"io"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // LogCommand 实现日志命令
@@ -26,13 +28,13 @@ func (c *LogCommand) Execute(name string) (io.Reader, error) {
 	cmd := fmt.Sprintf("log:%s", name)
 
 	if err := c.sender(cmd); err != nil {
-		return nil, fmt.Errorf("发送日志命令失败: %v", err)
+		return nil, fmt.Errorf("发送日志命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -43,9 +45,9 @@ func (c *LogCommand) Execute(name string) (io.Reader, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -74,7 +76,7 @@ func (r *logReader) Read(p []byte) (n int, err error) {
 		if err.Error() == "EOF" {
 			return 0, io.EOF
 		}
-		return 0, fmt.Errorf("读取日志数据失败: %v", err)
+		return 0, fmt.Errorf("读取日志数据失败: %w", err)
 	}
 
 	// 如果没有数据返回，表示结束
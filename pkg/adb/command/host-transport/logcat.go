@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // LogcatCommand 实现logcat命令
@@ -34,19 +37,20 @@ func (c *LogcatCommand) Execute(options *LogcatOptions) (io.Reader, error) {
 
 	// 构建命令
 	// 注意：LG G Flex需要-B选项带过滤器，虽然实际上不使用
-	cmd := "shell:echo && logcat -B *:I 2>/dev/null"
+	cmd := shellcmd.New("echo")
 	if options.Clear {
-		cmd = "shell:echo && logcat -c 2>/dev/null && " + cmd
+		cmd.And(shellcmd.New("logcat", "-c").Redirect("2>", "/dev/null"))
 	}
+	cmd.And(shellcmd.New("logcat", "-B", "*:I").Redirect("2>", "/dev/null"))
 
-	if err := c.sender(cmd); err != nil {
-		return nil, fmt.Errorf("发送logcat命令失败: %v", err)
+	if err := c.sendShell(cmd); err != nil {
+		return nil, fmt.Errorf("发送logcat命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -57,9 +61,9 @@ func (c *LogcatCommand) Execute(options *LogcatOptions) (io.Reader, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -97,7 +101,7 @@ func (r *logcatReader) Read(p []byte) (n int, err error) {
 		if err.Error() == "EOF" {
 			return 0, io.EOF
 		}
-		return 0, fmt.Errorf("读取logcat数据失败: %v", err)
+		return 0, fmt.Errorf("读取logcat数据失败: %w", err)
 	}
 
 	// 如果没有数据返回，表示结束
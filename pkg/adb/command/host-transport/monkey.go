@@ -3,8 +3,12 @@ package hosttransport
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // MonkeyCommand 实现Monkey测试命令
@@ -27,16 +31,16 @@ func (c *MonkeyCommand) Execute(port int) (io.Reader, error) {
 	// 设置环境变量以修复一些设备上的/sdcard问题
 	// 一些设备的/sdcard路径有问题（如/mnt/sdcard），monkey会尝试在那里写入日志
 	// 通过设置EXTERNAL_STORAGE环境变量，我们可以改变日志写入位置
-	cmd := fmt.Sprintf("shell:EXTERNAL_STORAGE=/data/local/tmp monkey --port %d -v", port)
+	cmd := shellcmd.New("monkey", "--port", strconv.Itoa(port), "-v").Env("EXTERNAL_STORAGE", "/data/local/tmp")
 
-	if err := c.sender(cmd); err != nil {
-		return nil, fmt.Errorf("发送monkey命令失败: %v", err)
+	if err := c.sendShell(cmd); err != nil {
+		return nil, fmt.Errorf("发送monkey命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -47,9 +51,9 @@ func (c *MonkeyCommand) Execute(port int) (io.Reader, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -91,7 +95,7 @@ func (r *monkeyReader) waitForInitialization() error {
 			// 读取数据直到发现初始化标记
 			data, err := r.command.reader(1024)
 			if err != nil {
-				done <- fmt.Errorf("读取初始化数据失败: %v", err)
+				done <- fmt.Errorf("读取初始化数据失败: %w", err)
 				return
 			}
 
@@ -125,7 +129,7 @@ func (r *monkeyReader) Read(p []byte) (n int, err error) {
 		if err.Error() == "EOF" {
 			return 0, io.EOF
 		}
-		return 0, fmt.Errorf("读取monkey数据失败: %v", err)
+		return 0, fmt.Errorf("读取monkey数据失败: %w", err)
 	}
 
 	// 如果没有数据返回，表示结束
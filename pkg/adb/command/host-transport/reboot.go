@@ -2,6 +2,8 @@ package hosttransport
 
 import (
 	"fmt"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // RebootCommand 实现重启命令
@@ -23,30 +25,30 @@ func NewRebootCommand(sender func(string) error, reader func(int) (string, error
 func (c *RebootCommand) Execute() error {
 	// 发送重启命令
 	if err := c.sender("reboot:"); err != nil {
-		return fmt.Errorf("发送重启命令失败: %v", err)
+		return fmt.Errorf("发送重启命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取所有剩余数据
-		_, err := c.reader(0)
+		_, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取剩余数据失败: %v", err)
+			return fmt.Errorf("读取剩余数据失败: %w", err)
 		}
 		return nil
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf(errMsg)
+		return adberr.NewFailError(errMsg)
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -76,30 +78,30 @@ func (c *RebootCommand) ExecuteWithMode(mode RebootMode) error {
 
 	// 发送重启命令
 	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("发送重启命令失败: %v", err)
+		return fmt.Errorf("发送重启命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取所有剩余数据
-		_, err := c.reader(0)
+		_, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取剩余数据失败: %v", err)
+			return fmt.Errorf("读取剩余数据失败: %w", err)
 		}
 		return nil
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf(errMsg)
+		return adberr.NewFailError(errMsg)
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -1,9 +1,14 @@
 package hosttransport
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/retry"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // RemountCommand 实现重新挂载命令
@@ -25,13 +30,13 @@ func NewRemountCommand(sender func(string) error, reader func(int) (string, erro
 func (c *RemountCommand) Execute() error {
 	// 发送重新挂载命令
 	if err := c.sender("remount:"); err != nil {
-		return fmt.Errorf("发送重新挂载命令失败: %v", err)
+		return fmt.Errorf("发送重新挂载命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -41,9 +46,9 @@ func (c *RemountCommand) Execute() error {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf("remount failed: %s", errMsg)
+		return fmt.Errorf("remount failed: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -52,18 +57,18 @@ func (c *RemountCommand) Execute() error {
 
 // ExecuteWithRetry 执行重新挂载命令并在需要时重试
 func (c *RemountCommand) ExecuteWithRetry(maxRetries int) error {
-	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		err := c.Execute()
-		if err == nil {
-			return nil
-		}
-		lastErr = err
-
-		// 等待一小段时间后重试
-		time.Sleep(time.Second)
+	policy := &retry.Policy{
+		MaxAttempts:    maxRetries,
+		InitialBackoff: time.Second,
+		Retryable:      func(error) bool { return true },
+	}
+	err := policy.Do(context.Background(), func(context.Context) error {
+		return c.Execute()
+	})
+	if err != nil {
+		return fmt.Errorf("remount failed after %d attempts: %w", maxRetries, err)
 	}
-	return fmt.Errorf("remount failed after %d attempts: %v", maxRetries, lastErr)
+	return nil
 }
 
 // ExecuteWithVerification 执行重新挂载命令并验证结果
@@ -75,21 +80,20 @@ func (c *RemountCommand) ExecuteWithVerification() error {
 
 	// 验证挂载状态
 	// 这里可以添加额外的验证逻辑，比如检查 mount 命令输出
-	cmd := "shell:mount | grep system"
-	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("验证挂载状态失败: %v", err)
+	if err := c.sendShell(shellcmd.New("mount").Pipe(shellcmd.New("grep", "system"))); err != nil {
+		return fmt.Errorf("验证挂载状态失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取验证响应失败: %v", err)
+		return fmt.Errorf("读取验证响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
-		output, err := c.reader(0)
+		output, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取验证输出失败: %v", err)
+			return fmt.Errorf("读取验证输出失败: %w", err)
 		}
 
 		// 检查输出中是否包含 "rw" 标志
@@ -101,9 +105,9 @@ func (c *RemountCommand) ExecuteWithVerification() error {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取验证错误信息失败: %v", err)
+			return fmt.Errorf("读取验证错误信息失败: %w", err)
 		}
-		return fmt.Errorf("验证失败: %s", errMsg)
+		return fmt.Errorf("验证失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected verification response: %s", reply)
@@ -2,6 +2,8 @@ package hosttransport
 
 import (
 	"fmt"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // ReverseCommand 实现反向端口转发命令
@@ -25,13 +27,13 @@ func (c *ReverseCommand) Execute(remote, local string) error {
 	cmd := fmt.Sprintf("reverse:forward:%s;%s", remote, local)
 
 	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("发送反向端口转发命令失败: %v", err)
+		return fmt.Errorf("发送反向端口转发命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -39,7 +41,7 @@ func (c *ReverseCommand) Execute(remote, local string) error {
 		// 读取确认响应
 		confirm, err := c.reader(4)
 		if err != nil {
-			return fmt.Errorf("读取确认响应失败: %v", err)
+			return fmt.Errorf("读取确认响应失败: %w", err)
 		}
 
 		switch confirm {
@@ -48,9 +50,9 @@ func (c *ReverseCommand) Execute(remote, local string) error {
 		case FAIL:
 			errMsg, err := c.reader(0)
 			if err != nil {
-				return fmt.Errorf("读取错误信息失败: %v", err)
+				return fmt.Errorf("读取错误信息失败: %w", err)
 			}
-			return fmt.Errorf("反向端口转发失败: %s", errMsg)
+			return fmt.Errorf("反向端口转发失败: %w", adberr.NewFailError(errMsg))
 		default:
 			return fmt.Errorf("unexpected confirmation response: %s, expected OKAY or FAIL", confirm)
 		}
@@ -58,9 +60,9 @@ func (c *ReverseCommand) Execute(remote, local string) error {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf("反向端口转发失败: %s", errMsg)
+		return fmt.Errorf("反向端口转发失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
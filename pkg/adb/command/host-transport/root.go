@@ -5,6 +5,9 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // RootCommand 实现root权限命令
@@ -26,21 +29,21 @@ func NewRootCommand(sender func(string) error, reader func(int) (string, error))
 func (c *RootCommand) Execute() error {
 	// 发送root命令
 	if err := c.sender("root:"); err != nil {
-		return fmt.Errorf("发送root命令失败: %v", err)
+		return fmt.Errorf("发送root命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取完整响应
-		response, err := c.reader(0)
+		response, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取root响应失败: %v", err)
+			return fmt.Errorf("读取root响应失败: %w", err)
 		}
 
 		// 检查是否包含成功重启的消息
@@ -54,9 +57,9 @@ func (c *RootCommand) Execute() error {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf("root失败: %s", errMsg)
+		return fmt.Errorf("root失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -81,7 +84,7 @@ func (c *RootCommand) ExecuteWithVerification() error {
 
 	// 验证root状态
 	if err := c.verifyRoot(); err != nil {
-		return fmt.Errorf("root验证失败: %v", err)
+		return fmt.Errorf("root验证失败: %w", err)
 	}
 
 	return nil
@@ -90,22 +93,22 @@ func (c *RootCommand) ExecuteWithVerification() error {
 // verifyRoot 验证是否成功获取root权限
 func (c *RootCommand) verifyRoot() error {
 	// 发送验证命令
-	if err := c.sender("shell:id"); err != nil {
-		return fmt.Errorf("发送验证命令失败: %v", err)
+	if err := c.sendShell(shellcmd.New("id")); err != nil {
+		return fmt.Errorf("发送验证命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取验证响应失败: %v", err)
+		return fmt.Errorf("读取验证响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取id命令输出
-		output, err := c.reader(0)
+		output, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取验证输出失败: %v", err)
+			return fmt.Errorf("读取验证输出失败: %w", err)
 		}
 
 		// 检查输出是否包含 uid=0(root)
@@ -117,9 +120,9 @@ func (c *RootCommand) verifyRoot() error {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取验证错误信息失败: %v", err)
+			return fmt.Errorf("读取验证错误信息失败: %w", err)
 		}
-		return fmt.Errorf("验证失败: %s", errMsg)
+		return fmt.Errorf("验证失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected verification response: %s", reply)
@@ -1,8 +1,12 @@
 package hosttransport
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"strings"
+
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // ScreencapCommand 实现屏幕截图命令
@@ -20,111 +24,92 @@ func NewScreencapCommand(sender func(string) error, reader func(int) (string, er
 	}
 }
 
-// Execute 执行屏幕截图命令
+// screencapCommand 返回截图命令，stderr重定向到/dev/null以避免错误信息混入图像
+func screencapCommand() *shellcmd.Command {
+	return shellcmd.New("screencap", "-p").Redirect("2>", "/dev/null")
+}
+
+// Execute 通过shell:执行屏幕截图命令，旧设备输出中的CRLF会被还原
 func (c *ScreencapCommand) Execute() (io.Reader, error) {
-	// 发送命令，重定向stderr到/dev/null以避免错误信息
-	if err := c.sender("shell:echo && screencap -p 2>/dev/null"); err != nil {
-		return nil, fmt.Errorf("发送屏幕截图命令失败: %v", err)
+	reader, err := c.exec().ExecuteLegacy(screencapCommand())
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("设备不支持screencap命令")
 	}
+	return reader, err
+}
 
-	// 读取响应头
-	reply, err := c.reader(4)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
-	}
+// ExecuteRaw 通过exec:执行屏幕截图命令，输出不需要转换，设备不支持exec:时返回FailError
+func (c *ScreencapCommand) ExecuteRaw() (io.Reader, error) {
+	return c.exec().Execute(screencapCommand())
+}
 
-	switch reply {
-	case OKAY:
-		// 读取第一个字节来检测行结束符
-		firstByte, err := c.reader(1)
-		if err != nil {
-			if err.Error() == "EOF" {
-				return nil, fmt.Errorf("设备不支持screencap命令")
-			}
-			return nil, fmt.Errorf("读取数据失败: %v", err)
-		}
-
-		// 创建新的转换器
-		transform := NewLineTransform(firstByte, true)
-		return transform, nil
-
-	case FAIL:
-		errMsg, err := c.reader(0)
-		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
-		}
-		return nil, fmt.Errorf(errMsg)
-
-	default:
-		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
-	}
+// exec 创建使用相同连接和引用方式的exec命令
+func (c *ScreencapCommand) exec() *ExecCommand {
+	cmd := NewExecCommand(c.sender, c.reader)
+	cmd.SetDialect(c.dialect)
+	return cmd
 }
 
 // LineTransform 实现行结束符转换
+// 旧设备的shell会把输出中的LF转换为CRLF，需要还原
 type LineTransform struct {
-	command    *ScreencapCommand
-	buffer     []byte
-	autoDetect bool
-	firstByte  string
+	reader  func(int) (string, error)
+	convert bool
+	pending bool // 上一块数据以CR结尾，尚未确定是否属于CRLF
 }
 
-// NewLineTransform 创建新的行转换器
-func NewLineTransform(firstByte string, autoDetect bool) *LineTransform {
+// NewLineTransform 创建新的行转换器，convert为false时原样输出
+func NewLineTransform(reader func(int) (string, error), convert bool) *LineTransform {
 	return &LineTransform{
-		buffer:     make([]byte, 4096),
-		autoDetect: autoDetect,
-		firstByte:  firstByte,
+		reader:  reader,
+		convert: convert,
 	}
 }
 
 // Read 实现io.Reader接口
 func (t *LineTransform) Read(p []byte) (n int, err error) {
-	// 如果有第一个字节，先处理它
-	if len(t.firstByte) > 0 {
-		n = copy(p, []byte(t.firstByte))
-		t.firstByte = ""
-		return n, nil
+	if len(p) == 0 {
+		return 0, nil
 	}
 
-	// 从命令读取数据
-	data, err := t.command.reader(len(p))
-	if err != nil {
-		if err.Error() == "EOF" {
-			return 0, io.EOF
-		}
-		return 0, fmt.Errorf("读取数据失败: %v", err)
+	size := len(p)
+	if t.pending && size > 1 {
+		size--
 	}
 
-	// 如果没有数据返回，表示结束
-	if len(data) == 0 {
-		return 0, io.EOF
+	data, err := t.reader(size)
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("读取数据失败: %w", err)
 	}
 
-	// 如果需要自动检测和转换行结束符
-	if t.autoDetect {
-		// 转换行结束符
-		data = t.convertLineEndings(data)
+	if t.convert {
+		data = t.convertLineEndings(data, err == io.EOF)
+	}
+
+	if len(data) == 0 && err == io.EOF {
+		return 0, io.EOF
 	}
 
 	// 复制转换后的数据到目标缓冲区
-	return copy(p, []byte(data)), nil
+	return copy(p, data), nil
 }
 
-// convertLineEndings 转换行结束符
-func (t *LineTransform) convertLineEndings(data string) string {
-	// 根据需要实现行结束符转换逻辑
-	// 这里可以添加检测和转换CRLF/LF的代码
-	return data
-}
+// convertLineEndings 将CRLF转换为LF，末尾的CR保留到下一块数据中判断
+func (t *LineTransform) convertLineEndings(data string, eof bool) string {
+	if t.pending {
+		data = "\r" + data
+		t.pending = false
+	}
+
+	if !eof && strings.HasSuffix(data, "\r") {
+		data = data[:len(data)-1]
+		t.pending = true
+	}
 
-// Write 实现写入功能（如果需要）
-func (t *LineTransform) Write(p []byte) (n int, err error) {
-	// 实现写入逻辑（如果需要）
-	return len(p), nil
+	return strings.ReplaceAll(data, "\r\n", "\n")
 }
 
 // Close 关闭转换器（如果需要）
 func (t *LineTransform) Close() error {
-	// 实现清理逻辑（如果需要）
 	return nil
 }
@@ -3,12 +3,16 @@ package hosttransport
 import (
 	"fmt"
 	"io"
-	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // ShellCommand 实现shell命令
 type ShellCommand struct {
 	BaseCommand
+	stream   io.Reader
+	sentinel string
 }
 
 // NewShellCommand 创建新的shell命令实例
@@ -21,34 +25,35 @@ func NewShellCommand(sender func(string) error, reader func(int) (string, error)
 	}
 }
 
+// WithStream 设置读取命令输出的数据流，如连接本身，数据到达后立即返回
+// 默认通过reader读取，每次读取填满缓冲区或遇到EOF才返回
+func (c *ShellCommand) WithStream(stream io.Reader) *ShellCommand {
+	c.stream = stream
+	return c
+}
+
+// WithSentinel 设置ExecuteWithExitCode使用的哨兵，默认每次随机生成
+func (c *ShellCommand) WithSentinel(sentinel string) *ShellCommand {
+	c.sentinel = sentinel
+	return c
+}
+
 // Execute 执行shell命令
 func (c *ShellCommand) Execute(command interface{}) (io.Reader, error) {
-	var cmd string
-
-	// 检查命令是否为字符串数组
-	switch v := command.(type) {
-	case []string:
-		// 转义并连接命令
-		escapedCommands := make([]string, len(v))
-		for i, part := range v {
-			escapedCommands[i] = c.escape(part)
-		}
-		cmd = strings.Join(escapedCommands, " ")
-	case string:
-		cmd = v
-	default:
-		return nil, fmt.Errorf("不支持的命令类型: %T", command)
+	cmd, err := c.commandString(command)
+	if err != nil {
+		return nil, err
 	}
 
 	// 发送shell命令
 	if err := c.sender(fmt.Sprintf("shell:%s", cmd)); err != nil {
-		return nil, fmt.Errorf("发送shell命令失败: %v", err)
+		return nil, fmt.Errorf("发送shell命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -59,15 +64,34 @@ func (c *ShellCommand) Execute(command interface{}) (io.Reader, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf("shell命令失败: %s", errMsg)
+		return nil, fmt.Errorf("shell命令失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
 }
 
+// commandString 将命令转换为字符串，字符串数组中的每个参数都会按设备shell的规则引用，
+// 字符串作为原样的脚本使用，既不引用也不区分引用方式，
+// 其中含有包名、路径等外部数据时调用方应改用[]string或*shellcmd.Command
+func (c *BaseCommand) commandString(command interface{}) (string, error) {
+	switch v := command.(type) {
+	case []string:
+		if len(v) == 0 {
+			return "", fmt.Errorf("命令为空")
+		}
+		return shellcmd.New(v[0], v[1:]...).Dialect(c.dialect).Build()
+	case *shellcmd.Command:
+		return v.Dialect(c.dialect).Build()
+	case string:
+		return shellcmd.Raw(v).Build()
+	default:
+		return "", fmt.Errorf("不支持的命令类型: %T", command)
+	}
+}
+
 // shellReader 实现io.Reader接口，用于读取shell输出
 type shellReader struct {
 	command *ShellCommand
@@ -76,6 +100,9 @@ type shellReader struct {
 
 // createShellReader 创建新的shell读取器
 func (c *ShellCommand) createShellReader() io.Reader {
+	if c.stream != nil {
+		return c.stream
+	}
 	return &shellReader{
 		command: c,
 		buffer:  make([]byte, 4096), // 使用4KB的缓冲区
@@ -90,7 +117,7 @@ func (r *shellReader) Read(p []byte) (n int, err error) {
 		if err.Error() == "EOF" {
 			return 0, io.EOF
 		}
-		return 0, fmt.Errorf("读取shell数据失败: %v", err)
+		return 0, fmt.Errorf("读取shell数据失败: %w", err)
 	}
 
 	// 如果没有数据返回，表示结束
@@ -101,24 +128,3 @@ func (r *shellReader) Read(p []byte) (n int, err error) {
 	// 复制数据到目标缓冲区
 	return copy(p, []byte(data)), nil
 }
-
-// escape 转义命令中的特殊字符
-func (c *ShellCommand) escape(input string) string {
-	// 实现转义逻辑
-	// 这里需要根据具体需求实现，例如：
-	escaped := strings.ReplaceAll(input, " ", "\\ ")
-	escaped = strings.ReplaceAll(escaped, "(", "\\(")
-	escaped = strings.ReplaceAll(escaped, ")", "\\)")
-	escaped = strings.ReplaceAll(escaped, "[", "\\[")
-	escaped = strings.ReplaceAll(escaped, "]", "\\]")
-	escaped = strings.ReplaceAll(escaped, "&", "\\&")
-	escaped = strings.ReplaceAll(escaped, "|", "\\|")
-	escaped = strings.ReplaceAll(escaped, ";", "\\;")
-	escaped = strings.ReplaceAll(escaped, "<", "\\<")
-	escaped = strings.ReplaceAll(escaped, ">", "\\>")
-	escaped = strings.ReplaceAll(escaped, "$", "\\$")
-	escaped = strings.ReplaceAll(escaped, "`", "\\`")
-	escaped = strings.ReplaceAll(escaped, "\"", "\\\"")
-	escaped = strings.ReplaceAll(escaped, "'", "\\'")
-	return escaped
-}
@@ -0,0 +1,193 @@
+package hosttransport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// sentinelPrefix 哨兵的前缀，后接每次执行随机生成的十六进制串
+const sentinelPrefix = "ADBKIT_"
+
+// maxHeaderLength 包含进程ID的首行的最大长度
+const maxHeaderLength = 64
+
+// wrappedCommand 匹配WrapExitCode生成的命令
+var wrappedCommand = regexp.MustCompile(`^echo (` + sentinelPrefix + `[0-9a-f]+):\$\$\n\(\n((?s).*)\n\)\necho ([^:]+):\$\?$`)
+
+// NewSentinel 生成随机的哨兵，命令的输出中几乎不可能出现相同的字符串
+func NewSentinel() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return sentinelPrefix + hex.EncodeToString(b[:])
+}
+
+// NewSentinelSequence 返回按固定顺序生成哨兵的函数，每个返回的函数都从头开始，
+// 用于录制和回放会话时让客户端发送相同的命令
+func NewSentinelSequence() func() string {
+	var n atomic.Uint64
+	return func() string {
+		return fmt.Sprintf("%s%016x", sentinelPrefix, n.Add(1))
+	}
+}
+
+// WrapExitCode 包装命令，使shell先输出"<sentinel>:<进程ID>"行，命令结束后输出"<sentinel>:<退出码>"行
+// 命令在子shell中执行，命令中的exit或以exec结尾的命令只会结束子shell，外层shell仍然输出退出码
+func WrapExitCode(command string, sentinel string) string {
+	return fmt.Sprintf("echo %s:$$\n(\n%s\n)\necho %s:$?", sentinel, command, sentinel)
+}
+
+// UnwrapExitCode 解析WrapExitCode包装的命令，返回原始命令和哨兵，用于模拟设备
+func UnwrapExitCode(wrapped string) (command string, sentinel string, ok bool) {
+	match := wrappedCommand.FindStringSubmatch(wrapped)
+	if match == nil || match[1] != match[3] {
+		return wrapped, "", false
+	}
+	return match[2], match[1], true
+}
+
+// ExecuteWithExitCode 执行shell命令并通过哨兵得到退出码和进程ID，用于不支持shell_v2的设备
+// 返回的读取器去掉了哨兵行，读到EOF之后可以通过ExitCode得到退出码
+func (c *ShellCommand) ExecuteWithExitCode(command interface{}) (*ExitCodeReader, error) {
+	cmd, err := c.commandString(command)
+	if err != nil {
+		return nil, err
+	}
+
+	sentinel := c.sentinel
+	if sentinel == "" {
+		sentinel = NewSentinel()
+	}
+	reader, err := c.Execute(WrapExitCode(cmd, sentinel))
+	if err != nil {
+		return nil, err
+	}
+
+	// 读取首行的进程ID；shell在输出首行之前失败时，首行作为普通输出返回
+	var header []byte
+	for len(header) < maxHeaderLength && !bytes.HasSuffix(header, []byte("\n")) {
+		data, err := c.reader(1)
+		if err == io.EOF || (err == nil && data == "") {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取进程ID失败: %w", err)
+		}
+		header = append(header, data...)
+	}
+
+	pid := 0
+	if value, ok := strings.CutPrefix(strings.TrimRight(string(header), "\r\n"), sentinel+":"); ok && bytes.HasSuffix(header, []byte("\n")) {
+		if pid, err = strconv.Atoi(value); err == nil {
+			header = nil
+		}
+	}
+
+	return &ExitCodeReader{
+		reader:   reader,
+		sentinel: []byte(sentinel + ":"),
+		pid:      pid,
+		buf:      header,
+		ready:    len(header),
+	}, nil
+}
+
+// ExitCodeReader 去掉输出末尾的哨兵行并解析退出码
+type ExitCodeReader struct {
+	reader   io.Reader
+	sentinel []byte
+	pid      int
+	buf      []byte // 已读取但还没有返回的数据
+	ready    int    // buf中可以返回的长度，其余部分可能是哨兵的开头
+	trailer  []byte // 哨兵之后的数据，即退出码
+	found    bool
+	eof      bool
+	err      error // 读取错误，在返回剩余数据之后返回
+}
+
+// Pid 返回执行命令的shell的进程ID，未知时返回0
+func (r *ExitCodeReader) Pid() int {
+	return r.pid
+}
+
+// Read 实现io.Reader接口
+func (r *ExitCodeReader) Read(p []byte) (int, error) {
+	for {
+		if r.ready > 0 {
+			n := copy(p, r.buf[:r.ready])
+			r.buf = r.buf[n:]
+			r.ready -= n
+			return n, nil
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		chunk := make([]byte, 4096)
+		n, err := r.reader.Read(chunk)
+		if r.found {
+			r.trailer = append(r.trailer, chunk[:n]...)
+		} else {
+			r.buf = append(r.buf, chunk[:n]...)
+			r.scan()
+		}
+
+		if err != nil {
+			// 哨兵不会再完整出现，保留的数据也是命令的输出
+			if !r.found {
+				r.ready = len(r.buf)
+			}
+			if err == io.EOF {
+				r.eof = true
+			} else {
+				r.err = err
+			}
+		}
+	}
+}
+
+// scan 在缓冲区中查找哨兵，末尾可能是哨兵开头的数据留到下次读取时判断
+func (r *ExitCodeReader) scan() {
+	if i := bytes.Index(r.buf, r.sentinel); i >= 0 {
+		r.trailer = append(r.trailer, r.buf[i+len(r.sentinel):]...)
+		r.buf = r.buf[:i]
+		r.ready = i
+		r.found = true
+		return
+	}
+
+	keep := 0
+	for n := min(len(r.sentinel)-1, len(r.buf)); n > 0; n-- {
+		if bytes.HasSuffix(r.buf, r.sentinel[:n]) {
+			keep = n
+			break
+		}
+	}
+	r.ready = len(r.buf) - keep
+}
+
+// ExitCode 返回命令的退出码，需要先读到EOF
+// 没有读到哨兵行（如命令被杀死或连接被关闭）时返回io.ErrUnexpectedEOF
+func (r *ExitCodeReader) ExitCode() (int, error) {
+	if !r.eof {
+		return -1, fmt.Errorf("输出没有读取完")
+	}
+	if !r.found {
+		return -1, io.ErrUnexpectedEOF
+	}
+
+	code, err := strconv.Atoi(strings.TrimSpace(string(r.trailer)))
+	if err != nil {
+		return -1, fmt.Errorf("invalid exit code: %q", r.trailer)
+	}
+	return code, nil
+}
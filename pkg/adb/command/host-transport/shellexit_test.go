@@ -0,0 +1,96 @@
+package hosttransport
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const testSentinel = "ADBKIT_0123456789abcdef"
+
+func TestUnwrapExitCode(t *testing.T) {
+	commands := []string{"ls", "echo a; echo b", "cat <<EOF\nline\nEOF", "exit 3", ""}
+	for _, command := range commands {
+		got, sentinel, ok := UnwrapExitCode(WrapExitCode(command, testSentinel))
+		if !ok || got != command || sentinel != testSentinel {
+			t.Errorf("UnwrapExitCode(WrapExitCode(%q)) = %q, %q, %v", command, got, sentinel, ok)
+		}
+	}
+
+	if got, _, ok := UnwrapExitCode("ls -l"); ok || got != "ls -l" {
+		t.Errorf("UnwrapExitCode(ls -l) = %q, %v, want unchanged", got, ok)
+	}
+}
+
+func TestExitCodeReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		want     string
+		wantCode int
+		wantErr  error
+	}{
+		{"exit code", "out\n" + testSentinel + ":3\n", "out\n", 3, nil},
+		{"no output", testSentinel + ":0\n", "", 0, nil},
+		{"no trailing newline", "partial" + testSentinel + ":1\n", "partial", 1, nil},
+		{"crlf", "out\r\n" + testSentinel + ":2\r\n", "out\r\n", 2, nil},
+		{"sentinel prefix in output", "ADBKIT_01\n" + testSentinel + ":0\n", "ADBKIT_01\n", 0, nil},
+		{"killed", "out\n", "out\n", -1, io.ErrUnexpectedEOF},
+		{"truncated sentinel", "out\nADBKIT_0123", "out\nADBKIT_0123", -1, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 逐字节读取，哨兵被拆到多次读取中
+			reader := &ExitCodeReader{
+				reader:   iotest.OneByteReader(strings.NewReader(tt.output)),
+				sentinel: []byte(testSentinel + ":"),
+			}
+			output, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if string(output) != tt.want {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+			code, err := reader.ExitCode()
+			if !errors.Is(err, tt.wantErr) || code != tt.wantCode {
+				t.Errorf("ExitCode = %d, %v, want %d, %v", code, err, tt.wantCode, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWrapExitCodeShell 通过本机的sh验证exit和exec不会丢失退出码行
+func TestWrapExitCodeShell(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"echo hi", "hi\n" + testSentinel + ":0\n"},
+		{"false", testSentinel + ":1\n"},
+		{"exit 7", testSentinel + ":7\n"},
+		{"exec sh -c 'exit 5'", testSentinel + ":5\n"},
+		{"cd /; pwd", "/\n" + testSentinel + ":0\n"},
+	}
+
+	for _, tt := range tests {
+		output, _ := exec.Command(sh, "-c", WrapExitCode(tt.command, testSentinel)).Output()
+		// 首行是shell的进程ID
+		header, rest, _ := strings.Cut(string(output), "\n")
+		if !strings.HasPrefix(header, testSentinel+":") {
+			t.Errorf("%s: first line = %q, want the process ID", tt.command, header)
+		}
+		if rest != tt.want {
+			t.Errorf("%s: output = %q, want %q", tt.command, rest, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package hosttransport
+
+import (
+	"fmt"
+
+	"adb-kit-go/pkg/adb/adberr"
+)
+
+// ShellV2Command 实现shell协议v2的shell命令，需要设备支持shell_v2特性
+// 应答OKAY之后连接上传输的是shellproto格式的数据包
+type ShellV2Command struct {
+	BaseCommand
+}
+
+// NewShellV2Command 创建新的shell v2命令实例
+func NewShellV2Command(sender func(string) error, reader func(int) (string, error)) *ShellV2Command {
+	return &ShellV2Command{
+		BaseCommand: BaseCommand{
+			sender: sender,
+			reader: reader,
+		},
+	}
+}
+
+// Execute 在不分配PTY的情况下执行命令，stdout和stderr分别传输
+func (c *ShellV2Command) Execute(command string) error {
+	return c.execute("shell,v2,raw:" + command)
+}
+
+// ExecutePty 在PTY中执行命令，command为空时启动交互式shell
+// term为设备上的TERM环境变量，如xterm-256color，为空时使用设备的默认值；PTY的输出全部通过stdout传输
+func (c *ShellV2Command) ExecutePty(command string, term string) error {
+	service := "shell,v2,"
+	if term != "" {
+		service += "TERM=" + term + ","
+	}
+	return c.execute(service + "pty:" + command)
+}
+
+// execute 发送服务并读取应答
+func (c *ShellV2Command) execute(service string) error {
+	if err := c.sender(service); err != nil {
+		return fmt.Errorf("发送shell命令失败: %w", err)
+	}
+
+	reply, err := c.reader(4)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	switch reply {
+	case OKAY:
+		return nil
+
+	case FAIL:
+		errMsg, err := c.reader(0)
+		if err != nil {
+			return fmt.Errorf("读取错误信息失败: %w", err)
+		}
+		return fmt.Errorf("shell命令失败: %w", adberr.NewFailError(errMsg))
+
+	default:
+		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
+	}
+}
@@ -2,7 +2,12 @@ package hosttransport
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // StartActivityCommand 实现启动活动命令
@@ -20,123 +25,154 @@ func NewStartActivityCommand(sender func(string) error, reader func(int) (string
 	}
 }
 
+// IntentOptions 启动活动或服务的intent参数，对应am start的选项，空值的字段不传给am
+type IntentOptions struct {
+	Action    string   // -a
+	Data      string   // -d，数据URI
+	MimeType  string   // -t
+	Category  []string // -c，可以有多个
+	Component string   // -n，如com.example/.MainActivity
+	Flags     int      // -f，Intent.setFlags的标志位，0表示不设置
+	Extras    []Extra  // 按顺序传给am
+	Debug     bool     // -D，启用调试
+	Wait      bool     // -W，等待启动完成，只对活动有效
+	User      string   // --user，用户ID或current、all
+}
+
+// ExtraType intent附加数据的类型，值为am的选项
+type ExtraType string
+
+// 附加数据类型
+const (
+	ExtraString    ExtraType = "--es"
+	ExtraBool      ExtraType = "--ez"
+	ExtraInt       ExtraType = "--ei"
+	ExtraLong      ExtraType = "--el"
+	ExtraFloat     ExtraType = "--ef"
+	ExtraURI       ExtraType = "--eu"
+	ExtraComponent ExtraType = "--ecn"
+	ExtraNull      ExtraType = "--esn" // 值为null的字符串，没有Value
+)
+
+// Extra 一个intent附加数据
+type Extra struct {
+	Key   string
+	Type  ExtraType
+	Value string
+}
+
+// StringExtra 创建字符串附加数据
+func StringExtra(key, value string) Extra {
+	return Extra{Key: key, Type: ExtraString, Value: value}
+}
+
+// BoolExtra 创建布尔附加数据
+func BoolExtra(key string, value bool) Extra {
+	return Extra{Key: key, Type: ExtraBool, Value: strconv.FormatBool(value)}
+}
+
+// IntExtra 创建整数附加数据
+func IntExtra(key string, value int) Extra {
+	return Extra{Key: key, Type: ExtraInt, Value: strconv.Itoa(value)}
+}
+
+// LongExtra 创建长整数附加数据
+func LongExtra(key string, value int64) Extra {
+	return Extra{Key: key, Type: ExtraLong, Value: strconv.FormatInt(value, 10)}
+}
+
+// FloatExtra 创建浮点数附加数据，am的--ef按Java的float解析，因此值为float32
+func FloatExtra(key string, value float32) Extra {
+	return Extra{Key: key, Type: ExtraFloat, Value: strconv.FormatFloat(float64(value), 'f', -1, 32)}
+}
+
+// NullExtra 创建值为null的附加数据
+func NullExtra(key string) Extra {
+	return Extra{Key: key, Type: ExtraNull}
+}
+
 // Execute 执行启动活动命令
-func (c *StartActivityCommand) Execute(options map[string]interface{}) error {
+func (c *StartActivityCommand) Execute(options *IntentOptions) error {
 	args := c.intentArgs(options)
 
 	// 构建启动命令
-	cmd := fmt.Sprintf("shell:am start %s", strings.Join(args, " "))
-
-	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("发送启动活动命令失败: %v", err)
+	if err := c.sendShell(shellcmd.New("am", "start").Arg(args...)); err != nil {
+		return fmt.Errorf("发送启动活动命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
-		// 读取所有剩余数据
-		_, err := c.reader(0)
+		// 读取所有剩余数据，am在启动失败时仍然返回OKAY，错误信息在输出中
+		output, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取剩余数据失败: %v", err)
+			return fmt.Errorf("读取剩余数据失败: %w", err)
+		}
+		if match := errorLineRegex.FindStringSubmatch(output); match != nil {
+			return fmt.Errorf("启动活动失败: %s", strings.TrimSpace(match[1]))
 		}
 		return nil
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf("启动活动失败: %s", errMsg)
+		return fmt.Errorf("启动活动失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
 }
 
-// intentArgs 生成启动活动的参数
-func (c *StartActivityCommand) intentArgs(options map[string]interface{}) []string {
+// errorLineRegex 匹配am命令输出中的错误行
+var errorLineRegex = regexp.MustCompile(`(?m)^Error: (.*)$`)
+
+// intentArgs 生成启动活动的参数，参数在构建命令行时引用
+func (c *StartActivityCommand) intentArgs(options *IntentOptions) []string {
 	var args []string
+	if options == nil {
+		return args
+	}
 
-	if extras, ok := options["extras"].(map[string]interface{}); ok {
-		args = append(args, c.formatExtras(extras)...)
+	for _, extra := range options.Extras {
+		args = append(args, string(extra.Type), extra.Key)
+		if extra.Type != ExtraNull {
+			args = append(args, extra.Value)
+		}
 	}
-	if action, ok := options["action"].(string); ok {
-		args = append(args, "-a", c.escape(action))
+	if options.Action != "" {
+		args = append(args, "-a", options.Action)
 	}
-	if data, ok := options["data"].(string); ok {
-		args = append(args, "-d", c.escape(data))
+	if options.Data != "" {
+		args = append(args, "-d", options.Data)
 	}
-	if mimeType, ok := options["mimeType"].(string); ok {
-		args = append(args, "-t", c.escape(mimeType))
+	if options.MimeType != "" {
+		args = append(args, "-t", options.MimeType)
 	}
-	if category, ok := options["category"].([]string); ok {
-		for _, cat := range category {
-			args = append(args, "-c", c.escape(cat))
-		}
+	for _, category := range options.Category {
+		args = append(args, "-c", category)
 	}
-	if component, ok := options["component"].(string); ok {
-		args = append(args, "-n", c.escape(component))
+	if options.Component != "" {
+		args = append(args, "-n", options.Component)
 	}
-	if flags, ok := options["flags"].(string); ok {
-		args = append(args, "-f", c.escape(flags))
+	if options.Flags != 0 {
+		args = append(args, "-f", strconv.Itoa(options.Flags))
 	}
-	if debug, ok := options["debug"].(bool); ok && debug {
+	if options.Debug {
 		args = append(args, "-D")
 	}
-	if wait, ok := options["wait"].(bool); ok && wait {
+	if options.Wait {
 		args = append(args, "-W")
 	}
-	if user, ok := options["user"].(int); ok {
-		args = append(args, "--user", fmt.Sprintf("%d", user))
+	if options.User != "" {
+		args = append(args, "--user", options.User)
 	}
 
 	return args
 }
-
-// formatExtras 格式化额外参数
-func (c *StartActivityCommand) formatExtras(extras map[string]interface{}) []string {
-	var args []string
-	for key, value := range extras {
-		switch v := value.(type) {
-		case string:
-			args = append(args, fmt.Sprintf("--es %s %s", c.escape(key), c.escape(v)))
-		case bool:
-			args = append(args, fmt.Sprintf("--ez %s %t", c.escape(key), v))
-		case int:
-			args = append(args, fmt.Sprintf("--ei %s %d", c.escape(key), v))
-		case float64:
-			args = append(args, fmt.Sprintf("--ef %s %f", c.escape(key), v))
-		case nil:
-			args = append(args, fmt.Sprintf("--esn %s", c.escape(key)))
-		default:
-			// Handle other types or throw an error
-		}
-	}
-	return args
-}
-
-// escape 转义命令中的特殊字符
-func (c *StartActivityCommand) escape(input string) string {
-	// 实现转义逻辑
-	// 这里需要根据具体需求实现，例如：
-	escaped := strings.ReplaceAll(input, " ", "\\ ")
-	escaped = strings.ReplaceAll(escaped, "(", "\\(")
-	escaped = strings.ReplaceAll(escaped, ")", "\\)")
-	escaped = strings.ReplaceAll(escaped, "[", "\\[")
-	escaped = strings.ReplaceAll(escaped, "]", "\\]")
-	escaped = strings.ReplaceAll(escaped, "&", "\\&")
-	escaped = strings.ReplaceAll(escaped, "|", "\\|")
-	escaped = strings.ReplaceAll(escaped, ";", "\\;")
-	escaped = strings.ReplaceAll(escaped, "<", "\\<")
-	escaped = strings.ReplaceAll(escaped, ">", "\\>")
-	escaped = strings.ReplaceAll(escaped, "$", "\\$")
-	escaped = strings.ReplaceAll(escaped, "`", "\\`")
-	escaped = strings.ReplaceAll(escaped, "\"", "\\\"")
-	escaped = strings.ReplaceAll(escaped, "'", "\\'")
-	return escaped
-}
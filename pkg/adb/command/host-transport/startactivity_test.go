@@ -0,0 +1,28 @@
+package hosttransport
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExtraValues(t *testing.T) {
+	tests := []struct {
+		extra Extra
+		want  string
+	}{
+		{StringExtra("k", "a b"), "a b"},
+		{BoolExtra("k", true), "true"},
+		{IntExtra("k", -3), "-3"},
+		{LongExtra("k", math.MaxInt64), "9223372036854775807"},
+		{FloatExtra("k", 0.1), "0.1"},
+		{FloatExtra("k", 16777217), "16777216"}, // float32只有24位精度
+		{FloatExtra("k", -2.5e-3), "-0.0025"},
+		{NullExtra("k"), ""},
+	}
+
+	for _, tt := range tests {
+		if tt.extra.Value != tt.want {
+			t.Errorf("%s value = %q, want %q", tt.extra.Type, tt.extra.Value, tt.want)
+		}
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/retry"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // StartServiceCommand 实现启动服务命令
@@ -25,34 +29,28 @@ func NewStartServiceCommand(sender func(string) error, reader func(int) (string,
 }
 
 // Execute 执行启动服务命令
-func (c *StartServiceCommand) Execute(options map[string]interface{}) error {
+func (c *StartServiceCommand) Execute(options *IntentOptions) error {
 	// 获取intent参数
+	// intentArgs已经包含用户参数（如果存在）
 	args := c.intentArgs(options)
 
-	// 添加用户参数（如果存在）
-	if user, ok := options["user"].(int); ok {
-		args = append(args, "--user", fmt.Sprintf("%d", user))
-	}
-
 	// 构建启动服务命令
-	cmd := fmt.Sprintf("shell:am startservice %s", strings.Join(args, " "))
-
-	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("发送启动服务命令失败: %v", err)
+	if err := c.sendShell(shellcmd.New("am", "startservice").Arg(args...)); err != nil {
+		return fmt.Errorf("发送启动服务命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取并检查服务启动结果
-		output, err := c.reader(0)
+		output, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取服务启动结果失败: %v", err)
+			return fmt.Errorf("读取服务启动结果失败: %w", err)
 		}
 
 		// 检查输出是否包含错误信息
@@ -64,9 +62,9 @@ func (c *StartServiceCommand) Execute(options map[string]interface{}) error {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf("启动服务失败: %s", errMsg)
+		return fmt.Errorf("启动服务失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -74,7 +72,7 @@ func (c *StartServiceCommand) Execute(options map[string]interface{}) error {
 }
 
 // ExecuteWithTimeout 执行启动服务命令并设置超时
-func (c *StartServiceCommand) ExecuteWithTimeout(options map[string]interface{}, timeout time.Duration) error {
+func (c *StartServiceCommand) ExecuteWithTimeout(options *IntentOptions, timeout time.Duration) error {
 	// 创建一个带有超时的上下文
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -97,18 +95,17 @@ func (c *StartServiceCommand) ExecuteWithTimeout(options map[string]interface{},
 }
 
 // ExecuteWithRetry 执行启动服务命令并在失败时重试
-func (c *StartServiceCommand) ExecuteWithRetry(options map[string]interface{}, maxRetries int, retryDelay time.Duration) error {
-	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		err := c.Execute(options)
-		if err == nil {
-			return nil
-		}
-		lastErr = err
-
-		if i < maxRetries-1 {
-			time.Sleep(retryDelay)
-		}
+func (c *StartServiceCommand) ExecuteWithRetry(options *IntentOptions, maxRetries int, retryDelay time.Duration) error {
+	policy := &retry.Policy{
+		MaxAttempts:    maxRetries,
+		InitialBackoff: retryDelay,
+		Retryable:      func(error) bool { return true },
+	}
+	err := policy.Do(context.Background(), func(context.Context) error {
+		return c.Execute(options)
+	})
+	if err != nil {
+		return fmt.Errorf("启动服务失败（重试%d次后）: %w", maxRetries, err)
 	}
-	return fmt.Errorf("启动服务失败（重试%d次后）: %v", maxRetries, lastErr)
+	return nil
 }
@@ -3,6 +3,8 @@ package hosttransport
 import (
 	"fmt"
 	"io"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // SyncCommand 实现同步命令
@@ -24,13 +26,13 @@ func NewSyncCommand(sender func(string) error, reader func(int) (string, error))
 func (c *SyncCommand) Execute() (*SyncConnection, error) {
 	// 发送同步命令
 	if err := c.sender("sync:"); err != nil {
-		return nil, fmt.Errorf("发送同步命令失败: %v", err)
+		return nil, fmt.Errorf("发送同步命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -41,9 +43,9 @@ func (c *SyncCommand) Execute() (*SyncConnection, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf("同步命令失败: %s", errMsg)
+		return nil, fmt.Errorf("同步命令失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -69,7 +71,7 @@ func (c *SyncConnection) Push(reader io.Reader, path string, mode int, mtime int
 	// 发送SEND命令
 	cmd := fmt.Sprintf("SEND,%s,%d", path, mode)
 	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("发送SEND命令失败: %v", err)
+		return fmt.Errorf("发送SEND命令失败: %w", err)
 	}
 
 	// 传输文件数据
@@ -77,13 +79,13 @@ func (c *SyncConnection) Push(reader io.Reader, path string, mode int, mtime int
 	for {
 		n, err := reader.Read(buffer)
 		if err != nil && err != io.EOF {
-			return fmt.Errorf("读取文件数据失败: %v", err)
+			return fmt.Errorf("读取文件数据失败: %w", err)
 		}
 
 		if n > 0 {
 			// 发送数据块
 			if err := c.sender(string(buffer[:n])); err != nil {
-				return fmt.Errorf("发送文件数据失败: %v", err)
+				return fmt.Errorf("发送文件数据失败: %w", err)
 			}
 		}
 
@@ -95,13 +97,13 @@ func (c *SyncConnection) Push(reader io.Reader, path string, mode int, mtime int
 	// 发送DONE命令和修改时间
 	doneCmd := fmt.Sprintf("DONE,%d", mtime)
 	if err := c.sender(doneCmd); err != nil {
-		return fmt.Errorf("发送DONE命令失败: %v", err)
+		return fmt.Errorf("发送DONE命令失败: %w", err)
 	}
 
 	// 检查响应
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取最终响应失败: %v", err)
+		return fmt.Errorf("读取最终响应失败: %w", err)
 	}
 
 	if reply != OKAY {
@@ -116,7 +118,7 @@ func (c *SyncConnection) Pull(path string) (io.Reader, error) {
 	// 发送RECV命令
 	cmd := fmt.Sprintf("RECV,%s", path)
 	if err := c.sender(cmd); err != nil {
-		return nil, fmt.Errorf("发送RECV命令失败: %v", err)
+		return nil, fmt.Errorf("发送RECV命令失败: %w", err)
 	}
 
 	// 创建并返回文件读取器
@@ -140,7 +142,7 @@ func (r *syncReader) Read(p []byte) (n int, err error) {
 		// 需要读取新的数据块
 		data, err := r.connection.reader(len(r.buffer))
 		if err != nil {
-			return 0, fmt.Errorf("读取同步数据失败: %v", err)
+			return 0, fmt.Errorf("读取同步数据失败: %w", err)
 		}
 
 		if len(data) == 0 {
@@ -162,7 +164,7 @@ func (r *syncReader) Read(p []byte) (n int, err error) {
 func (c *SyncConnection) Close() error {
 	// 发送QUIT命令
 	if err := c.sender("QUIT"); err != nil {
-		return fmt.Errorf("发送QUIT命令失败: %v", err)
+		return fmt.Errorf("发送QUIT命令失败: %w", err)
 	}
 	return nil
 }
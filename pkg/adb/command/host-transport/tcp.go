@@ -3,6 +3,8 @@ package hosttransport
 import (
 	"fmt"
 	"io"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // TcpCommand 实现TCP连接命令
@@ -32,13 +34,13 @@ func (c *TcpCommand) Execute(port int, host string) (io.Reader, error) {
 
 	// 发送TCP命令
 	if err := c.sender(cmd); err != nil {
-		return nil, fmt.Errorf("发送TCP命令失败: %v", err)
+		return nil, fmt.Errorf("发送TCP命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -49,9 +51,9 @@ func (c *TcpCommand) Execute(port int, host string) (io.Reader, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf("TCP连接失败: %s", errMsg)
+		return nil, fmt.Errorf("TCP连接失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -80,7 +82,7 @@ func (r *tcpReader) Read(p []byte) (n int, err error) {
 		if err.Error() == "EOF" {
 			return 0, io.EOF
 		}
-		return 0, fmt.Errorf("读取TCP数据失败: %v", err)
+		return 0, fmt.Errorf("读取TCP数据失败: %w", err)
 	}
 
 	// 如果没有数据返回，表示结束
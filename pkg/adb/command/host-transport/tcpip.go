@@ -3,6 +3,8 @@ package hosttransport
 import (
 	"fmt"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // TcpIpCommand 实现TCP/IP命令
@@ -25,21 +27,21 @@ func (c *TcpIpCommand) Execute(port int) (int, error) {
 	// 发送TCP/IP命令
 	cmd := fmt.Sprintf("tcpip:%d", port)
 	if err := c.sender(cmd); err != nil {
-		return 0, fmt.Errorf("发送TCP/IP命令失败: %v", err)
+		return 0, fmt.Errorf("发送TCP/IP命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return 0, fmt.Errorf("读取响应失败: %v", err)
+		return 0, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取完整响应
-		response, err := c.reader(0)
+		response, err := c.reader(-1)
 		if err != nil {
-			return 0, fmt.Errorf("读取响应数据失败: %v", err)
+			return 0, fmt.Errorf("读取响应数据失败: %w", err)
 		}
 
 		// 检查响应是否包含成功消息
@@ -51,9 +53,9 @@ func (c *TcpIpCommand) Execute(port int) (int, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return 0, fmt.Errorf("读取错误信息失败: %v", err)
+			return 0, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return 0, fmt.Errorf("TCP/IP命令失败: %s", errMsg)
+		return 0, fmt.Errorf("TCP/IP命令失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return 0, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
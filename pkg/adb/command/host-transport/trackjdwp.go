@@ -3,6 +3,9 @@ package hosttransport
 import (
 	"fmt"
 	"strings"
+	"sync"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // TrackJdwpCommand 实现JDWP跟踪命令
@@ -23,12 +26,12 @@ func NewTrackJdwpCommand(sender func(string) error, reader func(int) (string, er
 // Execute 执行JDWP跟踪命令
 func (c *TrackJdwpCommand) Execute() (*JdwpTracker, error) {
 	if err := c.sender("track-jdwp"); err != nil {
-		return nil, fmt.Errorf("发送JDWP跟踪命令失败: %v", err)
+		return nil, fmt.Errorf("发送JDWP跟踪命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -37,9 +40,9 @@ func (c *TrackJdwpCommand) Execute() (*JdwpTracker, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
@@ -52,6 +55,8 @@ type JdwpTracker struct {
 	pids     []string
 	pidMap   map[string]bool
 	handlers map[string][]func(string)
+	ended    bool
+	mu       sync.Mutex
 }
 
 // NewJdwpTracker 创建新的JDWP跟踪器
@@ -72,8 +77,12 @@ func (t *JdwpTracker) readLoop() {
 	for {
 		data, err := t.reader(0)
 		if err != nil {
+			// 主动结束时连接被关闭，不再报告错误
+			if t.isEnded() {
+				return
+			}
 			t.emit("error", err.Error())
-			t.emit("end", "")
+			t.End()
 			return
 		}
 
@@ -115,6 +124,9 @@ func (t *JdwpTracker) update(newPids []string) {
 
 // On 注册事件处理器
 func (t *JdwpTracker) On(event string, handler func(string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if t.handlers[event] == nil {
 		t.handlers[event] = make([]func(string), 0)
 	}
@@ -123,14 +135,33 @@ func (t *JdwpTracker) On(event string, handler func(string)) {
 
 // emit 触发事件
 func (t *JdwpTracker) emit(event string, data string) {
-	if handlers, ok := t.handlers[event]; ok {
-		for _, handler := range handlers {
-			handler(data)
-		}
+	t.mu.Lock()
+	handlers := make([]func(string), len(t.handlers[event]))
+	copy(handlers, t.handlers[event])
+	t.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(data)
 	}
 }
 
-// End 结束跟踪
+// isEnded 检查跟踪是否已结束
+func (t *JdwpTracker) isEnded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ended
+}
+
+// End 结束跟踪，只会触发一次end事件
+// 调用方应在end事件中关闭底层连接以停止读取循环
 func (t *JdwpTracker) End() {
+	t.mu.Lock()
+	if t.ended {
+		t.mu.Unlock()
+		return
+	}
+	t.ended = true
+	t.mu.Unlock()
+
 	t.emit("end", "")
 }
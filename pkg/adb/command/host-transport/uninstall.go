@@ -2,12 +2,16 @@ package hosttransport
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // UninstallCommand 实现卸载命令
 type UninstallCommand struct {
 	BaseCommand
+	useCmd bool
 }
 
 // NewUninstallCommand 创建新的卸载命令实例
@@ -20,25 +24,31 @@ func NewUninstallCommand(sender func(string) error, reader func(int) (string, er
 	}
 }
 
+// UseCmd 通过cmd package而不是pm卸载
+func (c *UninstallCommand) UseCmd() *UninstallCommand {
+	c.useCmd = true
+	return c
+}
+
 // Execute 执行卸载命令
 func (c *UninstallCommand) Execute(pkg string) error {
 	// 发送卸载命令
-	if err := c.sender(fmt.Sprintf("shell:pm uninstall %s", pkg)); err != nil {
-		return fmt.Errorf("发送卸载命令失败: %v", err)
+	if err := c.sendShell(packageManager(c.useCmd).Arg("uninstall", pkg)); err != nil {
+		return fmt.Errorf("发送卸载命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取卸载结果
-		output, err := c.reader(0)
+		output, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取卸载结果失败: %v", err)
+			return fmt.Errorf("读取卸载结果失败: %w", err)
 		}
 
 		// 检查输出
@@ -53,9 +63,9 @@ func (c *UninstallCommand) Execute(pkg string) error {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf("uninstall failed: %s", errMsg)
+		return fmt.Errorf("uninstall failed: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -64,29 +74,29 @@ func (c *UninstallCommand) Execute(pkg string) error {
 
 // ExecuteWithOptions 执行带选项的卸载命令
 func (c *UninstallCommand) ExecuteWithOptions(pkg string, keepData bool, user int) error {
-	cmd := fmt.Sprintf("shell:pm uninstall")
+	cmd := packageManager(c.useCmd).Arg("uninstall")
 	if keepData {
-		cmd += " -k"
+		cmd.Arg("-k")
 	}
 	if user >= 0 {
-		cmd += fmt.Sprintf(" --user %d", user)
+		cmd.Arg("--user", strconv.Itoa(user))
 	}
-	cmd += " " + pkg
+	cmd.Arg(pkg)
 
-	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("发送卸载命令失败: %v", err)
+	if err := c.sendShell(cmd); err != nil {
+		return fmt.Errorf("发送卸载命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
-		output, err := c.reader(0)
+		output, err := c.reader(-1)
 		if err != nil {
-			return fmt.Errorf("读取卸载结果失败: %v", err)
+			return fmt.Errorf("读取卸载结果失败: %w", err)
 		}
 
 		output = strings.TrimSpace(output)
@@ -98,9 +108,9 @@ func (c *UninstallCommand) ExecuteWithOptions(pkg string, keepData bool, user in
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf("uninstall failed: %s", errMsg)
+		return fmt.Errorf("uninstall failed: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -3,6 +3,8 @@ package hosttransport
 import (
 	"fmt"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 // UsbCommand 实现USB命令
@@ -24,21 +26,21 @@ func NewUsbCommand(sender func(string) error, reader func(int) (string, error))
 func (c *UsbCommand) Execute() (bool, error) {
 	// 发送USB命令
 	if err := c.sender("usb:"); err != nil {
-		return false, fmt.Errorf("发送USB命令失败: %v", err)
+		return false, fmt.Errorf("发送USB命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return false, fmt.Errorf("读取响应失败: %v", err)
+		return false, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		// 读取完整响应
-		response, err := c.reader(0)
+		response, err := c.reader(-1)
 		if err != nil {
-			return false, fmt.Errorf("读取响应数据失败: %v", err)
+			return false, fmt.Errorf("读取响应数据失败: %w", err)
 		}
 
 		// 检查响应是否包含成功消息
@@ -50,9 +52,9 @@ func (c *UsbCommand) Execute() (bool, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return false, fmt.Errorf("读取错误信息失败: %v", err)
+			return false, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return false, fmt.Errorf("USB命令失败: %s", errMsg)
+		return false, fmt.Errorf("USB命令失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return false, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -2,7 +2,11 @@ package hosttransport
 
 import (
 	"fmt"
+	"regexp"
 	"time"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/shellcmd"
 )
 
 // WaitBootCompleteCommand 实现等待启动完成命令
@@ -23,40 +27,31 @@ func NewWaitBootCompleteCommand(sender func(string) error, reader func(int) (str
 // Execute 执行等待启动完成命令
 func (c *WaitBootCompleteCommand) Execute() error {
 	// 发送等待启动完成命令
-	cmd := "shell:while getprop sys.boot_completed 2>/dev/null; do sleep 1; done"
-	if err := c.sender(cmd); err != nil {
-		return fmt.Errorf("发送等待启动完成命令失败: %v", err)
+	cmd := shellcmd.Raw("while getprop sys.boot_completed 2>/dev/null; do sleep 1; done")
+	if err := c.sendShell(cmd); err != nil {
+		return fmt.Errorf("发送等待启动完成命令失败: %w", err)
 	}
 
 	// 读取响应头
 	reply, err := c.reader(4)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
-		// 等待直到收到 "1" 表示启动完成
-		for {
-			output, err := c.reader(0)
-			if err != nil {
-				return fmt.Errorf("读取启动状态失败: %v", err)
-			}
-
-			if output == "1\n" {
-				return nil
-			}
-
-			// 短暂休眠避免过度消耗CPU
-			time.Sleep(100 * time.Millisecond)
+		// 等待直到输出 "1" 表示启动完成
+		if _, err := c.searchLine(regexp.MustCompile(`^1$`)); err != nil {
+			return fmt.Errorf("读取启动状态失败: %w", err)
 		}
+		return nil
 
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return fmt.Errorf("读取错误信息失败: %v", err)
+			return fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return fmt.Errorf("等待启动完成失败: %s", errMsg)
+		return fmt.Errorf("等待启动完成失败: %w", adberr.NewFailError(errMsg))
 
 	default:
 		return fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
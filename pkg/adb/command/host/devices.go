@@ -1,10 +1,13 @@
 package host
 
 import (
+	"encoding/binary"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
 )
 
 const (
@@ -13,10 +16,16 @@ const (
 )
 
 // Device 表示一个ADB设备
+// Type之外的字段仅在 devices-l 和 track-devices-l 命令中使用
 type Device struct {
-	ID   string
-	Type string
-	Path string // 仅在 devices-l 命令中使用
+	ID          string
+	Type        string
+	Path        string // USB路径，如 usb:1-1，非USB设备为空
+	Product     string
+	Model       string
+	DeviceName  string // devices -l 中的device字段，即设备代号
+	TransportID uint64
+	Extras      map[string]string // 其他未识别的键值对
 }
 
 // Command 接口定义了所有ADB主机命令的基本行为
@@ -48,14 +57,25 @@ type VersionCommand struct {
 	BaseCommand
 }
 
+// DisconnectCommand 实现断开网络设备连接的命令
+type DisconnectCommand struct {
+	BaseCommand
+}
+
 type TransportCommand struct {
 	BaseCommand
 }
 
+// TportCommand 切换到设备传输并返回传输ID
+type TportCommand struct {
+	BaseCommand
+}
+
 type TrackDevicesCommand struct {
 	BaseCommand
 	DevicesCommand
 	onTrack func([]Device)
+	long    bool // 使用host:track-devices-l，列表包含devices -l的全部字段
 }
 
 // Tracker 接口定义设备跟踪器的行为
@@ -113,6 +133,15 @@ func NewVersionCommand(sender func(string) error, reader func(int) (string, erro
 	}
 }
 
+func NewDisconnectCommand(sender func(string) error, reader func(int) (string, error)) *DisconnectCommand {
+	return &DisconnectCommand{
+		BaseCommand: BaseCommand{
+			sender: sender,
+			reader: reader,
+		},
+	}
+}
+
 func NewTransportCommand(sender func(string) error, reader func(int) (string, error)) *TransportCommand {
 	return &TransportCommand{
 		BaseCommand: BaseCommand{
@@ -122,16 +151,34 @@ func NewTransportCommand(sender func(string) error, reader func(int) (string, er
 	}
 }
 
-func NewTrackDevicesCommand(sender func(string) error, reader func(int) (string, error), onTrack func([]Device)) *TrackDevicesCommand {
-	return &TrackDevicesCommand{
+func NewTportCommand(sender func(string) error, reader func(int) (string, error)) *TportCommand {
+	return &TportCommand{
 		BaseCommand: BaseCommand{
 			sender: sender,
 			reader: reader,
 		},
-		onTrack: onTrack,
 	}
 }
 
+func NewTrackDevicesCommand(sender func(string) error, reader func(int) (string, error), onTrack func([]Device)) *TrackDevicesCommand {
+	base := BaseCommand{
+		sender: sender,
+		reader: reader,
+	}
+	return &TrackDevicesCommand{
+		BaseCommand:    base,
+		DevicesCommand: DevicesCommand{BaseCommand: base},
+		onTrack:        onTrack,
+	}
+}
+
+// NewTrackDevicesLongCommand 创建使用host:track-devices-l的跟踪命令，设备包含型号和传输ID等字段
+func NewTrackDevicesLongCommand(sender func(string) error, reader func(int) (string, error), onTrack func([]Device)) *TrackDevicesCommand {
+	c := NewTrackDevicesCommand(sender, reader, onTrack)
+	c.long = true
+	return c
+}
+
 // 连接成功的正则表达式
 var reOK = regexp.MustCompile(`connected to|already connected`)
 
@@ -144,13 +191,13 @@ func (c *BaseCommand) Execute(host string, port string) (string, error) {
 	// 发送连接命令
 	cmd := fmt.Sprintf("host:connect:%s:%s", host, port)
 	if err := c.sender(cmd); err != nil {
-		return "", fmt.Errorf("发送命令失败: %v", err)
+		return "", fmt.Errorf("发送命令失败: %w", err)
 	}
 
 	// 读取4字节响应
 	reply, err := c.reader(4)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %v", err)
+		return "", fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -158,7 +205,7 @@ func (c *BaseCommand) Execute(host string, port string) (string, error) {
 		// 读取详细响应
 		value, err := c.reader(0) // 0表示读取到结束
 		if err != nil {
-			return "", fmt.Errorf("读取详细信息失败: %v", err)
+			return "", fmt.Errorf("读取详细信息失败: %w", err)
 		}
 
 		if reOK.MatchString(value) {
@@ -170,9 +217,48 @@ func (c *BaseCommand) Execute(host string, port string) (string, error) {
 		// 读取错误信息
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return "", fmt.Errorf("读取错误信息失败: %v", err)
+			return "", fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return "", fmt.Errorf(errMsg)
+		return "", adberr.NewFailError(errMsg)
+
+	default:
+		return "", fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
+	}
+}
+
+// Execute 执行断开连接命令
+// 可能的返回值:
+// - "No such device 192.168.2.2:5555"
+// - ""
+func (c *DisconnectCommand) Execute(host string, port string) (string, error) {
+	cmd := fmt.Sprintf("host:disconnect:%s:%s", host, port)
+	if err := c.sender(cmd); err != nil {
+		return "", fmt.Errorf("发送命令失败: %w", err)
+	}
+
+	reply, err := c.reader(4)
+	if err != nil {
+		return "", fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	switch reply {
+	case OKAY:
+		value, err := c.reader(0)
+		if err != nil {
+			return "", fmt.Errorf("读取详细信息失败: %w", err)
+		}
+
+		if value == "" || strings.HasPrefix(value, "disconnected") {
+			return fmt.Sprintf("%s:%s", host, port), nil
+		}
+		return "", fmt.Errorf(value)
+
+	case FAIL:
+		errMsg, err := c.reader(0)
+		if err != nil {
+			return "", fmt.Errorf("读取错误信息失败: %w", err)
+		}
+		return "", adberr.NewFailError(errMsg)
 
 	default:
 		return "", fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
@@ -182,12 +268,12 @@ func (c *BaseCommand) Execute(host string, port string) (string, error) {
 // Execute 执行设备列表查询命令
 func (c *DevicesCommand) Execute() (interface{}, error) {
 	if err := c.sender("host:devices"); err != nil {
-		return nil, fmt.Errorf("发送命令失败: %v", err)
+		return nil, fmt.Errorf("发送命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -196,9 +282,9 @@ func (c *DevicesCommand) Execute() (interface{}, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
@@ -207,12 +293,12 @@ func (c *DevicesCommand) Execute() (interface{}, error) {
 // Execute 执行带路径信息的设备列表查询命令
 func (c *DevicesWithPathsCommand) Execute() (interface{}, error) {
 	if err := c.sender("host:devices-l"); err != nil {
-		return nil, fmt.Errorf("发送命令失败: %v", err)
+		return nil, fmt.Errorf("发送命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -221,9 +307,9 @@ func (c *DevicesWithPathsCommand) Execute() (interface{}, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
@@ -232,12 +318,12 @@ func (c *DevicesWithPathsCommand) Execute() (interface{}, error) {
 // Execute 执行终止ADB服务器命令
 func (c *KillCommand) Execute() (interface{}, error) {
 	if err := c.sender("host:kill"); err != nil {
-		return nil, fmt.Errorf("发送终止命令失败: %v", err)
+		return nil, fmt.Errorf("发送终止命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -246,9 +332,9 @@ func (c *KillCommand) Execute() (interface{}, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return false, fmt.Errorf(errMsg)
+		return false, adberr.NewFailError(errMsg)
 	default:
 		return false, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
@@ -258,7 +344,7 @@ func (c *KillCommand) Execute() (interface{}, error) {
 func (c *DevicesCommand) readDevices() ([]Device, error) {
 	value, err := c.reader(0)
 	if err != nil {
-		return nil, fmt.Errorf("读取设备列表失败: %v", err)
+		return nil, fmt.Errorf("读取设备列表失败: %w", err)
 	}
 	return c.parseDevices(value)
 }
@@ -266,7 +352,7 @@ func (c *DevicesCommand) readDevices() ([]Device, error) {
 func (c *DevicesWithPathsCommand) readDevices() ([]Device, error) {
 	value, err := c.reader(0)
 	if err != nil {
-		return nil, fmt.Errorf("读取设备列表失败: %v", err)
+		return nil, fmt.Errorf("读取设备列表失败: %w", err)
 	}
 	return c.parseDevices(value)
 }
@@ -297,53 +383,121 @@ func (c *DevicesCommand) parseDevices(value string) ([]Device, error) {
 }
 
 func (c *DevicesWithPathsCommand) parseDevices(value string) ([]Device, error) {
-	devices := make([]Device, 0)
-	if value == "" {
-		return devices, nil
+	return ParseDevicesLong(value)
+}
+
+// readTracked 读取一次跟踪推送的设备列表
+func (c *TrackDevicesCommand) readTracked() ([]Device, error) {
+	if !c.long {
+		return c.readDevices()
+	}
+	value, err := c.reader(0)
+	if err != nil {
+		return nil, fmt.Errorf("读取设备列表失败: %w", err)
 	}
+	return ParseDevicesLong(value)
+}
 
-	lines := strings.Split(value, "\n")
-	for _, line := range lines {
+// devices -l 中有专门字段的键
+var longDeviceKeys = []string{"usb:", "product:", "model:", "device:", "transport_id:"}
+
+// ParseDevicesLong 解析 host:devices-l 和 host:track-devices-l 返回的设备列表
+// 每行为序列号、状态和若干 key:value 字段，也兼容只有序列号和状态的短格式
+func ParseDevicesLong(value string) ([]Device, error) {
+	devices := make([]Device, 0)
+	for _, line := range strings.Split(value, "\n") {
 		if line = strings.TrimSpace(line); line == "" {
 			continue
 		}
 
-		parts := strings.Fields(line)
-		if len(parts) < 3 {
-			return nil, fmt.Errorf("无效的设备信息格式: %s", line)
+		device, err := ParseDeviceLong(line)
+		if err != nil {
+			return nil, err
 		}
-
-		devices = append(devices, Device{
-			ID:   parts[0],
-			Type: parts[1],
-			Path: parts[2],
-		})
+		devices = append(devices, device)
 	}
 	return devices, nil
 }
+
+// ParseDeviceLong 解析 devices -l 格式的一行
+func ParseDeviceLong(line string) (Device, error) {
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return Device{}, fmt.Errorf("无效的设备信息格式: %s", line)
+	}
+
+	device := Device{ID: parts[0], Type: parts[1]}
+	fields := parts[2:]
+	if device.Type == "no" && len(fields) > 0 && fields[0] == "permissions" {
+		// "no permissions" 之后是包含空格的说明，直到第一个已知字段为止
+		device.Type = "no permissions"
+		for len(fields) > 0 && !isLongDeviceField(fields[0]) {
+			fields = fields[1:]
+		}
+	}
+
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "usb":
+			device.Path = field
+		case "product":
+			device.Product = value
+		case "model":
+			device.Model = value
+		case "device":
+			device.DeviceName = value
+		case "transport_id":
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return Device{}, fmt.Errorf("无效的传输ID: %s", line)
+			}
+			device.TransportID = id
+		default:
+			if device.Extras == nil {
+				device.Extras = make(map[string]string)
+			}
+			device.Extras[key] = value
+		}
+	}
+	return device, nil
+}
+
+// isLongDeviceField 检查是否是devices -l中有专门字段的键值对
+func isLongDeviceField(field string) bool {
+	for _, prefix := range longDeviceKeys {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+	return false
+}
 func (c *VersionCommand) Execute() (interface{}, error) {
 	if err := c.sender("host:version"); err != nil {
-		return nil, fmt.Errorf("发送版本查询命令失败: %v", err)
+		return nil, fmt.Errorf("发送版本查询命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
 	case OKAY:
 		value, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取版本值失败: %v", err)
+			return nil, fmt.Errorf("读取版本值失败: %w", err)
 		}
 		return c.parseVersion(value)
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 	default:
 		// 某些版本的ADB直接返回版本号
 		return c.parseVersion(reply)
@@ -352,14 +506,18 @@ func (c *VersionCommand) Execute() (interface{}, error) {
 
 // Execute 执行传输命令
 func (c *TransportCommand) Execute(serial string) (interface{}, error) {
-	cmd := fmt.Sprintf("host:transport:%s", serial)
-	if err := c.sender(cmd); err != nil {
-		return nil, fmt.Errorf("发送传输命令失败: %v", err)
+	return c.ExecuteSelector(SelectSerial(serial))
+}
+
+// ExecuteSelector 按选择方式切换到设备传输
+func (c *TransportCommand) ExecuteSelector(selector Selector) (interface{}, error) {
+	if err := c.sender(selector.TransportService()); err != nil {
+		return nil, fmt.Errorf("发送传输命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -368,23 +526,64 @@ func (c *TransportCommand) Execute(serial string) (interface{}, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return false, fmt.Errorf(errMsg)
+		return false, adberr.NewFailError(errMsg)
 	default:
 		return false, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
 }
 
+// Execute 执行tport命令，切换到设备传输
+// 成功时服务器在OKAY之后返回8字节小端序的传输ID
+func (c *TportCommand) Execute(selector Selector) (uint64, error) {
+	cmd, err := selector.TportService()
+	if err != nil {
+		return 0, err
+	}
+	if err := c.sender(cmd); err != nil {
+		return 0, fmt.Errorf("发送传输命令失败: %w", err)
+	}
+
+	reply, err := c.reader(4)
+	if err != nil {
+		return 0, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	switch reply {
+	case OKAY:
+		value, err := c.reader(8)
+		if err != nil {
+			return 0, fmt.Errorf("读取传输ID失败: %w", err)
+		}
+		if len(value) != 8 {
+			return 0, fmt.Errorf("无效的传输ID长度: %d", len(value))
+		}
+		return binary.LittleEndian.Uint64([]byte(value)), nil
+	case FAIL:
+		errMsg, err := c.reader(0)
+		if err != nil {
+			return 0, fmt.Errorf("读取错误信息失败: %w", err)
+		}
+		return 0, adberr.NewFailError(errMsg)
+	default:
+		return 0, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
+	}
+}
+
 // Execute 执行设备跟踪命令
 func (c *TrackDevicesCommand) Execute() (interface{}, error) {
-	if err := c.sender("host:track-devices"); err != nil {
-		return nil, fmt.Errorf("发送跟踪命令失败: %v", err)
+	service := "host:track-devices"
+	if c.long {
+		service = "host:track-devices-l"
+	}
+	if err := c.sender(service); err != nil {
+		return nil, fmt.Errorf("发送跟踪命令失败: %w", err)
 	}
 
 	reply, err := c.reader(4)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	switch reply {
@@ -397,9 +596,9 @@ func (c *TrackDevicesCommand) Execute() (interface{}, error) {
 	case FAIL:
 		errMsg, err := c.reader(0)
 		if err != nil {
-			return nil, fmt.Errorf("读取错误信息失败: %v", err)
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
 		}
-		return nil, fmt.Errorf(errMsg)
+		return nil, adberr.NewFailError(errMsg)
 	default:
 		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
 	}
@@ -423,7 +622,7 @@ func (t *deviceTracker) Start() error {
 			case <-t.done:
 				return
 			default:
-				if devices, err := t.cmd.readDevices(); err == nil && t.cmd.onTrack != nil {
+				if devices, err := t.cmd.readTracked(); err == nil && t.cmd.onTrack != nil {
 					t.cmd.onTrack(devices)
 				}
 			}
@@ -0,0 +1,64 @@
+package host
+
+import (
+	"fmt"
+	"strings"
+
+	"adb-kit-go/pkg/adb/adberr"
+)
+
+// HostFeaturesCommand 实现查询ADB服务器支持的特性的命令
+type HostFeaturesCommand struct {
+	BaseCommand
+}
+
+// NewHostFeaturesCommand 创建新的服务器特性查询命令实例
+func NewHostFeaturesCommand(sender func(string) error, reader func(int) (string, error)) *HostFeaturesCommand {
+	return &HostFeaturesCommand{
+		BaseCommand: BaseCommand{
+			sender: sender,
+			reader: reader,
+		},
+	}
+}
+
+// Execute 通过host:host-features查询服务器支持的特性
+// 不支持该服务的旧服务器返回的错误满足 errors.Is(err, adberr.ErrUnknownService)
+func (c *HostFeaturesCommand) Execute() ([]string, error) {
+	if err := c.sender("host:host-features"); err != nil {
+		return nil, fmt.Errorf("发送特性查询命令失败: %w", err)
+	}
+
+	reply, err := c.reader(4)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	switch reply {
+	case OKAY:
+		value, err := c.reader(0)
+		if err != nil {
+			return nil, fmt.Errorf("读取特性列表失败: %w", err)
+		}
+		return ParseFeatures(value), nil
+	case FAIL:
+		errMsg, err := c.reader(0)
+		if err != nil {
+			return nil, fmt.Errorf("读取错误信息失败: %w", err)
+		}
+		return nil, adberr.NewFailError(errMsg)
+	default:
+		return nil, fmt.Errorf("unexpected response: %s, expected OKAY or FAIL", reply)
+	}
+}
+
+// ParseFeatures 解析逗号分隔的特性列表，忽略空项
+func ParseFeatures(value string) []string {
+	var features []string
+	for _, feature := range strings.Split(value, ",") {
+		if feature = strings.TrimSpace(feature); feature != "" {
+			features = append(features, feature)
+		}
+	}
+	return features
+}
@@ -0,0 +1,119 @@
+package host
+
+import (
+	"fmt"
+)
+
+// Selector 描述如何从ADB服务器选择设备传输
+// 零值表示选择唯一连接的设备
+type Selector struct {
+	serial      string
+	transportID uint64
+	kind        string // "serial"、"transport-id"、"usb"、"local" 或 "any"
+}
+
+// SelectSerial 按序列号选择设备
+func SelectSerial(serial string) Selector {
+	return Selector{kind: "serial", serial: serial}
+}
+
+// SelectTransportID 按传输ID选择设备，序列号重复或为空的设备只能通过传输ID区分
+func SelectTransportID(id uint64) Selector {
+	return Selector{kind: "transport-id", transportID: id}
+}
+
+// SelectUsb 选择唯一的USB设备，相当于adb -d
+func SelectUsb() Selector {
+	return Selector{kind: "usb"}
+}
+
+// SelectLocal 选择唯一的TCP/IP设备或模拟器，相当于adb -e
+func SelectLocal() Selector {
+	return Selector{kind: "local"}
+}
+
+// SelectAny 选择唯一连接的设备
+func SelectAny() Selector {
+	return Selector{kind: "any"}
+}
+
+// Serial 返回按序列号选择时的序列号
+func (s Selector) Serial() string {
+	return s.serial
+}
+
+// TransportID 返回按传输ID选择时的传输ID
+func (s Selector) TransportID() uint64 {
+	return s.transportID
+}
+
+// Kind 返回选择方式
+func (s Selector) Kind() string {
+	if s.kind == "" {
+		return "any"
+	}
+	return s.kind
+}
+
+// TransportService 返回切换到设备传输的服务名
+func (s Selector) TransportService() string {
+	switch s.Kind() {
+	case "serial":
+		return fmt.Sprintf("host:transport:%s", s.serial)
+	case "transport-id":
+		return fmt.Sprintf("host:transport-id:%d", s.transportID)
+	default:
+		return fmt.Sprintf("host:transport-%s", s.Kind())
+	}
+}
+
+// TportService 返回切换到设备传输并返回传输ID的服务名
+// 按传输ID选择时传输ID已知，不支持tport
+func (s Selector) TportService() (string, error) {
+	switch s.Kind() {
+	case "serial":
+		return fmt.Sprintf("host:tport:serial:%s", s.serial), nil
+	case "transport-id":
+		return "", fmt.Errorf("tport不支持按传输ID选择设备")
+	default:
+		return fmt.Sprintf("host:tport:%s", s.Kind()), nil
+	}
+}
+
+// HostPrefix 返回host-serial类命令的前缀，如 "host-serial:<serial>:"
+func (s Selector) HostPrefix() string {
+	switch s.Kind() {
+	case "serial":
+		return fmt.Sprintf("host-serial:%s:", s.serial)
+	case "transport-id":
+		return fmt.Sprintf("host-transport-id:%d:", s.transportID)
+	case "usb":
+		return "host-usb:"
+	case "local":
+		return "host-local:"
+	default:
+		return "host:"
+	}
+}
+
+// WaitForType 返回wait-for-<type>-<state>命令中的传输类型
+func (s Selector) WaitForType() string {
+	switch s.Kind() {
+	case "usb", "local":
+		return s.Kind()
+	default:
+		return "any"
+	}
+}
+
+// String 返回选择方式的可读描述
+func (s Selector) String() string {
+	switch s.Kind() {
+	case "serial":
+		return s.serial
+	case "transport-id":
+		return fmt.Sprintf("transport-id:%d", s.transportID)
+	default:
+		return s.Kind()
+	}
+}
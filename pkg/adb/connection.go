@@ -1,61 +1,86 @@
 package adb
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net"
 	"os/exec"
 	"sync"
 	"time"
+
+	"adb-kit-go/pkg/adb/session"
 )
 
+// 默认的连接超时时间，仅在上下文没有设置截止时间时生效
+const defaultDialTimeout = 10 * time.Second
+
 // Connection ADB连接
 type Connection struct {
 	options       *Options
 	socket        net.Conn
 	parser        *Parser
+	protocol      *Protocol
 	mu            sync.Mutex
 	handlers      map[string][]func(interface{})
 	closed        bool
 	triedStarting bool
+	ctxErr        error
+	log           *connLog // 未设置Options.Logger时为空
+	writer        io.Writer
+	recorder      *session.ConnRecorder // 未设置Options.Recorder时为空
+	metrics       *connMetrics          // 未设置Options.Metrics时为空
 }
 
 // NewConnection 创建新的连接
 func NewConnection(options *Options) *Connection {
 	if options == nil {
-		options = &Options{
-			Port: 5037,
-			Bin:  "adb",
-		}
+		options = &Options{Bin: "adb"}
 	}
 
 	return &Connection{
 		options:  options,
+		protocol: NewProtocol(),
 		handlers: make(map[string][]func(interface{})),
+		log:      newConnLog(options),
+		metrics:  newConnMetrics(options),
 	}
 }
 
 // Connect 建立连接
 func (c *Connection) Connect() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	return c.ConnectContext(context.Background())
+}
 
+// ConnectContext 建立连接，上下文结束时取消拨号
+func (c *Connection) ConnectContext(ctx context.Context) error {
+	c.mu.Lock()
 	if c.socket != nil {
+		c.mu.Unlock()
 		return fmt.Errorf("connection already established")
 	}
+	c.mu.Unlock()
 
-	// 连接到ADB服务器
-	addr := fmt.Sprintf("127.0.0.1:%d", c.options.Port)
-	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	addr, err := c.options.ServerAddress()
 	if err != nil {
-		// 如果连接失败，尝试启动ADB服务器
-		if !c.triedStarting {
-			c.triedStarting = true
-			if err := c.startServer(); err != nil {
-				return fmt.Errorf("failed to start ADB server: %v", err)
-			}
-			return c.Connect()
+		return err
+	}
+
+	conn, err := c.dial(ctx, addr)
+	if err != nil && ctx.Err() == nil && !c.triedStarting && addr.IsLocal() {
+		// 如果连接本机服务器失败，尝试启动ADB服务器
+		// 远程服务器由其所在主机负责启动
+		c.triedStarting = true
+		if err := c.startServer(ctx, addr); err != nil {
+			return fmt.Errorf("%w: failed to start ADB server: %w", ErrServerUnreachable, err)
 		}
-		return fmt.Errorf("failed to connect to ADB server: %v", err)
+		conn, err = c.dial(ctx, addr)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w at %s: %w", ErrServerUnreachable, addr, err)
 	}
 
 	// 设置TCP选项
@@ -63,42 +88,197 @@ func (c *Connection) Connect() error {
 		tcpConn.SetNoDelay(true)
 	}
 
+	var stream io.ReadCloser = conn
+	if c.log != nil {
+		stream = c.log.wrap(conn)
+	}
+	var writer io.Writer = conn
+	if c.options.Recorder != nil {
+		stream, writer = c.record(stream, conn)
+	}
+
+	c.mu.Lock()
 	c.socket = conn
-	c.parser = NewParser(conn)
+	c.writer = writer
+	c.parser = NewParser(stream)
+	c.mu.Unlock()
 
 	// 触发连接事件
 	c.emit("connect", nil)
 
-	// 启动读取循环
-	go c.readLoop()
-
 	return nil
 }
 
+// dial 拨号连接ADB服务器
+func (c *Connection) dial(ctx context.Context, addr *ServerAddress) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	if _, ok := ctx.Deadline(); !ok {
+		dialer.Timeout = defaultDialTimeout
+	}
+
+	return dialer.DialContext(ctx, addr.Network, addr.Address)
+}
+
+// Watch 在上下文结束时关闭连接，以中断正在进行的读写
+// 返回的函数用于停止监听，应在操作完成后调用
+func (c *Connection) Watch(ctx context.Context) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.ctxErr = ctx.Err()
+			c.mu.Unlock()
+			c.Close()
+		case <-stop:
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(stop) })
+	}
+}
+
+// Err 返回导致连接被关闭的上下文错误
+func (c *Connection) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ctxErr
+}
+
 // Write 写入数据
 func (c *Connection) Write(data []byte) (int, error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	socket, writer := c.socket, c.writer
+	c.mu.Unlock()
 
-	if c.socket == nil {
+	if socket == nil {
+		if err := c.Err(); err != nil {
+			return 0, err
+		}
 		return 0, fmt.Errorf("connection not established")
 	}
 
-	return c.socket.Write(data)
+	n, err := writer.Write(data)
+	if c.log != nil {
+		c.log.write(data[:n])
+	}
+	if err != nil {
+		if ctxErr := c.Err(); ctxErr != nil {
+			return n, ctxErr
+		}
+	}
+	return n, err
+}
+
+// Send 发送带长度前缀的命令
+func (c *Connection) Send(cmd string) error {
+	if c.log != nil {
+		c.log.send(cmd)
+	}
+	if c.metrics != nil {
+		c.metrics.send(cmd)
+	}
+	_, err := c.Write(c.protocol.EncodeData([]byte(cmd)))
+	return err
+}
+
+// ReadString 从连接读取数据
+// length > 0 时读取指定长度（流结束时返回剩余数据），
+// length == 0 时读取带4字节长度前缀的值，
+// length < 0 时读取直到流结束
+func (c *Connection) ReadString(length int) (string, error) {
+	c.mu.Lock()
+	parser := c.parser
+	c.mu.Unlock()
+
+	if parser == nil {
+		return "", fmt.Errorf("connection not established")
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case length == 0:
+		data, err = parser.ReadValue()
+		if c.metrics != nil {
+			c.metrics.reason(string(data), err)
+		}
+	case length < 0:
+		data, err = parser.ReadAll()
+	default:
+		buffer := make([]byte, length)
+		n, readErr := io.ReadFull(parser.Raw(), buffer)
+		if readErr == io.ErrUnexpectedEOF {
+			readErr = nil
+		}
+		data, err = buffer[:n], readErr
+		if length == 4 && c.log != nil {
+			c.log.reply(string(data))
+		}
+		if length == 4 && c.metrics != nil {
+			c.metrics.reply(string(data), err)
+		}
+	}
+
+	if err != nil {
+		if ctxErr := c.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+	}
+	return string(data), err
+}
+
+// Read 实现io.Reader接口，直接读取连接上的原始数据
+// 用于命令完成握手后的长时间运行的数据流
+func (c *Connection) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	parser := c.parser
+	c.mu.Unlock()
+
+	if parser == nil {
+		return 0, fmt.Errorf("connection not established")
+	}
+
+	n, err := parser.Raw().Read(p)
+	if err != nil && err != io.EOF {
+		if ctxErr := c.Err(); ctxErr != nil {
+			return n, ctxErr
+		}
+	}
+	return n, err
 }
 
 // Close 关闭连接
 func (c *Connection) Close() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if c.socket == nil || c.closed {
+		c.mu.Unlock()
 		return nil
 	}
 
 	c.closed = true
 	err := c.socket.Close()
 	c.socket = nil
+	ctxErr := c.ctxErr
+	c.mu.Unlock()
+
+	if c.log != nil {
+		c.log.close()
+	}
+	if c.recorder != nil {
+		c.recorder.Close()
+	}
+	if c.metrics != nil {
+		c.metrics.close(ctxErr)
+	}
 
 	// 触发关闭事件
 	c.emit("close", nil)
@@ -107,8 +287,12 @@ func (c *Connection) Close() error {
 }
 
 // startServer 启动ADB服务器
-func (c *Connection) startServer() error {
-	cmd := exec.Command(c.options.Bin, "start-server")
+func (c *Connection) startServer(ctx context.Context, addr *ServerAddress) error {
+	args := append(addr.ServerArgs(), "start-server")
+	if c.log != nil {
+		c.log.current().Info("starting adb server", "address", addr.String(), "bin", c.options.Bin)
+	}
+	cmd := exec.CommandContext(ctx, c.options.Bin, args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("adb start-server failed: %v, output: %s", err, output)
@@ -116,43 +300,6 @@ func (c *Connection) startServer() error {
 	return nil
 }
 
-// readLoop 读取循环
-func (c *Connection) readLoop() {
-	buffer := make([]byte, 4096)
-	for {
-		c.mu.Lock()
-		if c.closed || c.socket == nil {
-			c.mu.Unlock()
-			return
-		}
-		socket := c.socket
-		c.mu.Unlock()
-
-		n, err := socket.Read(buffer)
-		if err != nil {
-			if !c.closed {
-				c.handleError(err)
-			}
-			return
-		}
-
-		if n > 0 {
-			c.handleData(buffer[:n])
-		}
-	}
-}
-
-// handleData 处理接收到的数据
-func (c *Connection) handleData(data []byte) {
-	c.emit("data", data)
-}
-
-// handleError 处理错误
-func (c *Connection) handleError(err error) {
-	c.emit("error", err)
-	c.Close()
-}
-
 // On 注册事件处理器
 func (c *Connection) On(event string, handler func(interface{})) {
 	c.mu.Lock()
@@ -192,6 +339,20 @@ func (c *Connection) emit(event string, data interface{}) {
 	}
 }
 
+// logWith 为连接之后的日志添加属性
+func (c *Connection) logWith(args ...any) {
+	if c.log != nil {
+		c.log.with(args...)
+	}
+}
+
+// markTransport 记录连接已切换为设备传输，用于统计打开的传输数
+func (c *Connection) markTransport() {
+	if c.metrics != nil {
+		c.metrics.openTransport()
+	}
+}
+
 // GetParser 获取解析器
 func (c *Connection) GetParser() *Parser {
 	return c.parser
@@ -0,0 +1,783 @@
+package adb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"adb-kit-go/pkg/adb/command/host"
+	hostserial "adb-kit-go/pkg/adb/command/host-serial"
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+	adbsync "adb-kit-go/pkg/adb/sync"
+	"adb-kit-go/pkg/adb/tcpusb"
+)
+
+// AndroidSerialEnv 与官方adb一致的默认设备序列号环境变量
+const AndroidSerialEnv = "ANDROID_SERIAL"
+
+// DeviceClient 绑定到单个设备的客户端，方法与Client相同但不需要传入序列号
+// 首次建立传输时解析设备并缓存其传输ID和序列号，之后通过传输ID访问设备；
+// 设备断开后以新的传输ID重新连接时按序列号重新解析，不会绑定到其他设备
+type DeviceClient struct {
+	client      *Client // 传输固定到本设备的客户端视图
+	selector    host.Selector
+	mu          sync.Mutex
+	serial      string
+	transportID uint64
+	noTport     bool // 服务器不支持host:tport，无法缓存传输ID
+}
+
+// Device 返回绑定到指定设备的客户端
+// serial为空时使用ANDROID_SERIAL环境变量，仍为空则选择唯一连接的设备
+func (c *Client) Device(serial string) *DeviceClient {
+	if serial == "" {
+		serial = os.Getenv(AndroidSerialEnv)
+	}
+	if serial == "" {
+		return c.Select(host.SelectAny())
+	}
+	return c.Select(host.SelectSerial(serial))
+}
+
+// AnyDevice 返回绑定到唯一连接的设备的客户端，有多个设备时操作会失败
+func (c *Client) AnyDevice() *DeviceClient {
+	return c.Select(host.SelectAny())
+}
+
+// UsbDevice 返回绑定到唯一USB设备的客户端，相当于adb -d
+func (c *Client) UsbDevice() *DeviceClient {
+	return c.Select(host.SelectUsb())
+}
+
+// EmulatorDevice 返回绑定到唯一TCP/IP设备或模拟器的客户端，相当于adb -e
+func (c *Client) EmulatorDevice() *DeviceClient {
+	return c.Select(host.SelectLocal())
+}
+
+// DeviceByTransportID 返回绑定到指定传输ID的客户端
+// 传输ID在设备断开后失效，此后的操作会失败而不会重新解析
+func (c *Client) DeviceByTransportID(id uint64) *DeviceClient {
+	return c.Select(host.SelectTransportID(id))
+}
+
+// Select 返回按选择方式绑定到设备的客户端
+func (c *Client) Select(selector host.Selector) *DeviceClient {
+	d := &DeviceClient{
+		selector:    selector,
+		serial:      selector.Serial(),
+		transportID: selector.TransportID(),
+	}
+	d.client = &Client{
+		options:  c.options,
+		device:   d,
+		devices:  c.devices,
+		slots:    c.slots,
+		props:    c.props,
+		features: c.features,
+	}
+	return d
+}
+
+// Serial 返回设备序列号，未指定序列号的设备在解析之前返回空字符串
+func (d *DeviceClient) Serial() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.serial
+}
+
+// TransportID 返回缓存的传输ID，尚未解析时返回0
+func (d *DeviceClient) TransportID() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.transportID
+}
+
+// Resolve 解析设备并缓存其序列号和传输ID
+// 对于AnyDevice、UsbDevice和EmulatorDevice，解析后客户端固定到该设备
+func (d *DeviceClient) Resolve(ctx context.Context) error {
+	_, err := d.resolveSerial(ctx)
+	return err
+}
+
+// Client 返回绑定到本设备的客户端视图，其所有传输都指向本设备，忽略传入的序列号
+func (d *DeviceClient) Client() *Client {
+	return d.client
+}
+
+// current 返回当前用于查找设备的选择方式，已知序列号时按序列号查找
+func (d *DeviceClient) current() host.Selector {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.serial != "" {
+		return host.SelectSerial(d.serial)
+	}
+	return d.selector
+}
+
+// transport 建立到本设备的传输
+func (d *DeviceClient) transport(ctx context.Context) (*Transport, error) {
+	d.mu.Lock()
+	id, noTport := d.transportID, d.noTport
+	d.mu.Unlock()
+
+	if id != 0 {
+		transport, err := d.client.TransportSelectorContext(ctx, host.SelectTransportID(id))
+		if err == nil || ctx.Err() != nil || d.selector.Kind() == "transport-id" {
+			return transport, err
+		}
+
+		// 传输ID已失效，设备可能已断开或以新的传输ID重新连接
+		d.mu.Lock()
+		if d.transportID == id {
+			d.transportID = 0
+		}
+		d.mu.Unlock()
+	}
+
+	selector := d.current()
+	if !noTport {
+		var newID uint64
+		transport, err := d.client.openTransport(ctx, selector, func(conn *Connection) error {
+			var err error
+			newID, err = host.NewTportCommand(conn.Send, conn.ReadString).Execute(selector)
+			if err == nil {
+				conn.logWith("transport_id", newID)
+			}
+			return err
+		})
+		if err == nil {
+			d.mu.Lock()
+			d.transportID = newID
+			d.mu.Unlock()
+			// 传输ID失效后按序列号重新找到同一台设备，而不是按原来的选择方式选中其他设备
+			if err := d.bindSerial(ctx, host.SelectTransportID(newID)); err != nil {
+				transport.Close()
+				return nil, err
+			}
+			return transport, nil
+		}
+		if ctx.Err() != nil || !errors.Is(err, ErrUnknownService) {
+			return nil, err
+		}
+
+		// 旧版本的服务器不支持host:tport
+		d.mu.Lock()
+		d.noTport = true
+		d.mu.Unlock()
+	}
+
+	// 无法缓存传输ID时先查询并固定序列号，之后始终按序列号选择设备
+	if err := d.bindSerial(ctx, selector); err != nil {
+		return nil, err
+	}
+	return d.client.TransportSelectorContext(ctx, d.current())
+}
+
+// bindSerial 对于Any、Usb和Local选择方式，通过get-serialno查询设备的序列号并缓存
+// 已知序列号或按传输ID选择时不做任何事
+func (d *DeviceClient) bindSerial(ctx context.Context, selector host.Selector) error {
+	if d.Serial() != "" || d.selector.Kind() == "serial" || d.selector.Kind() == "transport-id" {
+		return nil
+	}
+
+	var serial string
+	err := d.client.withConnection(ctx, func(conn *Connection) error {
+		var err error
+		serial, err = hostserial.NewGetSerialNoCommand(conn.Send, conn.ReadString).ExecuteSelector(selector)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	if d.serial == "" {
+		d.serial = serial
+	}
+	d.mu.Unlock()
+	return nil
+}
+
+// hostSelector 返回host-serial命令使用的选择方式，优先使用传输ID
+func (d *DeviceClient) hostSelector(ctx context.Context) (host.Selector, error) {
+	if id := d.TransportID(); id != 0 {
+		return host.SelectTransportID(id), nil
+	}
+
+	selector := d.current()
+	if selector.Kind() != "serial" && !d.tportDisabled() {
+		// 先建立一次传输以固定设备
+		transport, err := d.transport(ctx)
+		if err != nil {
+			return selector, err
+		}
+		transport.Close()
+
+		if id := d.TransportID(); id != 0 {
+			return host.SelectTransportID(id), nil
+		}
+	}
+	if err := d.bindSerial(ctx, selector); err != nil {
+		return selector, err
+	}
+	return d.current(), nil
+}
+
+// tportDisabled 检查服务器是否不支持host:tport
+func (d *DeviceClient) tportDisabled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.noTport
+}
+
+// resolveSerial 返回设备序列号，未知时先固定设备再通过传输ID查询
+func (d *DeviceClient) resolveSerial(ctx context.Context) (string, error) {
+	if serial := d.Serial(); serial != "" {
+		return serial, nil
+	}
+
+	selector, err := d.hostSelector(ctx)
+	if err != nil {
+		return "", err
+	}
+	if serial := d.Serial(); serial != "" {
+		return serial, nil
+	}
+
+	var serial string
+	err = d.client.withConnection(ctx, func(conn *Connection) error {
+		var err error
+		serial, err = hostserial.NewGetSerialNoCommand(conn.Send, conn.ReadString).ExecuteSelector(selector)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// 之后即使传输ID变化，也按序列号重新找到同一台设备
+	d.mu.Lock()
+	if d.serial == "" {
+		d.serial = serial
+	}
+	d.mu.Unlock()
+
+	return serial, nil
+}
+
+// Transport 创建设备传输
+func (d *DeviceClient) Transport() (*Transport, error) {
+	return d.client.Transport(d.Serial())
+}
+
+// TransportContext 创建设备传输，上下文结束时取消建立过程
+func (d *DeviceClient) TransportContext(ctx context.Context) (*Transport, error) {
+	return d.client.TransportContext(ctx, d.Serial())
+}
+
+// Shell 执行Shell命令
+func (d *DeviceClient) Shell(command string) (*ShellResponse, error) {
+	return d.client.Shell(d.Serial(), command)
+}
+
+// ShellContext 执行Shell命令，上下文结束时关闭传输
+func (d *DeviceClient) ShellContext(ctx context.Context, command string) (*ShellResponse, error) {
+	return d.client.ShellContext(ctx, d.Serial(), command)
+}
+
+// RunShell 执行Shell命令并等待结束，设备支持shell_v2时分别返回stdout、stderr和退出码
+func (d *DeviceClient) RunShell(command string) (*ShellResult, error) {
+	return d.client.RunShell(d.Serial(), command)
+}
+
+// RunShellContext 执行Shell命令并等待结束，上下文结束时关闭传输
+func (d *DeviceClient) RunShellContext(ctx context.Context, command string) (*ShellResult, error) {
+	return d.client.RunShellContext(ctx, d.Serial(), command)
+}
+
+// OpenShell 执行Shell命令并返回其输入输出流
+func (d *DeviceClient) OpenShell(command string) (*ShellStream, error) {
+	return d.client.OpenShell(d.Serial(), command)
+}
+
+// OpenShellContext 执行Shell命令并返回其输入输出流，上下文结束或关闭流时断开传输
+func (d *DeviceClient) OpenShellContext(ctx context.Context, command string) (*ShellStream, error) {
+	return d.client.OpenShellContext(ctx, d.Serial(), command)
+}
+
+// OpenPtyShell 在设备的PTY中执行命令，command为空时启动交互式shell
+func (d *DeviceClient) OpenPtyShell(command string, options *PtyOptions) (*ShellStream, error) {
+	return d.client.OpenPtyShell(d.Serial(), command, options)
+}
+
+// OpenPtyShellContext 在设备的PTY中执行命令，上下文结束或关闭流时断开传输
+func (d *DeviceClient) OpenPtyShellContext(ctx context.Context, command string, options *PtyOptions) (*ShellStream, error) {
+	return d.client.OpenPtyShellContext(ctx, d.Serial(), command, options)
+}
+
+// Install 安装APK
+func (d *DeviceClient) Install(apkPath string) error {
+	return d.client.Install(d.Serial(), apkPath)
+}
+
+// InstallContext 推送本地APK到临时目录并安装
+func (d *DeviceClient) InstallContext(ctx context.Context, apkPath string) error {
+	return d.client.InstallContext(ctx, d.Serial(), apkPath)
+}
+
+// Uninstall 卸载应用
+func (d *DeviceClient) Uninstall(packageName string) error {
+	return d.client.Uninstall(d.Serial(), packageName)
+}
+
+// UninstallContext 卸载应用
+func (d *DeviceClient) UninstallContext(ctx context.Context, packageName string) error {
+	return d.client.UninstallContext(ctx, d.Serial(), packageName)
+}
+
+// SyncService 打开设备的同步服务
+func (d *DeviceClient) SyncService() (*Sync, error) {
+	return d.client.SyncService(d.Serial())
+}
+
+// SyncServiceContext 打开设备的同步服务，上下文结束时取消建立过程
+func (d *DeviceClient) SyncServiceContext(ctx context.Context) (*Sync, error) {
+	return d.client.SyncServiceContext(ctx, d.Serial())
+}
+
+// Push 推送文件到设备
+func (d *DeviceClient) Push(local string, remote string) error {
+	return d.client.Push(d.Serial(), local, remote)
+}
+
+// PushContext 推送文件到设备，上下文结束时中止传输
+func (d *DeviceClient) PushContext(ctx context.Context, local string, remote string) error {
+	return d.client.PushContext(ctx, d.Serial(), local, remote)
+}
+
+// Pull 从设备拉取文件
+func (d *DeviceClient) Pull(remote string, local string) error {
+	return d.client.Pull(d.Serial(), remote, local)
+}
+
+// PullContext 从设备拉取文件，上下文结束时中止传输
+func (d *DeviceClient) PullContext(ctx context.Context, remote string, local string) error {
+	return d.client.PullContext(ctx, d.Serial(), remote, local)
+}
+
+// Stat 获取设备上文件的状态
+func (d *DeviceClient) Stat(path string) (*adbsync.Stats, error) {
+	return d.client.Stat(d.Serial(), path)
+}
+
+// StatContext 获取设备上文件的状态
+func (d *DeviceClient) StatContext(ctx context.Context, path string) (*adbsync.Stats, error) {
+	return d.client.StatContext(ctx, d.Serial(), path)
+}
+
+// ReadDir 列出设备上目录中的条目
+func (d *DeviceClient) ReadDir(path string) ([]*adbsync.Entry, error) {
+	return d.client.ReadDir(d.Serial(), path)
+}
+
+// ReadDirContext 列出设备上目录中的条目
+func (d *DeviceClient) ReadDirContext(ctx context.Context, path string) ([]*adbsync.Entry, error) {
+	return d.client.ReadDirContext(ctx, d.Serial(), path)
+}
+
+// FS 返回设备的文件系统
+func (d *DeviceClient) FS() *DeviceFS {
+	return d.client.FS(d.Serial())
+}
+
+// FSContext 返回设备的文件系统，所有操作使用ctx
+func (d *DeviceClient) FSContext(ctx context.Context) *DeviceFS {
+	return d.client.FSContext(ctx, d.Serial())
+}
+
+// Forward 端口转发
+func (d *DeviceClient) Forward(local string, remote string) error {
+	return d.client.Forward(d.Serial(), local, remote)
+}
+
+// ForwardContext 端口转发
+func (d *DeviceClient) ForwardContext(ctx context.Context, local string, remote string) error {
+	return d.client.ForwardContext(ctx, d.Serial(), local, remote)
+}
+
+// OpenLogcat 打开logcat日志流
+func (d *DeviceClient) OpenLogcat(options *hosttransport.LogcatOptions) (io.ReadCloser, error) {
+	return d.client.OpenLogcat(d.Serial(), options)
+}
+
+// OpenLogcatContext 打开logcat日志流，上下文结束或关闭流时断开传输
+func (d *DeviceClient) OpenLogcatContext(ctx context.Context, options *hosttransport.LogcatOptions) (io.ReadCloser, error) {
+	return d.client.OpenLogcatContext(ctx, d.Serial(), options)
+}
+
+// OpenMonkey 在设备上启动monkey并返回其输出流
+func (d *DeviceClient) OpenMonkey(port int) (io.ReadCloser, error) {
+	return d.client.OpenMonkey(d.Serial(), port)
+}
+
+// OpenMonkeyContext 在设备上启动monkey，上下文结束或关闭流时断开传输
+func (d *DeviceClient) OpenMonkeyContext(ctx context.Context, port int) (io.ReadCloser, error) {
+	return d.client.OpenMonkeyContext(ctx, d.Serial(), port)
+}
+
+// TrackJdwp 跟踪设备上可调试的进程
+func (d *DeviceClient) TrackJdwp() (*hosttransport.JdwpTracker, error) {
+	return d.client.TrackJdwp(d.Serial())
+}
+
+// TrackJdwpContext 跟踪设备上可调试的进程，上下文结束时停止跟踪
+func (d *DeviceClient) TrackJdwpContext(ctx context.Context) (*hosttransport.JdwpTracker, error) {
+	return d.client.TrackJdwpContext(ctx, d.Serial())
+}
+
+// CreateTcpUsbBridge 创建TCP/USB桥接
+func (d *DeviceClient) CreateTcpUsbBridge(options map[string]interface{}) (*tcpusb.Server, error) {
+	return d.client.CreateTcpUsbBridge(d.Serial(), options)
+}
+
+// Screencap 截取设备屏幕，返回PNG图像数据流
+func (d *DeviceClient) Screencap() (io.ReadCloser, error) {
+	return d.client.Screencap(d.Serial())
+}
+
+// ScreencapContext 截取设备屏幕，上下文结束或关闭流时断开传输
+func (d *DeviceClient) ScreencapContext(ctx context.Context) (io.ReadCloser, error) {
+	return d.client.ScreencapContext(ctx, d.Serial())
+}
+
+// OpenShellSession 打开shell会话
+func (d *DeviceClient) OpenShellSession() (*ShellSession, error) {
+	return d.client.OpenShellSession(d.Serial())
+}
+
+// OpenShellSessionContext 打开shell会话，上下文只用于建立过程
+func (d *DeviceClient) OpenShellSessionContext(ctx context.Context) (*ShellSession, error) {
+	return d.client.OpenShellSessionContext(ctx, d.Serial())
+}
+
+// Exec 执行命令并返回原始的二进制输出流
+func (d *DeviceClient) Exec(command string) (io.ReadCloser, error) {
+	return d.client.Exec(d.Serial(), command)
+}
+
+// ExecContext 执行命令并返回原始的二进制输出流
+func (d *DeviceClient) ExecContext(ctx context.Context, command string) (io.ReadCloser, error) {
+	return d.client.ExecContext(ctx, d.Serial(), command)
+}
+
+// OpenExec 通过exec:执行命令并返回双向的原始数据流
+func (d *DeviceClient) OpenExec(command string) (io.ReadWriteCloser, error) {
+	return d.client.OpenExec(d.Serial(), command)
+}
+
+// OpenExecContext 通过exec:执行命令并返回双向的原始数据流
+func (d *DeviceClient) OpenExecContext(ctx context.Context, command string) (io.ReadWriteCloser, error) {
+	return d.client.OpenExecContext(ctx, d.Serial(), command)
+}
+
+// ExecIn 通过exec:执行命令并将stdin的数据写入命令的标准输入
+func (d *DeviceClient) ExecIn(command string, stdin io.Reader) error {
+	return d.client.ExecIn(d.Serial(), command, stdin)
+}
+
+// ExecInContext 通过exec:执行命令并将stdin的数据写入命令的标准输入
+func (d *DeviceClient) ExecInContext(ctx context.Context, command string, stdin io.Reader) error {
+	return d.client.ExecInContext(ctx, d.Serial(), command, stdin)
+}
+
+// Cat 通过cat读取设备上的文件
+func (d *DeviceClient) Cat(path string) (io.ReadCloser, error) {
+	return d.client.Cat(d.Serial(), path)
+}
+
+// CatContext 通过cat读取设备上的文件
+func (d *DeviceClient) CatContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	return d.client.CatContext(ctx, d.Serial(), path)
+}
+
+// Tar 将设备上的目录打包为tar数据流
+func (d *DeviceClient) Tar(dir string) (io.ReadCloser, error) {
+	return d.client.Tar(d.Serial(), dir)
+}
+
+// TarContext 将设备上的目录打包为tar数据流
+func (d *DeviceClient) TarContext(ctx context.Context, dir string) (io.ReadCloser, error) {
+	return d.client.TarContext(ctx, d.Serial(), dir)
+}
+
+// FrameBuffer 读取设备帧缓冲区，format为"raw"时返回原始像素数据，否则通过gm转换为指定格式
+func (d *DeviceClient) FrameBuffer(format string) (io.ReadCloser, *hosttransport.FrameBufferMeta, error) {
+	return d.client.FrameBuffer(d.Serial(), format)
+}
+
+// FrameBufferContext 读取设备帧缓冲区，上下文结束或关闭流时断开传输
+func (d *DeviceClient) FrameBufferContext(ctx context.Context, format string) (io.ReadCloser, *hosttransport.FrameBufferMeta, error) {
+	return d.client.FrameBufferContext(ctx, d.Serial(), format)
+}
+
+// Reboot 重启设备
+func (d *DeviceClient) Reboot() error {
+	return d.client.Reboot(d.Serial())
+}
+
+// RebootContext 重启设备
+func (d *DeviceClient) RebootContext(ctx context.Context) error {
+	return d.client.RebootContext(ctx, d.Serial())
+}
+
+// RebootInto 重启设备到指定模式
+func (d *DeviceClient) RebootInto(mode hosttransport.RebootMode) error {
+	return d.client.RebootInto(d.Serial(), mode)
+}
+
+// RebootIntoContext 重启设备到指定模式
+func (d *DeviceClient) RebootIntoContext(ctx context.Context, mode hosttransport.RebootMode) error {
+	return d.client.RebootIntoContext(ctx, d.Serial(), mode)
+}
+
+// Remount 以读写模式重新挂载system分区
+func (d *DeviceClient) Remount() error {
+	return d.client.Remount(d.Serial())
+}
+
+// RemountContext 以读写模式重新挂载system分区
+func (d *DeviceClient) RemountContext(ctx context.Context) error {
+	return d.client.RemountContext(ctx, d.Serial())
+}
+
+// Root 以root权限重启设备上的adbd
+func (d *DeviceClient) Root() error {
+	return d.client.Root(d.Serial())
+}
+
+// RootContext 以root权限重启设备上的adbd
+func (d *DeviceClient) RootContext(ctx context.Context) error {
+	return d.client.RootContext(ctx, d.Serial())
+}
+
+// TcpIp 让设备上的adbd在指定TCP端口上监听，返回实际使用的端口
+func (d *DeviceClient) TcpIp(port int) (int, error) {
+	return d.client.TcpIp(d.Serial(), port)
+}
+
+// TcpIpContext 让设备上的adbd在指定TCP端口上监听，返回实际使用的端口
+func (d *DeviceClient) TcpIpContext(ctx context.Context, port int) (int, error) {
+	return d.client.TcpIpContext(ctx, d.Serial(), port)
+}
+
+// Usb 让设备上的adbd切换回USB模式
+func (d *DeviceClient) Usb() error {
+	return d.client.Usb(d.Serial())
+}
+
+// UsbContext 让设备上的adbd切换回USB模式
+func (d *DeviceClient) UsbContext(ctx context.Context) error {
+	return d.client.UsbContext(ctx, d.Serial())
+}
+
+// StartActivity 启动活动
+func (d *DeviceClient) StartActivity(options *hosttransport.IntentOptions) error {
+	return d.client.StartActivity(d.Serial(), options)
+}
+
+// StartActivityContext 启动活动
+func (d *DeviceClient) StartActivityContext(ctx context.Context, options *hosttransport.IntentOptions) error {
+	return d.client.StartActivityContext(ctx, d.Serial(), options)
+}
+
+// StartService 启动服务，options与StartActivity相同
+func (d *DeviceClient) StartService(options *hosttransport.IntentOptions) error {
+	return d.client.StartService(d.Serial(), options)
+}
+
+// StartServiceContext 启动服务
+func (d *DeviceClient) StartServiceContext(ctx context.Context, options *hosttransport.IntentOptions) error {
+	return d.client.StartServiceContext(ctx, d.Serial(), options)
+}
+
+// GetProperties 获取设备的系统属性
+func (d *DeviceClient) GetProperties() (map[string]string, error) {
+	return d.client.GetProperties(d.Serial())
+}
+
+// GetPropertiesContext 获取设备的系统属性
+func (d *DeviceClient) GetPropertiesContext(ctx context.Context) (map[string]string, error) {
+	return d.client.GetPropertiesContext(ctx, d.Serial())
+}
+
+// GetFeatures 获取设备的系统特性（pm list features）
+func (d *DeviceClient) GetFeatures() (hosttransport.SystemFeatures, error) {
+	return d.client.GetFeatures(d.Serial())
+}
+
+// GetFeaturesContext 获取设备支持的特性
+func (d *DeviceClient) GetFeaturesContext(ctx context.Context) (hosttransport.SystemFeatures, error) {
+	return d.client.GetFeaturesContext(ctx, d.Serial())
+}
+
+// GetPackages 获取设备上已安装的包名列表
+func (d *DeviceClient) GetPackages() ([]string, error) {
+	return d.client.GetPackages(d.Serial())
+}
+
+// GetPackagesContext 获取设备上已安装的包名列表
+func (d *DeviceClient) GetPackagesContext(ctx context.Context) ([]string, error) {
+	return d.client.GetPackagesContext(ctx, d.Serial())
+}
+
+// Clear 清除应用数据
+func (d *DeviceClient) Clear(pkg string) error {
+	return d.client.Clear(d.Serial(), pkg)
+}
+
+// ClearContext 清除应用数据
+func (d *DeviceClient) ClearContext(ctx context.Context, pkg string) error {
+	return d.client.ClearContext(ctx, d.Serial(), pkg)
+}
+
+// IsInstalled 检查包是否已安装
+func (d *DeviceClient) IsInstalled(pkg string) (bool, error) {
+	return d.client.IsInstalled(d.Serial(), pkg)
+}
+
+// IsInstalledContext 检查包是否已安装
+func (d *DeviceClient) IsInstalledContext(ctx context.Context, pkg string) (bool, error) {
+	return d.client.IsInstalledContext(ctx, d.Serial(), pkg)
+}
+
+// WaitBootComplete 等待设备启动完成
+func (d *DeviceClient) WaitBootComplete() error {
+	return d.client.WaitBootComplete(d.Serial())
+}
+
+// WaitBootCompleteContext 等待设备启动完成，通常需要通过上下文设置超时
+func (d *DeviceClient) WaitBootCompleteContext(ctx context.Context) error {
+	return d.client.WaitBootCompleteContext(ctx, d.Serial())
+}
+
+// Features 查询设备支持的特性
+func (d *DeviceClient) Features() (FeatureSet, error) {
+	return d.client.DeviceFeatures(d.Serial())
+}
+
+// FeaturesContext 查询设备支持的特性，结果按传输缓存
+func (d *DeviceClient) FeaturesContext(ctx context.Context) (FeatureSet, error) {
+	return d.client.DeviceFeaturesContext(ctx, d.Serial())
+}
+
+// SupportsFeature 检查服务器和设备是否都支持特性
+func (d *DeviceClient) SupportsFeature(feature string) (bool, error) {
+	return d.client.SupportsFeature(d.Serial(), feature)
+}
+
+// SupportsFeatureContext 检查服务器和设备是否都支持特性
+func (d *DeviceClient) SupportsFeatureContext(ctx context.Context, feature string) (bool, error) {
+	return d.client.SupportsFeatureContext(ctx, d.Serial(), feature)
+}
+
+// WaitReady 等待设备可以使用：启动完成、包管理器可用，按选项要求用户已解锁
+func (d *DeviceClient) WaitReady(options *ReadinessOptions) error {
+	return d.client.WaitReady(d.Serial(), options)
+}
+
+// WaitReadyContext 等待设备可以使用，通常需要通过上下文设置超时
+func (d *DeviceClient) WaitReadyContext(ctx context.Context, options *ReadinessOptions) error {
+	return d.client.WaitReadyContext(ctx, d.Serial(), options)
+}
+
+// Reverse 建立从设备到主机的反向端口转发
+func (d *DeviceClient) Reverse(remote string, local string) error {
+	return d.client.Reverse(d.Serial(), remote, local)
+}
+
+// ReverseContext 建立从设备到主机的反向端口转发
+func (d *DeviceClient) ReverseContext(ctx context.Context, remote string, local string) error {
+	return d.client.ReverseContext(ctx, d.Serial(), remote, local)
+}
+
+// ListReverses 列出设备上的反向端口转发
+func (d *DeviceClient) ListReverses() ([]hosttransport.Reverse, error) {
+	return d.client.ListReverses(d.Serial())
+}
+
+// ListReversesContext 列出设备上的反向端口转发
+func (d *DeviceClient) ListReversesContext(ctx context.Context) ([]hosttransport.Reverse, error) {
+	return d.client.ListReversesContext(ctx, d.Serial())
+}
+
+// OpenLog 打开设备上指定名称的日志流，如main、system、events
+func (d *DeviceClient) OpenLog(name string) (io.ReadCloser, error) {
+	return d.client.OpenLog(d.Serial(), name)
+}
+
+// OpenLogContext 打开设备上指定名称的日志流，上下文结束或关闭流时断开传输
+func (d *DeviceClient) OpenLogContext(ctx context.Context, name string) (io.ReadCloser, error) {
+	return d.client.OpenLogContext(ctx, d.Serial(), name)
+}
+
+// OpenTcp 连接设备上的TCP端口，host为空时连接设备本机
+func (d *DeviceClient) OpenTcp(port int, host string) (io.ReadWriteCloser, error) {
+	return d.client.OpenTcp(d.Serial(), port, host)
+}
+
+// OpenTcpContext 连接设备上的TCP端口，上下文结束或关闭连接时断开传输
+func (d *DeviceClient) OpenTcpContext(ctx context.Context, port int, host string) (io.ReadWriteCloser, error) {
+	return d.client.OpenTcpContext(ctx, d.Serial(), port, host)
+}
+
+// OpenLocal 连接设备上的本地socket，path可以带有localabstract:等前缀，否则视为localfilesystem路径
+func (d *DeviceClient) OpenLocal(path string) (io.ReadWriteCloser, error) {
+	return d.client.OpenLocal(d.Serial(), path)
+}
+
+// OpenLocalContext 连接设备上的本地socket，上下文结束或关闭连接时断开传输
+func (d *DeviceClient) OpenLocalContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	return d.client.OpenLocalContext(ctx, d.Serial(), path)
+}
+
+// GetSerialNo 获取设备序列号
+func (d *DeviceClient) GetSerialNo() (string, error) {
+	return d.client.GetSerialNo(d.Serial())
+}
+
+// GetSerialNoContext 获取设备序列号
+func (d *DeviceClient) GetSerialNoContext(ctx context.Context) (string, error) {
+	return d.client.GetSerialNoContext(ctx, d.Serial())
+}
+
+// GetDevicePath 获取设备路径，如usb:1-1
+func (d *DeviceClient) GetDevicePath() (string, error) {
+	return d.client.GetDevicePath(d.Serial())
+}
+
+// GetDevicePathContext 获取设备路径
+func (d *DeviceClient) GetDevicePathContext(ctx context.Context) (string, error) {
+	return d.client.GetDevicePathContext(ctx, d.Serial())
+}
+
+// ListForwards 列出设备的端口转发
+func (d *DeviceClient) ListForwards() ([]hostserial.Forward, error) {
+	return d.client.ListForwards(d.Serial())
+}
+
+// ListForwardsContext 列出设备的端口转发
+func (d *DeviceClient) ListForwardsContext(ctx context.Context) ([]hostserial.Forward, error) {
+	return d.client.ListForwardsContext(ctx, d.Serial())
+}
+
+// WaitForDevice 等待设备上线
+func (d *DeviceClient) WaitForDevice() error {
+	return d.client.WaitForDevice(d.Serial())
+}
+
+// WaitForDeviceContext 等待设备上线，通常需要通过上下文设置超时
+func (d *DeviceClient) WaitForDeviceContext(ctx context.Context) error {
+	return d.client.WaitForDeviceContext(ctx, d.Serial())
+}
@@ -0,0 +1,273 @@
+package adb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"syscall"
+	"time"
+
+	adbsync "adb-kit-go/pkg/adb/sync"
+)
+
+// DeviceFS 通过同步协议以io/fs.FS的形式访问设备的文件系统，实现fs.ReadDirFS、fs.StatFS和fs.ReadFileFS，
+// 可以直接用于fs.WalkDir、fs.Glob和template.ParseFS等。
+// 名称按io/fs的规则使用不以/开头的相对路径，"."对应设备的根目录，需要其他根目录时使用fs.Sub。
+// 每次操作打开新的同步连接。与os.DirFS相同，Open和Stat跟随符号链接，ReadDir的条目返回链接本身的状态
+type DeviceFS struct {
+	client *Client
+	serial string
+	ctx    context.Context
+}
+
+// FS 返回设备的文件系统
+func (c *Client) FS(serial string) *DeviceFS {
+	return c.FSContext(context.Background(), serial)
+}
+
+// FSContext 返回设备的文件系统，所有操作使用ctx，上下文结束后操作返回错误
+func (c *Client) FSContext(ctx context.Context, serial string) *DeviceFS {
+	return &DeviceFS{client: c, serial: serial, ctx: ctx}
+}
+
+// Open 打开文件或目录，文件的内容在读取时通过同步协议拉取，需要关闭以断开连接
+func (f *DeviceFS) Open(name string) (fs.File, error) {
+	stats, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info := &fileInfo{name: path.Base(name), stats: stats}
+	if stats.IsDir() {
+		return &deviceDir{fsys: f, name: name, info: info}, nil
+	}
+
+	syncService, err := f.client.SyncServiceContext(f.ctx, f.serial)
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+	transfer, err := syncService.PullContext(f.ctx, devicePath(name))
+	if err != nil {
+		syncService.End()
+		return nil, fsError("open", name, err)
+	}
+	return &deviceFile{name: name, info: info, sync: syncService, transfer: transfer}, nil
+}
+
+// Stat 返回文件的状态，文件不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist)
+func (f *DeviceFS) Stat(name string) (fs.FileInfo, error) {
+	stats, err := f.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: path.Base(name), stats: stats}, nil
+}
+
+// ReadDir 列出目录中的条目，按名称排序
+// 设备对不存在的目录也返回空列表，此时通过Stat区分空目录、不存在的路径和文件
+func (f *DeviceFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries, err := f.client.ReadDirContext(f.ctx, f.serial, devicePath(name))
+	if err != nil {
+		return nil, fsError("readdir", name, err)
+	}
+	if len(entries) == 0 {
+		stats, err := f.stat("readdir", name)
+		if err != nil {
+			return nil, err
+		}
+		if !stats.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+		}
+	}
+
+	list := make([]fs.DirEntry, len(entries))
+	for i, entry := range entries {
+		list[i] = fs.FileInfoToDirEntry(&fileInfo{name: entry.Name(), stats: &entry.Stats})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list, nil
+}
+
+// ReadFile 读取文件的全部内容，状态和内容在同一个同步连接中获取
+func (f *DeviceFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+
+	var data []byte
+	err := f.client.withSync(f.ctx, f.serial, func(syncService *Sync) error {
+		stats, err := syncService.StatFollow(devicePath(name))
+		if err != nil {
+			return err
+		}
+		if stats.IsDir() {
+			return syscall.EISDIR
+		}
+
+		transfer, err := syncService.PullContext(f.ctx, devicePath(name))
+		if err != nil {
+			return err
+		}
+		if data, err = io.ReadAll(transfer); err != nil {
+			return err
+		}
+		return transfer.Wait()
+	})
+	if err != nil {
+		return nil, fsError("readfile", name, err)
+	}
+	return data, nil
+}
+
+// stat 检查名称并获取跟随符号链接的状态，错误包装为fs.PathError
+func (f *DeviceFS) stat(op string, name string) (*adbsync.Stats, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	var stats *adbsync.Stats
+	err := f.client.withSync(f.ctx, f.serial, func(syncService *Sync) (err error) {
+		stats, err = syncService.StatFollow(devicePath(name))
+		return err
+	})
+	if err != nil {
+		return nil, fsError(op, name, err)
+	}
+	return stats, nil
+}
+
+// devicePath 将io/fs的名称转换为设备上的绝对路径
+func devicePath(name string) string {
+	if name == "." {
+		return "/"
+	}
+	return "/" + name
+}
+
+// fsError 将错误包装为使用io/fs名称的fs.PathError
+func fsError(op string, name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// fileInfo 由同步协议的状态实现fs.FileInfo
+type fileInfo struct {
+	name  string
+	stats *adbsync.Stats
+}
+
+// Name 文件名
+func (i *fileInfo) Name() string {
+	return i.name
+}
+
+// Size 文件大小
+func (i *fileInfo) Size() int64 {
+	return i.stats.Size()
+}
+
+// Mode 文件模式
+func (i *fileInfo) Mode() fs.FileMode {
+	return i.stats.FileMode()
+}
+
+// ModTime 修改时间
+func (i *fileInfo) ModTime() time.Time {
+	return i.stats.ModTime()
+}
+
+// IsDir 是否为目录
+func (i *fileInfo) IsDir() bool {
+	return i.stats.IsDir()
+}
+
+// Sys 返回*adbsync.Stats
+func (i *fileInfo) Sys() interface{} {
+	return i.stats
+}
+
+// deviceFile DeviceFS打开的文件，读取拉取传输的数据
+type deviceFile struct {
+	name     string
+	info     *fileInfo
+	sync     *Sync
+	transfer *adbsync.PullTransfer
+}
+
+// Stat 返回打开时获取的状态
+func (f *deviceFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Read 读取文件内容
+func (f *deviceFile) Read(p []byte) (int, error) {
+	n, err := f.transfer.Read(p)
+	if err != nil && err != io.EOF {
+		err = fsError("read", f.name, err)
+	}
+	return n, err
+}
+
+// Close 取消未完成的传输并断开同步连接
+func (f *deviceFile) Close() error {
+	f.transfer.Cancel()
+	return f.sync.End()
+}
+
+// deviceDir DeviceFS打开的目录，条目在第一次ReadDir时获取
+type deviceDir struct {
+	fsys    *DeviceFS
+	name    string
+	info    *fileInfo
+	entries []fs.DirEntry
+	loaded  bool
+}
+
+// Stat 返回打开时获取的状态
+func (d *deviceDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read 目录不能读取
+func (d *deviceDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: syscall.EISDIR}
+}
+
+// Close 关闭目录
+func (d *deviceDir) Close() error {
+	return nil
+}
+
+// ReadDir 实现fs.ReadDirFile，n大于0时每次最多返回n个条目，没有更多条目时返回io.EOF
+func (d *deviceDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.loaded = entries, true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,88 @@
+package adb_test
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// deviceFSFeatures 分别测试同步协议v1和v2
+var deviceFSFeatures = []struct {
+	name     string
+	features []string
+}{
+	{"v1", nil},
+	{"v2", []string{"stat_v2", "ls_v2"}},
+}
+
+func TestDeviceFS(t *testing.T) {
+	for _, tt := range deviceFSFeatures {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t)
+			device := server.AddDevice("a")
+			device.SetFeatures(tt.features...)
+			device.FS.WriteFile("/data/local/tmp/a.txt", []byte("hello"), 0o644)
+			device.FS.WriteFile("/data/local/tmp/dir/b.txt", []byte("world"), 0o600)
+
+			fsys, err := fs.Sub(server.Client().FS("a"), "data/local/tmp")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestDeviceFSSymlinks 打开、读取和Stat跟随符号链接，目录项中的链接仍是链接
+func TestDeviceFSSymlinks(t *testing.T) {
+	for _, tt := range deviceFSFeatures {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t)
+			device := server.AddDevice("a")
+			device.SetFeatures(tt.features...)
+			device.FS.WriteFile("/storage/emulated/0/x/a.txt", []byte("A"), 0o644)
+			device.FS.Symlink("/storage/emulated/0", "/sdcard")
+			device.FS.Symlink("x/a.txt", "/storage/emulated/0/link.txt")
+			fsys := server.Client().FS("a")
+
+			info, err := fs.Stat(fsys, "sdcard")
+			if err != nil || !info.IsDir() {
+				t.Fatalf("Stat(sdcard) = %v, %v, want a directory", info, err)
+			}
+			for _, name := range []string{"sdcard/x/a.txt", "sdcard/link.txt"} {
+				data, err := fs.ReadFile(fsys, name)
+				if err != nil || string(data) != "A" {
+					t.Errorf("ReadFile(%s) = %q, %v, want A", name, data, err)
+				}
+			}
+
+			var walked []string
+			err = fs.WalkDir(fsys, "sdcard", func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				walked = append(walked, path)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkDir: %v", err)
+			}
+			if got, want := strings.Join(walked, ","), "sdcard,sdcard/link.txt,sdcard/x,sdcard/x/a.txt"; got != want {
+				t.Errorf("WalkDir visited %s, want %s", got, want)
+			}
+
+			entries, err := fs.ReadDir(fsys, "sdcard")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if entry.Name() == "link.txt" && entry.Type() != fs.ModeSymlink {
+					t.Errorf("link.txt type = %v, want symlink", entry.Type())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package adb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// deviceList 通过常驻的host:track-devices-l连接维护设备列表
+// ADB服务器应答host:devices等服务后会关闭连接，连接无法复用；
+// track-devices是少数保持连接的host服务，服务器会在设备变化时主动推送完整列表。
+// 跟踪器在服务器重启等断开后自动重连，列表只读取跟踪器的设备列表，不订阅事件通道；
+// 断开期间列表可能已经过时，get返回错误，由调用方改用一次性的查询
+type deviceList struct {
+	mu      sync.Mutex
+	tracker *Tracker
+	closed  bool
+}
+
+// get 返回当前设备列表，跟踪器不存在或已结束时重新建立，跟踪连接断开尚未重新同步时返回错误
+func (l *deviceList) get(ctx context.Context, c *Client) ([]Device, error) {
+	tracker, err := l.acquire(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-tracker.Synced():
+	case <-tracker.Done():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case <-tracker.Done():
+		return nil, fmt.Errorf("device tracker ended")
+	default:
+	}
+
+	tracked, ok := tracker.currentDevices()
+	if !ok {
+		return nil, fmt.Errorf("device tracker disconnected")
+	}
+	devices := make([]Device, 0, len(tracked))
+	for _, d := range tracked {
+		devices = append(devices, d.clone())
+	}
+	return devices, nil
+}
+
+// acquire 返回正在运行的跟踪器
+func (l *deviceList) acquire(ctx context.Context, c *Client) (*Tracker, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, fmt.Errorf("client closed")
+	}
+	if l.tracker != nil {
+		select {
+		case <-l.tracker.Done():
+		default:
+			return l.tracker, nil
+		}
+	}
+
+	// 跟踪器由客户端持有，不随本次调用的上下文结束
+	tracker, err := c.openTracker(ctx, context.Background())
+	if err != nil {
+		return nil, err
+	}
+	l.tracker = tracker
+	return tracker, nil
+}
+
+// close 结束跟踪连接
+func (l *deviceList) close() error {
+	l.mu.Lock()
+	tracker := l.tracker
+	l.tracker = nil
+	l.closed = true
+	l.mu.Unlock()
+
+	if tracker == nil {
+		return nil
+	}
+	return tracker.End()
+}
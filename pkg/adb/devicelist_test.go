@@ -0,0 +1,142 @@
+package adb_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"adb-kit-go/pkg/adb"
+	"adb-kit-go/pkg/adb/adbtest"
+)
+
+// trackingClient 返回开启TrackDeviceList的客户端，测试结束时关闭
+func trackingClient(t *testing.T, server *adbtest.Server) *adb.Client {
+	t.Helper()
+	options := server.Options()
+	options.TrackDeviceList = true
+	client := adb.NewClient(options)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// serials 以逗号连接ListDevices返回的序列号
+func serials(t *testing.T, client *adb.Client) string {
+	t.Helper()
+	devices, err := client.ListDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, device := range devices {
+		ids = append(ids, device.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestListDevices(t *testing.T) {
+	server := newServer(t)
+	const count = 100
+	for i := 0; i < count; i++ {
+		server.AddDevice(fmt.Sprintf("d%03d", i))
+	}
+
+	client := server.Client()
+	defer client.Close()
+	devices, err := client.ListDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != count {
+		t.Fatalf("ListDevices returned %d devices, want %d", len(devices), count)
+	}
+	for i, device := range devices {
+		if want := fmt.Sprintf("d%03d", i); device.ID != want || device.State != "device" {
+			t.Errorf("device %d = %s %s, want %s device", i, device.ID, device.State, want)
+		}
+	}
+}
+
+// TestListDevicesFollowsChanges 设备列表复用跟踪连接，之后的变化和服务器重启后的变化都能看到
+func TestListDevicesFollowsChanges(t *testing.T) {
+	server := newServer(t)
+	server.AddDevice("a")
+	client := trackingClient(t, server)
+
+	if got := serials(t, client); got != "a" {
+		t.Fatalf("devices = %s, want a", got)
+	}
+
+	server.AddDevice("b")
+	if !eventually(t, 5*time.Second, func() bool { return serials(t, client) == "a,b" }) {
+		t.Fatalf("devices = %s, want a,b", serials(t, client))
+	}
+
+	server.DropConnections()
+	server.RemoveDevice("a")
+	if !eventually(t, 5*time.Second, func() bool { return serials(t, client) == "b" }) {
+		t.Fatalf("devices after reconnect = %s, want b", serials(t, client))
+	}
+}
+
+// TestListDevicesWhileTrackerDisconnected 跟踪连接无法恢复时不返回断开前的列表
+func TestListDevicesWhileTrackerDisconnected(t *testing.T) {
+	server := newServer(t)
+	server.AddDevice("a")
+	client := trackingClient(t, server)
+
+	if got := serials(t, client); got != "a" {
+		t.Fatalf("devices = %s, want a", got)
+	}
+
+	server.InjectFault(adbtest.Fault{Service: "host:track-devices*", Close: true})
+	server.DropConnections()
+	server.RemoveDevice("a")
+	server.AddDevice("b")
+	if !eventually(t, 5*time.Second, func() bool { return serials(t, client) == "b" }) {
+		t.Fatalf("devices while tracker is disconnected = %s, want b", serials(t, client))
+	}
+	if requests := strings.Join(server.Requests(), "\n"); !strings.Contains(requests, "host:devices") {
+		t.Fatalf("requests = %s, want a host:devices query", requests)
+	}
+}
+
+// TestListDevicesLong 单次查询与跟踪器一样读取devices -l的字段
+func TestListDevicesLong(t *testing.T) {
+	server := newServer(t)
+	server.AddDevice("a").SetProp("ro.product.model", "Pixel 7")
+	client := server.Client()
+
+	devices, err := client.ListDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker, err := client.TrackDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Stop()
+	<-tracker.Synced()
+	tracked := tracker.GetDevices()
+
+	if len(devices) != 1 || len(tracked) != 1 {
+		t.Fatalf("ListDevices returned %d devices, tracker has %d, want 1", len(devices), len(tracked))
+	}
+	got, want := devices[0], tracked[0]
+	if got.Model != "Pixel_7" || got.TransportID == 0 {
+		t.Errorf("device = %+v, want model Pixel_7 and a transport ID", got)
+	}
+	if got.ID != want.ID || got.State != want.State || got.Path != want.Path || got.Model != want.Model || got.TransportID != want.TransportID {
+		t.Errorf("ListDevices = %+v, tracker = %+v", got, *want)
+	}
+
+	// 不支持host:devices-l的旧服务器
+	server.InjectFault(adbtest.Fault{Service: "host:devices-l", Fail: "unknown host service"})
+	devices, err = client.ListDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].ID != "a" || devices[0].State != "device" {
+		t.Fatalf("ListDevices on old server = %+v, want a device", devices)
+	}
+}
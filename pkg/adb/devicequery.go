@@ -0,0 +1,178 @@
+package adb
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+	"adb-kit-go/pkg/adb/query"
+)
+
+// DeviceQuery 解析后的设备查询，语法见 query 包
+type DeviceQuery = query.Query
+
+// ParseDeviceQuery 解析设备查询，如 `ro.build.version.sdk>=30 && model~"Pixel"`
+func ParseDeviceQuery(source string) (*DeviceQuery, error) {
+	return query.Parse(source)
+}
+
+// 查询中可以使用的系统属性别名
+var propertyAliases = map[string]string{
+	"abi":          "ro.product.cpu.abi",
+	"sdk":          "ro.build.version.sdk",
+	"release":      "ro.build.version.release",
+	"brand":        "ro.product.brand",
+	"manufacturer": "ro.product.manufacturer",
+	"fingerprint":  "ro.build.fingerprint",
+}
+
+// ListDevicesWithPaths 通过host:devices-l列出设备，包含USB路径、型号和传输ID等字段
+func (c *Client) ListDevicesWithPaths() ([]Device, error) {
+	return c.ListDevicesWithPathsContext(context.Background())
+}
+
+// ListDevicesWithPathsContext 通过host:devices-l列出设备，与ListDevicesContext相同
+func (c *Client) ListDevicesWithPathsContext(ctx context.Context) ([]Device, error) {
+	return c.ListDevicesContext(ctx)
+}
+
+// SelectDevices 返回满足查询的设备
+// 查询可以使用state、serial、devices -l中的字段（product、model、device、transport_id、usb），
+// 系统属性名（如ro.build.version.sdk）以及别名abi、sdk、release、brand、manufacturer和fingerprint；
+// 系统属性只在查询引用时读取，并按设备缓存
+func (c *Client) SelectDevices(source string) ([]Device, error) {
+	return c.SelectDevicesContext(context.Background(), source)
+}
+
+// SelectDevicesContext 返回满足查询的设备，返回设备的Props包含devices -l的字段和已读取的系统属性
+func (c *Client) SelectDevicesContext(ctx context.Context, source string) ([]Device, error) {
+	q, err := ParseDeviceQuery(source)
+	if err != nil {
+		return nil, err
+	}
+	return c.SelectDevicesQuery(ctx, q)
+}
+
+// SelectDevicesQuery 返回满足已解析查询的设备
+func (c *Client) SelectDevicesQuery(ctx context.Context, q *DeviceQuery) ([]Device, error) {
+	devices, err := c.ListDevicesWithPathsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []Device
+	for _, device := range devices {
+		attrs := &deviceAttributes{ctx: ctx, client: c, device: device}
+		if q.Match(attrs) {
+			device = device.clone()
+			for key, value := range attrs.props {
+				device.SetProperty(key, value)
+			}
+			selected = append(selected, device)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
+
+// ClearPropertyCache 清除设备查询缓存的系统属性
+func (c *Client) ClearPropertyCache() {
+	c.props.clear()
+}
+
+// deviceAttributes 查询时的设备属性，系统属性在首次引用时读取
+type deviceAttributes struct {
+	ctx    context.Context
+	client *Client
+	device Device
+	props  map[string]string
+	loaded bool
+}
+
+// Lookup 实现query.Attributes接口
+func (a *deviceAttributes) Lookup(key string) (string, bool) {
+	switch key {
+	case "serial":
+		return a.device.ID, true
+	case "state":
+		return a.device.State, true
+	case "usb":
+		path, ok := strings.CutPrefix(a.device.Path, "usb:")
+		return path, ok
+	case "product":
+		return a.device.Product, a.device.Product != ""
+	case "model":
+		return a.device.Model, a.device.Model != ""
+	case "device":
+		return a.device.DeviceName, a.device.DeviceName != ""
+	case "transport_id":
+		return strconv.FormatUint(a.device.TransportID, 10), a.device.TransportID != 0
+	}
+	if value, ok := a.device.Props[key]; ok {
+		return value, true
+	}
+	if alias, ok := propertyAliases[key]; ok {
+		key = alias
+	}
+	if !strings.Contains(key, ".") {
+		return "", false
+	}
+
+	if !a.loaded {
+		a.loaded = true
+		// 读取失败的设备视为没有系统属性，不影响其他设备的选择
+		a.props, _ = a.client.props.get(a.ctx, a.client, a.device)
+	}
+	value, ok := a.props[key]
+	return value, ok
+}
+
+// propertyCache 按设备缓存系统属性，设备以新的传输ID重新连接后重新读取
+type propertyCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]string
+}
+
+// get 返回设备的系统属性，只读取在线的设备
+func (p *propertyCache) get(ctx context.Context, c *Client, device Device) (map[string]string, error) {
+	if device.State != "device" {
+		return nil, nil
+	}
+	key := device.ID + "/" + strconv.FormatUint(device.TransportID, 10)
+
+	p.mu.Lock()
+	props, ok := p.entries[key]
+	p.mu.Unlock()
+	if ok {
+		return props, nil
+	}
+
+	dialect := c.dialect(ctx, device.ID)
+	err := c.retryTransport(ctx, device.ID, func(conn *Connection) error {
+		var err error
+		props, err = withDialect(hosttransport.NewGetPropertiesCommand(conn.Send, conn.ReadString), dialect).Execute()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.entries == nil {
+		p.entries = make(map[string]map[string]string)
+	}
+	p.entries[key] = props
+	return props, nil
+}
+
+// clear 清除全部缓存
+func (p *propertyCache) clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries = nil
+}
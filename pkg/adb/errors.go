@@ -0,0 +1,29 @@
+package adb
+
+import (
+	"adb-kit-go/pkg/adb/adberr"
+)
+
+// 错误类型，详见 adberr 包
+type (
+	// FailError ADB服务器或设备返回的FAIL响应
+	FailError = adberr.FailError
+	// PrematureEOFError 数据未读完时流已结束
+	PrematureEOFError = adberr.PrematureEOFError
+	// UnexpectedDataError 收到了与协议不符的数据
+	UnexpectedDataError = adberr.UnexpectedDataError
+	// InstallError 应用安装失败
+	InstallError = adberr.InstallError
+)
+
+// 哨兵错误，可以通过 errors.Is 判断
+var (
+	ErrDeviceNotFound     = adberr.ErrDeviceNotFound
+	ErrDeviceOffline      = adberr.ErrDeviceOffline
+	ErrDeviceUnauthorized = adberr.ErrDeviceUnauthorized
+	ErrMoreThanOneDevice  = adberr.ErrMoreThanOneDevice
+	ErrServerUnreachable  = adberr.ErrServerUnreachable
+	ErrPermissionDenied   = adberr.ErrPermissionDenied
+	ErrUnknownService     = adberr.ErrUnknownService
+	ErrInstallFailed      = adberr.ErrInstallFailed
+)
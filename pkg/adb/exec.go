@@ -0,0 +1,110 @@
+package adb
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+	"adb-kit-go/pkg/adb/shellcmd"
+)
+
+// Exec 执行命令并返回原始的二进制输出流，与adb exec-out相同
+func (c *Client) Exec(serial string, command string) (io.ReadCloser, error) {
+	return c.ExecContext(context.Background(), serial, command)
+}
+
+// ExecContext 执行命令并返回原始的二进制输出流，上下文结束或关闭流时断开传输
+// 设备支持exec:时输出不经过转换；不支持exec:的旧设备（Android 5.0之前）退回到shell:并还原CRLF。
+// 两种方式的stderr都会混入输出，二进制输出需要在命令中重定向stderr
+func (c *Client) ExecContext(ctx context.Context, serial string, command string) (io.ReadCloser, error) {
+	return c.openExec(ctx, serial,
+		func(conn *Connection) (io.Reader, error) {
+			return hosttransport.NewExecCommand(conn.Send, conn.ReadString).WithStream(conn).Execute(command)
+		},
+		func(conn *Connection) (io.Reader, error) {
+			return hosttransport.NewExecCommand(conn.Send, conn.ReadString).ExecuteLegacy(command)
+		},
+	)
+}
+
+// OpenExec 通过exec:执行命令并返回双向的原始数据流，写入的数据作为命令的标准输入
+func (c *Client) OpenExec(serial string, command string) (io.ReadWriteCloser, error) {
+	return c.OpenExecContext(context.Background(), serial, command)
+}
+
+// OpenExecContext 通过exec:执行命令并返回双向的原始数据流，上下文结束或关闭流时断开传输
+// 标准输入无法单独关闭，读到EOF才结束的命令需要关闭流；设备不支持exec:时返回FailError
+func (c *Client) OpenExecContext(ctx context.Context, serial string, command string) (io.ReadWriteCloser, error) {
+	return c.openStream(ctx, serial, func(conn *Connection) (io.Reader, error) {
+		return hosttransport.NewExecCommand(conn.Send, conn.ReadString).WithStream(conn).Execute(command)
+	})
+}
+
+// ExecIn 通过exec:执行命令并将stdin的数据原样写入命令的标准输入，与adb exec-in相同
+func (c *Client) ExecIn(serial string, command string, stdin io.Reader) error {
+	return c.ExecInContext(context.Background(), serial, command, stdin)
+}
+
+// ExecInContext 通过exec:执行命令并写入stdin直到EOF，然后关闭连接，命令读到EOF
+// 与adb exec-in相同，命令的输出被丢弃，如 ExecIn(serial, "cat > /data/local/tmp/file", f)
+func (c *Client) ExecInContext(ctx context.Context, serial string, command string, stdin io.Reader) error {
+	stream, err := c.OpenExecContext(ctx, serial, command)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if _, err := io.Copy(stream, stdin); err != nil {
+		return contextError(ctx, err)
+	}
+	return nil
+}
+
+// Cat 读取设备上的文件，不需要同步协议，可以读取sync:无法访问但shell用户可读的文件
+func (c *Client) Cat(serial string, path string) (io.ReadCloser, error) {
+	return c.CatContext(context.Background(), serial, path)
+}
+
+// CatContext 通过cat读取设备上的文件，上下文结束或关闭流时断开传输
+// 文件不存在等错误信息会混入输出，需要可靠的错误时使用SyncService
+func (c *Client) CatContext(ctx context.Context, serial string, path string) (io.ReadCloser, error) {
+	return c.execCommand(ctx, serial, shellcmd.New("cat", path))
+}
+
+// Tar 将设备上的目录打包为tar数据流
+func (c *Client) Tar(serial string, dir string) (io.ReadCloser, error) {
+	return c.TarContext(context.Background(), serial, dir)
+}
+
+// TarContext 将设备上的目录打包为tar数据流，上下文结束或关闭流时断开传输
+// 需要设备上有tar命令（Android 6.0及以上的toybox），tar的错误信息被丢弃
+func (c *Client) TarContext(ctx context.Context, serial string, dir string) (io.ReadCloser, error) {
+	return c.execCommand(ctx, serial, shellcmd.New("tar", "-cf", "-", "-C", dir, ".").Redirect("2>", "/dev/null"))
+}
+
+// execCommand 按设备的引用方式构建命令并通过ExecContext执行，参数中有NUL字节等无法构建时不打开传输
+func (c *Client) execCommand(ctx context.Context, serial string, cmd *shellcmd.Command) (io.ReadCloser, error) {
+	line, err := cmd.Dialect(c.dialect(ctx, serial)).Build()
+	if err != nil {
+		return nil, err
+	}
+	return c.ExecContext(ctx, serial, line)
+}
+
+// openExec 在设备传输上通过exec:打开输出流，设备拒绝exec:时打开新的传输通过legacy执行
+func (c *Client) openExec(ctx context.Context, serial string, exec, legacy func(conn *Connection) (io.Reader, error)) (io.ReadCloser, error) {
+	var unsupported bool
+	stream, err := c.openStream(ctx, serial, func(conn *Connection) (io.Reader, error) {
+		reader, err := exec(conn)
+		// 传输已经建立，此时的FAIL来自设备，表示设备不认识exec:服务
+		var failErr *FailError
+		unsupported = errors.As(err, &failErr)
+		return reader, err
+	})
+	if !unsupported {
+		return stream, err
+	}
+
+	return c.openStream(ctx, serial, legacy)
+}
@@ -0,0 +1,258 @@
+package adb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FanOutOptions 在多台设备上并发执行的选项
+type FanOutOptions struct {
+	// Concurrency 同时执行的设备数量上限，0表示不限制
+	Concurrency int
+	// Timeout 每台设备的超时时间，分步执行时为每一步的超时，0表示不限制
+	Timeout time.Duration
+	// Query 按查询语言选择设备，如 `sdk>=30 && abi=arm64-v8a`，见 Client.SelectDevices
+	Query string
+	// Filter 从ListDevices的结果中选择设备，与Query同时设置时两者都需满足
+	// Query和Filter都为空时选择状态为device的全部设备
+	Filter func(Device) bool
+}
+
+// FanOutResult 单台设备的执行结果
+type FanOutResult[T any] struct {
+	Serial   string
+	Value    T
+	Err      error
+	Duration time.Duration
+}
+
+// FanOutResults 按序列号索引的执行结果
+type FanOutResults[T any] map[string]*FanOutResult[T]
+
+// Serials 返回排序后的全部序列号
+func (r FanOutResults[T]) Serials() []string {
+	serials := make([]string, 0, len(r))
+	for serial := range r {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+	return serials
+}
+
+// Failed 返回排序后的失败设备序列号
+func (r FanOutResults[T]) Failed() []string {
+	var failed []string
+	for _, serial := range r.Serials() {
+		if r[serial].Err != nil {
+			failed = append(failed, serial)
+		}
+	}
+	return failed
+}
+
+// Err 合并所有设备的错误，每个错误都是 *DeviceError，全部成功时返回nil
+func (r FanOutResults[T]) Err() error {
+	var errs []error
+	for _, serial := range r.Failed() {
+		errs = append(errs, &DeviceError{Serial: serial, Err: r[serial].Err})
+	}
+	return errors.Join(errs...)
+}
+
+// DeviceError 单台设备上发生的错误
+type DeviceError struct {
+	Serial string
+	Err    error
+}
+
+// Error 实现error接口
+func (e *DeviceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Serial, e.Err)
+}
+
+// Unwrap 返回设备上的原始错误
+func (e *DeviceError) Unwrap() error {
+	return e.Err
+}
+
+// FanOutStep 分步执行中的一步
+type FanOutStep func(ctx context.Context, device *DeviceClient) error
+
+// FanOut 在ListDevices返回的设备上并发执行fn，结果按序列号汇总
+// 返回的错误只表示无法获取设备列表，各设备的错误见结果的Err方法
+func FanOut[T any](ctx context.Context, c *Client, options *FanOutOptions, fn func(ctx context.Context, device *DeviceClient) (T, error)) (FanOutResults[T], error) {
+	serials, err := c.fanOutSerials(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return FanOutDevices(ctx, c, serials, options, fn), nil
+}
+
+// FanOutDevices 在指定序列号的设备上并发执行fn，忽略选项中的Filter
+func FanOutDevices[T any](ctx context.Context, c *Client, serials []string, options *FanOutOptions, fn func(ctx context.Context, device *DeviceClient) (T, error)) FanOutResults[T] {
+	if options == nil {
+		options = &FanOutOptions{}
+	}
+
+	results := make(FanOutResults[T], len(serials))
+	for _, serial := range serials {
+		results[serial] = &FanOutResult[T]{Serial: serial}
+	}
+
+	var slots chan struct{}
+	if options.Concurrency > 0 {
+		slots = make(chan struct{}, options.Concurrency)
+	}
+
+	var wg sync.WaitGroup
+	for _, result := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if slots != nil {
+				select {
+				case slots <- struct{}{}:
+					defer func() { <-slots }()
+				case <-ctx.Done():
+					result.Err = ctx.Err()
+					return
+				}
+			}
+
+			started := time.Now()
+			result.Value, result.Err = runOnDevice(ctx, c.Device(result.Serial), options.Timeout, fn)
+			result.Duration = time.Since(started)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// FanOutSteps 在ListDevices返回的设备上分步执行，每一步在所有设备上同时开始：
+// 全部设备结束上一步之后才开始下一步，某一步失败的设备不再执行之后的步骤
+// 结果的Value为设备完成的步数，Concurrency在每一步内仍然生效
+func FanOutSteps(ctx context.Context, c *Client, options *FanOutOptions, steps ...FanOutStep) (FanOutResults[int], error) {
+	serials, err := c.fanOutSerials(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(FanOutResults[int], len(serials))
+	for _, serial := range serials {
+		results[serial] = &FanOutResult[int]{Serial: serial}
+	}
+
+	for i, step := range steps {
+		if len(serials) == 0 {
+			break
+		}
+
+		stepResults := FanOutDevices(ctx, c, serials, options, func(ctx context.Context, device *DeviceClient) (struct{}, error) {
+			return struct{}{}, step(ctx, device)
+		})
+
+		serials = serials[:0]
+		for _, serial := range stepResults.Serials() {
+			result, stepResult := results[serial], stepResults[serial]
+			result.Duration += stepResult.Duration
+			if stepResult.Err != nil {
+				result.Err = fmt.Errorf("step %d: %w", i+1, stepResult.Err)
+				continue
+			}
+			result.Value++
+			serials = append(serials, serial)
+		}
+	}
+
+	return results, nil
+}
+
+// ShellAll 在所有设备上执行Shell命令
+func (c *Client) ShellAll(options *FanOutOptions, command string) (FanOutResults[*ShellResponse], error) {
+	return c.ShellAllContext(context.Background(), options, command)
+}
+
+// ShellAllContext 在所有设备上执行Shell命令，上下文结束时中止所有设备上的命令
+func (c *Client) ShellAllContext(ctx context.Context, options *FanOutOptions, command string) (FanOutResults[*ShellResponse], error) {
+	return FanOut(ctx, c, options, func(ctx context.Context, device *DeviceClient) (*ShellResponse, error) {
+		return device.ShellContext(ctx, command)
+	})
+}
+
+// InstallAll 在所有设备上安装APK
+func (c *Client) InstallAll(options *FanOutOptions, apkPath string) (FanOutResults[struct{}], error) {
+	return c.InstallAllContext(context.Background(), options, apkPath)
+}
+
+// InstallAllContext 在所有设备上安装APK，上下文结束时中止所有设备上的安装
+func (c *Client) InstallAllContext(ctx context.Context, options *FanOutOptions, apkPath string) (FanOutResults[struct{}], error) {
+	return FanOut(ctx, c, options, func(ctx context.Context, device *DeviceClient) (struct{}, error) {
+		return struct{}{}, device.InstallContext(ctx, apkPath)
+	})
+}
+
+// fanOutSerials 返回按选项选中的设备序列号，没有选中任何设备时返回ErrDeviceNotFound
+func (c *Client) fanOutSerials(ctx context.Context, options *FanOutOptions) ([]string, error) {
+	if options == nil {
+		options = &FanOutOptions{}
+	}
+
+	var (
+		devices []Device
+		err     error
+	)
+	if options.Query != "" {
+		devices, err = c.SelectDevicesContext(ctx, options.Query)
+	} else {
+		devices, err = c.ListDevicesContext(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filter := options.Filter
+	switch {
+	case filter != nil:
+	case options.Query != "":
+		filter = func(Device) bool { return true }
+	default:
+		filter = func(device Device) bool { return device.State == "device" }
+	}
+
+	var serials []string
+	for _, device := range devices {
+		if filter(device) {
+			serials = append(serials, device.ID)
+		}
+	}
+	if len(serials) == 0 {
+		return nil, fmt.Errorf("%w: no devices selected", ErrDeviceNotFound)
+	}
+	return serials, nil
+}
+
+// runOnDevice 在单台设备上执行fn，超时只作用于本设备，fn中的panic作为错误返回
+func runOnDevice[T any](ctx context.Context, device *DeviceClient, timeout time.Duration, fn func(ctx context.Context, device *DeviceClient) (T, error)) (value T, err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return value, err
+	}
+	return fn(ctx, device)
+}
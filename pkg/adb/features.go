@@ -0,0 +1,331 @@
+package adb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"adb-kit-go/pkg/adb/command/host"
+	hostserial "adb-kit-go/pkg/adb/command/host-serial"
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+	"adb-kit-go/pkg/adb/shellcmd"
+)
+
+// 常用的ADB特性，见adb源码中的transport.cpp
+const (
+	FeatureShell2                    = "shell_v2"    // shell协议v2：分离stdout/stderr、返回退出码
+	FeatureCmd                       = "cmd"         // 支持cmd命令，如cmd package install
+	FeatureStat2                     = "stat_v2"     // 同步协议的STA2/LST2
+	FeatureLs2                       = "ls_v2"       // 同步协议的LIS2
+	FeatureSendRecv2                 = "sendrecv_v2" // 同步协议的SND2/RCV2
+	FeatureSendRecv2Brotli           = "sendrecv_v2_brotli"
+	FeatureSendRecv2LZ4              = "sendrecv_v2_lz4"
+	FeatureSendRecv2Zstd             = "sendrecv_v2_zstd"
+	FeatureSendRecv2DryRunSend       = "sendrecv_v2_dry_run_send"
+	FeatureLibusb                    = "libusb"
+	FeaturePushSync                  = "push_sync"
+	FeatureApex                      = "apex"
+	FeatureFixedPushMkdir            = "fixed_push_mkdir"
+	FeatureFixedPushSymlinkTimestamp = "fixed_push_symlink_timestamp"
+	FeatureAbb                       = "abb"
+	FeatureAbbExec                   = "abb_exec"
+	FeatureRemountShell              = "remount_shell"
+	FeatureTrackApp                  = "track_app"
+	FeatureOpenscreenMdns            = "openscreen_mdns"
+	FeatureDelayedAck                = "delayed_ack"
+	FeatureDevRaw                    = "devraw"
+)
+
+// FeatureSet 特性集合
+type FeatureSet map[string]bool
+
+// newFeatureSet 由特性列表创建集合
+func newFeatureSet(features []string) FeatureSet {
+	set := make(FeatureSet, len(features))
+	for _, feature := range features {
+		set[feature] = true
+	}
+	return set
+}
+
+// Has 检查是否支持特性
+func (s FeatureSet) Has(feature string) bool {
+	return s[feature]
+}
+
+// List 返回排序后的特性列表
+func (s FeatureSet) List() []string {
+	features := make([]string, 0, len(s))
+	for feature, ok := range s {
+		if ok {
+			features = append(features, feature)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+// String 返回逗号分隔的特性列表
+func (s FeatureSet) String() string {
+	return strings.Join(s.List(), ",")
+}
+
+// HostFeatures 查询ADB服务器支持的特性
+func (c *Client) HostFeatures() (FeatureSet, error) {
+	return c.HostFeaturesContext(context.Background())
+}
+
+// HostFeaturesContext 查询ADB服务器支持的特性，结果在客户端内缓存
+// 不支持host:host-features的旧服务器视为不支持任何特性
+func (c *Client) HostFeaturesContext(ctx context.Context) (FeatureSet, error) {
+	return c.features.host(ctx, func(ctx context.Context) (FeatureSet, error) {
+		var features FeatureSet
+		err := c.retryConnection(ctx, func(conn *Connection) error {
+			list, err := host.NewHostFeaturesCommand(conn.Send, conn.ReadString).Execute()
+			if errors.Is(err, ErrUnknownService) {
+				err = nil
+			}
+			features = newFeatureSet(list)
+			return err
+		})
+		return features, err
+	})
+}
+
+// DeviceFeatures 查询设备支持的特性
+func (c *Client) DeviceFeatures(serial string) (FeatureSet, error) {
+	return c.DeviceFeaturesContext(context.Background(), serial)
+}
+
+// DeviceFeaturesContext 通过host-serial:<serial>:features查询设备支持的特性，结果按传输缓存
+// 开启TrackDeviceList时设备以新的传输ID重新连接后自动重新查询，
+// 否则缓存保留到打开该设备的传输时发现设备已不存在或离线，也可以调用ClearFeatureCache清除
+func (c *Client) DeviceFeaturesContext(ctx context.Context, serial string) (FeatureSet, error) {
+	selector, err := c.selectorOf(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+	key := selector.String()
+
+	return c.features.device(ctx, key, c.trackedTransportID(ctx, serial), func(ctx context.Context) (FeatureSet, error) {
+		var features FeatureSet
+		err := c.retryConnection(ctx, func(conn *Connection) error {
+			list, err := hostserial.NewFeaturesCommand(conn.Send, conn.ReadString).ExecuteSelector(selector)
+			features = newFeatureSet(list)
+			return err
+		})
+		return features, err
+	})
+}
+
+// SupportsFeature 检查服务器和设备是否都支持特性，与adb命令行选择协议的方式相同
+func (c *Client) SupportsFeature(serial string, feature string) (bool, error) {
+	return c.SupportsFeatureContext(context.Background(), serial, feature)
+}
+
+// SupportsFeatureContext 检查服务器和设备是否都支持特性
+func (c *Client) SupportsFeatureContext(ctx context.Context, serial string, feature string) (bool, error) {
+	hostFeatures, err := c.HostFeaturesContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !hostFeatures.Has(feature) {
+		return false, nil
+	}
+
+	deviceFeatures, err := c.DeviceFeaturesContext(ctx, serial)
+	if err != nil {
+		return false, err
+	}
+	return deviceFeatures.Has(feature), nil
+}
+
+// ClearFeatureCache 清除缓存的服务器和设备特性以及设备的API级别，服务器升级后需要调用
+func (c *Client) ClearFeatureCache() {
+	c.features.clear()
+}
+
+// supports 检查是否可以使用特性，查询失败时按不支持处理，由调用方退回到旧的协议
+func (c *Client) supports(ctx context.Context, serial string, feature string) bool {
+	ok, err := c.SupportsFeatureContext(ctx, serial, feature)
+	return err == nil && ok
+}
+
+// trackedTransportID 返回设备列表中设备的传输ID，没有开启TrackDeviceList或设备不在列表中时返回0
+func (c *Client) trackedTransportID(ctx context.Context, serial string) uint64 {
+	var transportID uint64
+	if c.options.TrackDeviceList {
+		if devices, err := c.devices.get(ctx, c); err == nil {
+			for _, device := range devices {
+				if device.ID == serial {
+					transportID = device.TransportID
+				}
+			}
+		}
+	}
+	return transportID
+}
+
+// deviceSDK 读取设备的API级别（ro.build.version.sdk），与设备特性一样按传输缓存
+func (c *Client) deviceSDK(ctx context.Context, serial string) (int, error) {
+	selector, err := c.selectorOf(ctx, serial)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.features.sdk(ctx, selector.String(), c.trackedTransportID(ctx, serial), func(ctx context.Context) (int, error) {
+		var sdk int
+		err := c.retryTransport(ctx, serial, func(conn *Connection) error {
+			reader, err := hosttransport.NewShellCommand(conn.Send, conn.ReadString).Execute("getprop ro.build.version.sdk")
+			if err != nil {
+				return err
+			}
+			output, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			// 属性为空或无法解析时按未知处理并缓存，不再重复查询
+			sdk, _ = strconv.Atoi(strings.TrimSpace(string(output)))
+			return nil
+		})
+		return sdk, err
+	})
+}
+
+// dialect 返回构建设备上的命令行使用的引用方式，API级别查询失败时使用shellcmd.Modern
+// adbd在Android 4.0之后很久才开始报告设备特性，报告了特性的设备不可能需要Legacy，
+// 特性通常已经因为选择协议而缓存，这时不再到设备上读取API级别
+func (c *Client) dialect(ctx context.Context, serial string) shellcmd.Dialect {
+	if features, err := c.DeviceFeaturesContext(ctx, serial); err == nil && len(features) > 0 {
+		return shellcmd.Modern
+	}
+
+	sdk, err := c.deviceSDK(ctx, serial)
+	if err != nil {
+		return shellcmd.Modern
+	}
+	return shellcmd.DialectForSDK(sdk)
+}
+
+// withDialect 设置命令构建命令行时的引用方式并返回命令，便于链式调用
+func withDialect[T interface{ SetDialect(shellcmd.Dialect) }](command T, dialect shellcmd.Dialect) T {
+	command.SetDialect(dialect)
+	return command
+}
+
+// featureCache 缓存服务器特性和按传输缓存的设备特性、API级别
+type featureCache struct {
+	mu         sync.Mutex
+	hostSet    FeatureSet
+	devices    map[string]deviceFeatures
+	sdks       map[string]deviceSDK
+	generation int // clear时递增，避免清除前开始的查询写入旧结果
+}
+
+// deviceFeatures 一个传输的设备特性
+type deviceFeatures struct {
+	transportID uint64 // 0表示查询时传输ID未知
+	features    FeatureSet
+}
+
+// deviceSDK 一个传输的设备API级别
+type deviceSDK struct {
+	transportID uint64 // 0表示查询时传输ID未知
+	sdk         int
+}
+
+// host 返回缓存的服务器特性，没有时调用fetch查询
+func (f *featureCache) host(ctx context.Context, fetch func(ctx context.Context) (FeatureSet, error)) (FeatureSet, error) {
+	f.mu.Lock()
+	features, generation := f.hostSet, f.generation
+	f.mu.Unlock()
+	if features != nil {
+		return features, nil
+	}
+
+	features, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.generation == generation {
+		f.hostSet = features
+	}
+	return features, nil
+}
+
+// device 返回缓存的设备特性，transportID不为0且与缓存时不同时重新查询
+func (f *featureCache) device(ctx context.Context, key string, transportID uint64, fetch func(ctx context.Context) (FeatureSet, error)) (FeatureSet, error) {
+	f.mu.Lock()
+	entry, ok := f.devices[key]
+	generation := f.generation
+	f.mu.Unlock()
+	if ok && (transportID == 0 || entry.transportID == transportID) {
+		return entry.features, nil
+	}
+
+	features, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.generation == generation {
+		if f.devices == nil {
+			f.devices = make(map[string]deviceFeatures)
+		}
+		f.devices[key] = deviceFeatures{transportID: transportID, features: features}
+	}
+	return features, nil
+}
+
+// sdk 返回缓存的设备API级别，缓存规则与device相同
+func (f *featureCache) sdk(ctx context.Context, key string, transportID uint64, fetch func(ctx context.Context) (int, error)) (int, error) {
+	f.mu.Lock()
+	entry, ok := f.sdks[key]
+	generation := f.generation
+	f.mu.Unlock()
+	if ok && (transportID == 0 || entry.transportID == transportID) {
+		return entry.sdk, nil
+	}
+
+	sdk, err := fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.generation == generation {
+		if f.sdks == nil {
+			f.sdks = make(map[string]deviceSDK)
+		}
+		f.sdks[key] = deviceSDK{transportID: transportID, sdk: sdk}
+	}
+	return sdk, nil
+}
+
+// forget 清除设备的缓存
+func (f *featureCache) forget(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.devices, key)
+	delete(f.sdks, key)
+}
+
+// clear 清除全部缓存
+func (f *featureCache) clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hostSet = nil
+	f.devices = nil
+	f.sdks = nil
+	f.generation++
+}
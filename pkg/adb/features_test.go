@@ -0,0 +1,39 @@
+package adb_test
+
+import (
+	"strings"
+	"testing"
+
+	"adb-kit-go/pkg/adb/adbtest"
+)
+
+// TestDialectSkipsSDKForFeatureDevices 报告了设备特性的设备不读取API级别，没有特性的设备按API级别选择引用方式
+func TestDialectSkipsSDKForFeatureDevices(t *testing.T) {
+	server := newServer(t)
+	server.AddDevice("modern")
+	old := server.AddDevice("old")
+	old.SetFeatures()
+	old.SetProp("ro.build.version.sdk", "10")
+	for _, device := range []*adbtest.Device{server.Device("modern"), old} {
+		device.HandleShell(func(command string) (string, bool) {
+			return "Success\n", strings.Contains(command, "uninstall")
+		})
+	}
+	client := server.Client()
+
+	for _, serial := range []string{"modern", "old"} {
+		if err := client.Uninstall(serial, "com.example"); err != nil {
+			t.Fatalf("Uninstall %s: %v", serial, err)
+		}
+	}
+
+	var getprops int
+	for _, request := range server.Requests() {
+		if strings.Contains(request, "getprop ro.build.version.sdk") {
+			getprops++
+		}
+	}
+	if getprops != 1 {
+		t.Errorf("API level read %d times, want once for the device without features\n%s", getprops, strings.Join(server.Requests(), "\n"))
+	}
+}
@@ -5,7 +5,8 @@ import (
 	"io"
 )
 
-// LineTransform 实现行转换
+// LineTransform 实现行转换，将旧设备shell:输出中的CRLF还原为LF
+// 支持exec:的设备不需要转换，见Client.Exec
 type LineTransform struct {
 	savedR          []byte
 	autoDetect      bool
@@ -127,16 +128,20 @@ func NewTransformReader(reader io.Reader, options map[string]interface{}) *Trans
 
 // Read 实现io.Reader接口
 func (tr *TransformReader) Read(p []byte) (n int, err error) {
-	// 读取原始数据
-	n, err = tr.reader.Read(tr.buffer)
+	// 读取原始数据，转换后的数据不会比原始数据长，但可能带有上次保存的\r
+	size := len(p)
+	if size > 1 {
+		size--
+	}
+	n, err = tr.reader.Read(tr.buffer[:min(size, len(tr.buffer))])
 	if err != nil && err != io.EOF {
 		return 0, err
 	}
 
 	// 转换数据
-	transformed, err := tr.transform.Transform(tr.buffer[:n])
-	if err != nil {
-		return 0, err
+	transformed, terr := tr.transform.Transform(tr.buffer[:n])
+	if terr != nil {
+		return 0, terr
 	}
 
 	// 复制转换后的数据到输出buffer
@@ -0,0 +1,167 @@
+package adb
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"adb-kit-go/pkg/adb/command/host"
+)
+
+// connLog 记录单个连接上的命令、应答和流量
+// 每个连接持有自己的状态，同一进程中的多个客户端互不影响
+type connLog struct {
+	mu       sync.Mutex
+	logger   *slog.Logger
+	payloads bool // 是否以十六进制记录收发的数据
+	opened   time.Time
+	command  string    // 最近发送的命令
+	sent     time.Time // 最近一次发送命令的时间
+	pending  bool      // 已发送命令但尚未读到应答状态
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+// newConnLog 根据客户端选项创建连接日志，未设置Logger时返回nil
+func newConnLog(options *Options) *connLog {
+	if options.Logger == nil {
+		return nil
+	}
+	return &connLog{
+		logger:   options.Logger,
+		payloads: options.LogPayloads,
+		opened:   time.Now(),
+	}
+}
+
+// with 为之后的日志添加属性，如序列号和传输ID
+func (l *connLog) with(args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logger = l.logger.With(args...)
+}
+
+// current 返回当前的logger
+func (l *connLog) current() *slog.Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.logger
+}
+
+// wrap 包装socket以统计字节数，需要时记录收发的数据
+func (l *connLog) wrap(socket net.Conn) io.ReadCloser {
+	var reader io.Reader = socket
+	if l.payloads {
+		reader = NewDumpReader(socket, &connDump{log: l, message: "adb recv"})
+	}
+	return &loggedStream{Conn: socket, reader: reader, log: l}
+}
+
+// write 记录写入的数据
+func (l *connLog) write(p []byte) {
+	l.bytesOut.Add(int64(len(p)))
+	if l.payloads {
+		l.current().Debug("adb send", "bytes", len(p), "hex", hex.Dump(p))
+	}
+}
+
+// send 记录发送的命令
+func (l *connLog) send(cmd string) {
+	l.mu.Lock()
+	l.command = cmd
+	l.sent = time.Now()
+	l.pending = true
+	logger := l.logger
+	l.mu.Unlock()
+
+	logger.Debug("adb command", "command", cmd)
+}
+
+// reply 记录命令的应答状态，只记录命令发送后的第一个OKAY或FAIL
+func (l *connLog) reply(status string) {
+	l.mu.Lock()
+	if !l.pending || (status != OKAY && status != FAIL) {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = false
+	logger, command, sent := l.logger, l.command, l.sent
+	l.mu.Unlock()
+
+	level := slog.LevelDebug
+	if status == FAIL {
+		level = slog.LevelWarn
+	}
+	logger.Log(context.Background(), level, "adb reply",
+		"command", command,
+		"status", status,
+		"duration", time.Since(sent))
+}
+
+// close 记录连接关闭及其流量
+func (l *connLog) close() {
+	l.mu.Lock()
+	logger, command := l.logger, l.command
+	l.mu.Unlock()
+
+	logger.Debug("adb connection closed",
+		"command", command,
+		"bytes_sent", l.bytesOut.Load(),
+		"bytes_received", l.bytesIn.Load(),
+		"duration", time.Since(l.opened))
+}
+
+// loggedStream 统计从socket读取的字节数
+type loggedStream struct {
+	net.Conn
+	reader io.Reader
+	log    *connLog
+}
+
+// Read 实现io.Reader接口
+func (s *loggedStream) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	s.log.bytesIn.Add(int64(n))
+	return n, err
+}
+
+// connDump 以连接当前的logger记录数据
+type connDump struct {
+	log     *connLog
+	message string
+}
+
+// Write 实现io.Writer接口
+func (d *connDump) Write(p []byte) (int, error) {
+	d.log.current().Debug(d.message, "bytes", len(p), "hex", hex.Dump(p))
+	return len(p), nil
+}
+
+// transportAttrs 返回描述传输的日志属性，按序列号选择设备时加上设备列表中的传输ID
+// 没有开启TrackDeviceList时传输ID未知，只记录序列号
+func (c *Client) transportAttrs(ctx context.Context, selector host.Selector) []any {
+	attrs := selectorAttrs(selector)
+	if selector.Kind() == "serial" {
+		if id := c.trackedTransportID(ctx, selector.Serial()); id != 0 {
+			attrs = append(attrs, "transport_id", id)
+		}
+	}
+	return attrs
+}
+
+// selectorAttrs 返回描述设备选择方式的日志属性
+func selectorAttrs(selector host.Selector) []any {
+	switch selector.Kind() {
+	case "serial":
+		return []any{"serial", selector.Serial()}
+	case "transport-id":
+		return []any{"transport_id", selector.TransportID()}
+	default:
+		return []any{"selector", selector.String()}
+	}
+}
@@ -0,0 +1,33 @@
+package adb_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"adb-kit-go/pkg/adb"
+)
+
+// TestLogTransportIDForSerial 按序列号打开的传输也记录设备列表中的传输ID
+func TestLogTransportIDForSerial(t *testing.T) {
+	server := newServer(t)
+	device := server.AddDevice("a")
+	device.OnShell("echo hi", "hi\n")
+
+	var logs bytes.Buffer
+	options := server.Options()
+	options.TrackDeviceList = true
+	options.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := adb.NewClient(options)
+	defer client.Close()
+
+	if _, err := client.Shell("a", "echo hi"); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("serial=a transport_id=%d", device.TransportID())
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("logs do not contain %q:\n%s", want, logs.String())
+	}
+}
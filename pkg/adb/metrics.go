@@ -0,0 +1,133 @@
+package adb
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"adb-kit-go/pkg/adb/adberr"
+	"adb-kit-go/pkg/adb/metrics"
+)
+
+// Metrics 客户端指标接口，见 metrics.Metrics
+type Metrics = metrics.Metrics
+
+// NewMetricsRegistry 创建内置的指标集合，可导出为Prometheus文本格式或发布到expvar
+func NewMetricsRegistry() *metrics.Registry {
+	return metrics.NewRegistry()
+}
+
+// 连接上命令的状态
+const (
+	commandIdle        = iota // 没有等待应答的命令
+	commandAwaitReply         // 已发送命令，等待OKAY或FAIL
+	commandAwaitReason        // 收到FAIL，等待失败原因
+)
+
+// connMetrics 统计单个连接上命令的耗时和失败，以及连接作为设备传输的存续
+type connMetrics struct {
+	mu        sync.Mutex
+	metrics   Metrics
+	service   string    // 最近发送命令的服务类型
+	sent      time.Time // 最近一次发送命令的时间
+	state     int
+	transport bool // 连接已切换为设备传输，关闭时需要减少计数
+}
+
+// newConnMetrics 根据客户端选项创建连接指标，未设置Metrics时返回nil
+func newConnMetrics(options *Options) *connMetrics {
+	if options.Metrics == nil {
+		return nil
+	}
+	return &connMetrics{metrics: options.Metrics}
+}
+
+// send 记录发送的命令
+func (m *connMetrics) send(cmd string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.service = metrics.ServiceType(cmd)
+	m.sent = time.Now()
+	m.state = commandAwaitReply
+}
+
+// reply 记录命令的应答状态，FAIL的原因在之后读取
+func (m *connMetrics) reply(status string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state != commandAwaitReply {
+		return
+	}
+
+	switch {
+	case err != nil:
+		m.finish(err)
+	case status == "FAIL":
+		m.state = commandAwaitReason
+	default:
+		m.finish(nil)
+	}
+}
+
+// reason 记录FAIL应答的原因
+func (m *connMetrics) reason(message string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state != commandAwaitReason {
+		return
+	}
+
+	if err != nil {
+		m.finish(err)
+		return
+	}
+	m.finish(adberr.NewFailError(message))
+}
+
+// finish 上报等待中的命令，调用方需持有锁
+func (m *connMetrics) finish(err error) {
+	m.metrics.ObserveCommand(m.service, time.Since(m.sent), err)
+	m.state = commandIdle
+}
+
+// openTransport 记录连接已切换为设备传输
+func (m *connMetrics) openTransport() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.transport {
+		m.transport = true
+		m.metrics.AddTransports(1)
+	}
+}
+
+// close 上报未收到应答的命令，并减少打开的传输数
+func (m *connMetrics) close(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch m.state {
+	case commandAwaitReply, commandAwaitReason:
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		m.finish(err)
+	}
+	if m.transport {
+		m.transport = false
+		m.metrics.AddTransports(-1)
+	}
+}
+
+// transferResult 传输结束后的字节数和错误
+type transferResult interface {
+	BytesTransferred() int64
+	Wait() error
+}
+
+// observeTransfer 在传输结束后上报传输的字节数和吞吐量
+func (s *Sync) observeTransfer(direction string, started time.Time, transfer transferResult) {
+	if m := s.conn.options.Metrics; m != nil {
+		err := transfer.Wait()
+		m.ObserveTransfer(direction, transfer.BytesTransferred(), time.Since(started), err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package metrics 统计ADB客户端的命令、传输和跟踪器指标
+//
+// 客户端通过 Metrics 接口上报指标，Registry 是内置的实现，
+// 可以导出为Prometheus文本格式或发布到expvar。
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"adb-kit-go/pkg/adb/adberr"
+)
+
+// 传输方向
+const (
+	Push = "push"
+	Pull = "pull"
+)
+
+// Metrics 指标接口，实现需要支持并发调用
+type Metrics interface {
+	// ObserveCommand 记录一次命令，err为空表示服务器应答了OKAY
+	ObserveCommand(service string, duration time.Duration, err error)
+	// ObserveTransfer 记录一次文件传输，direction为Push或Pull
+	ObserveTransfer(direction string, bytes int64, duration time.Duration, err error)
+	// AddTransports 调整当前打开的设备传输数量
+	AddTransports(delta int)
+	// AddTrackers 调整当前活动的设备跟踪器数量
+	AddTrackers(delta int)
+}
+
+// ServiceType 返回命令的服务类型，去掉序列号、路径和参数等高基数部分
+// 如 "host:transport:abc" 为 "host:transport"，"shell:ls -l" 为 "shell"，
+// "host-serial:abc:get-state" 为 "host-serial:get-state"
+func ServiceType(service string) string {
+	name, rest, found := strings.Cut(service, ":")
+	if !found {
+		return name
+	}
+
+	switch name {
+	case "host":
+		sub, _, _ := strings.Cut(rest, ":")
+		return "host:" + sub
+	case "host-serial", "host-transport-id":
+		// 序列号可能包含冒号，命令在最后一个冒号之后
+		i := strings.LastIndex(rest, ":")
+		return name + ":" + rest[i+1:]
+	case "host-usb", "host-local":
+		return name + ":" + rest
+	case "reverse":
+		sub, _, _ := strings.Cut(rest, ":")
+		return "reverse:" + sub
+	}
+	return name
+}
+
+// ErrorClass 返回错误的类别，用作失败指标的标签
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, adberr.ErrDeviceNotFound):
+		return "device_not_found"
+	case errors.Is(err, adberr.ErrDeviceOffline):
+		return "device_offline"
+	case errors.Is(err, adberr.ErrDeviceUnauthorized):
+		return "device_unauthorized"
+	case errors.Is(err, adberr.ErrMoreThanOneDevice):
+		return "more_than_one_device"
+	case errors.Is(err, adberr.ErrServerUnreachable):
+		return "server_unreachable"
+	case errors.Is(err, adberr.ErrPermissionDenied):
+		return "permission_denied"
+	case errors.Is(err, adberr.ErrUnknownService):
+		return "unknown_service"
+	case errors.Is(err, adberr.ErrInstallFailed):
+		return "install_failed"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection_closed"
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	var failErr *adberr.FailError
+	if errors.As(err, &failErr) {
+		return "fail"
+	}
+	return "other"
+}
@@ -0,0 +1,250 @@
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DurationBuckets 命令耗时直方图的桶上界，单位秒
+var DurationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30}
+
+// ThroughputBuckets 传输吞吐量直方图的桶上界，单位字节/秒
+var ThroughputBuckets = []float64{64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20, 256 << 20}
+
+// Histogram 直方图，Counts[i]为落入第i个桶的观测数，最后一个元素对应+Inf
+type Histogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Sum     float64
+	Count   uint64
+}
+
+// newHistogram 创建直方图
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{Buckets: buckets, Counts: make([]uint64, len(buckets)+1)}
+}
+
+// observe 记录一次观测
+func (h *Histogram) observe(value float64) {
+	i := sort.SearchFloat64s(h.Buckets, value)
+	h.Counts[i]++
+	h.Sum += value
+	h.Count++
+}
+
+// clone 复制直方图
+func (h *Histogram) clone() *Histogram {
+	c := *h
+	c.Counts = append([]uint64(nil), h.Counts...)
+	return &c
+}
+
+// failureKey 失败计数的标签
+type failureKey struct {
+	service string
+	class   string
+}
+
+// Registry 内存中的指标集合，实现了 Metrics 接口
+type Registry struct {
+	mu         sync.Mutex
+	commands   map[string]*Histogram
+	failures   map[failureKey]uint64
+	bytes      map[string]uint64
+	transfers  map[string]*Histogram
+	transports int64
+	trackers   int64
+}
+
+// NewRegistry 创建指标集合
+func NewRegistry() *Registry {
+	return &Registry{
+		commands:  make(map[string]*Histogram),
+		failures:  make(map[failureKey]uint64),
+		bytes:     make(map[string]uint64),
+		transfers: make(map[string]*Histogram),
+	}
+}
+
+// ObserveCommand 记录命令的耗时，失败时按错误类别计数
+func (r *Registry) ObserveCommand(service string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.commands[service]
+	if !ok {
+		h = newHistogram(DurationBuckets)
+		r.commands[service] = h
+	}
+	h.observe(duration.Seconds())
+
+	if err != nil {
+		r.failures[failureKey{service, ErrorClass(err)}]++
+	}
+}
+
+// ObserveTransfer 记录传输的字节数，成功的传输同时记录吞吐量
+func (r *Registry) ObserveTransfer(direction string, bytes int64, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bytes[direction] += uint64(bytes)
+	if err != nil || bytes == 0 || duration <= 0 {
+		return
+	}
+
+	h, ok := r.transfers[direction]
+	if !ok {
+		h = newHistogram(ThroughputBuckets)
+		r.transfers[direction] = h
+	}
+	h.observe(float64(bytes) / duration.Seconds())
+}
+
+// AddTransports 调整打开的传输数量
+func (r *Registry) AddTransports(delta int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transports += int64(delta)
+}
+
+// AddTrackers 调整活动的跟踪器数量
+func (r *Registry) AddTrackers(delta int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trackers += int64(delta)
+}
+
+// Snapshot 指标快照
+type Snapshot struct {
+	Commands   map[string]*Histogram        `json:"commands"`
+	Failures   map[string]map[string]uint64 `json:"failures"`
+	Bytes      map[string]uint64            `json:"bytes"`
+	Throughput map[string]*Histogram        `json:"throughput"`
+	Transports int64                        `json:"transports"`
+	Trackers   int64                        `json:"trackers"`
+}
+
+// Snapshot 返回当前指标的副本，Failures按服务类型和错误类别索引
+func (r *Registry) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := Snapshot{
+		Commands:   make(map[string]*Histogram, len(r.commands)),
+		Failures:   make(map[string]map[string]uint64),
+		Bytes:      make(map[string]uint64, len(r.bytes)),
+		Throughput: make(map[string]*Histogram, len(r.transfers)),
+		Transports: r.transports,
+		Trackers:   r.trackers,
+	}
+	for service, h := range r.commands {
+		s.Commands[service] = h.clone()
+	}
+	for key, n := range r.failures {
+		if s.Failures[key.service] == nil {
+			s.Failures[key.service] = make(map[string]uint64)
+		}
+		s.Failures[key.service][key.class] = n
+	}
+	for direction, n := range r.bytes {
+		s.Bytes[direction] = n
+	}
+	for direction, h := range r.transfers {
+		s.Throughput[direction] = h.clone()
+	}
+	return s
+}
+
+// Publish 以name发布到expvar，可通过/debug/vars查看
+// 与 expvar.Publish 一样，重复的name会导致panic
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return r.Snapshot() }))
+}
+
+// WritePrometheus 以Prometheus文本格式写出指标
+func (r *Registry) WritePrometheus(writer io.Writer) error {
+	s := r.Snapshot()
+	w := bufio.NewWriter(writer)
+
+	header(w, "adb_commands_total", "counter", "ADB commands issued by service type.")
+	for _, service := range sortedKeys(s.Commands) {
+		fmt.Fprintf(w, "adb_commands_total{service=%s} %d\n", quote(service), s.Commands[service].Count)
+	}
+
+	header(w, "adb_command_failures_total", "counter", "ADB commands that failed by service type and error class.")
+	for _, service := range sortedKeys(s.Failures) {
+		for _, class := range sortedKeys(s.Failures[service]) {
+			fmt.Fprintf(w, "adb_command_failures_total{service=%s,class=%s} %d\n",
+				quote(service), quote(class), s.Failures[service][class])
+		}
+	}
+
+	header(w, "adb_command_duration_seconds", "histogram", "Time from sending an ADB command to its reply.")
+	for _, service := range sortedKeys(s.Commands) {
+		writeHistogram(w, "adb_command_duration_seconds", "service="+quote(service), s.Commands[service])
+	}
+
+	header(w, "adb_transfer_bytes_total", "counter", "Bytes pushed to and pulled from devices.")
+	for _, direction := range sortedKeys(s.Bytes) {
+		fmt.Fprintf(w, "adb_transfer_bytes_total{direction=%s} %d\n", quote(direction), s.Bytes[direction])
+	}
+
+	header(w, "adb_transfer_throughput_bytes_per_second", "histogram", "Throughput of completed file transfers.")
+	for _, direction := range sortedKeys(s.Throughput) {
+		writeHistogram(w, "adb_transfer_throughput_bytes_per_second", "direction="+quote(direction), s.Throughput[direction])
+	}
+
+	header(w, "adb_open_transports", "gauge", "Device transports currently open.")
+	fmt.Fprintf(w, "adb_open_transports %d\n", s.Transports)
+
+	header(w, "adb_active_trackers", "gauge", "Device trackers currently running.")
+	fmt.Fprintf(w, "adb_active_trackers %d\n", s.Trackers)
+
+	return w.Flush()
+}
+
+// header 写出指标的HELP和TYPE行
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeHistogram 写出直方图的累计桶、总和与计数
+func writeHistogram(w io.Writer, name, labels string, h *Histogram) {
+	var cumulative uint64
+	for i, bound := range h.Buckets {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s,le=%s} %d\n", name, labels, quote(formatFloat(bound)), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.Count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.Sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.Count)
+}
+
+// quote 按Prometheus标签值的规则加引号
+func quote(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
+
+// formatFloat 格式化浮点数
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sortedKeys 返回排序后的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -43,7 +43,7 @@ func (p *Parser) ReadAll() ([]byte, error) {
 	// 读取数据直到流结束
 	_, err := io.Copy(&buffer, p.stream)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read all data: %v", err)
+		return nil, fmt.Errorf("failed to read all data: %w", err)
 	}
 
 	p.ended = true
@@ -74,7 +74,7 @@ func (p *Parser) ReadBytes(length int) ([]byte, error) {
 				MissingBytes: length - n,
 			}
 		}
-		return nil, fmt.Errorf("failed to read bytes: %v", err)
+		return nil, fmt.Errorf("failed to read bytes: %w", err)
 	}
 
 	return buffer, nil
@@ -93,7 +93,7 @@ func (p *Parser) ReadByteFlow(length int, target io.Writer) error {
 				MissingBytes: length - int(n),
 			}
 		}
-		return fmt.Errorf("failed to copy bytes: %v", err)
+		return fmt.Errorf("failed to copy bytes: %w", err)
 	}
 
 	return nil
@@ -181,35 +181,3 @@ func (p *Parser) Unexpected(data []byte, expected string) error {
 		Expected:   expected,
 	}
 }
-
-// Error 类型定义
-type (
-	// FailError 失败错误
-	FailError struct {
-		Message string
-	}
-
-	// PrematureEOFError 过早EOF错误
-	PrematureEOFError struct {
-		MissingBytes int
-	}
-
-	// UnexpectedDataError 意外数据错误
-	UnexpectedDataError struct {
-		Unexpected string
-		Expected   string
-	}
-)
-
-// Error 实现
-func (e *FailError) Error() string {
-	return fmt.Sprintf("Failure: '%s'", e.Message)
-}
-
-func (e *PrematureEOFError) Error() string {
-	return fmt.Sprintf("Premature end of stream, needed %d more bytes", e.MissingBytes)
-}
-
-func (e *UnexpectedDataError) Error() string {
-	return fmt.Sprintf("Unexpected '%s', was expecting %s", e.Unexpected, e.Expected)
-}
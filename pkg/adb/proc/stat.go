@@ -116,7 +116,7 @@ func (p *ProcStat) parse(data string) {
 			val, err := strconv.ParseUint(fields[i+1], 10, 64)
 			if err != nil {
 				if p.OnError != nil {
-					p.OnError(fmt.Errorf("解析CPU数据失败: %v", err))
+					p.OnError(fmt.Errorf("解析CPU数据失败: %w", err))
 				}
 				return
 			}
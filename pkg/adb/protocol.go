@@ -2,6 +2,7 @@ package adb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"strconv"
 )
@@ -14,8 +15,12 @@ const (
 	OKAY = "OKAY"
 	FAIL = "FAIL"
 	STAT = "STAT"
+	LST2 = "LST2" // stat_v2特性的lstat，支持64位大小和错误码
+	STA2 = "STA2" // stat_v2特性的stat，跟随符号链接
 	LIST = "LIST"
 	DENT = "DENT"
+	LIS2 = "LIS2" // ls_v2特性的LIST，目录项包含64位大小和错误码
+	DNT2 = "DNT2"
 	RECV = "RECV"
 	DATA = "DATA"
 	DONE = "DONE"
@@ -27,7 +32,7 @@ const (
 func (p *Protocol) DecodeLength(length string) (int, error) {
 	val, err := strconv.ParseInt(length, 16, 32)
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode length: %v", err)
+		return 0, fmt.Errorf("failed to decode length: %w", err)
 	}
 	return int(val), nil
 }
@@ -103,10 +108,10 @@ func (p *Protocol) ValidateResponse(response []byte, expected string) error {
 
 // FormatSync 格式化同步命令
 func (p *Protocol) FormatSync(cmd string, length int) []byte {
-	// 同步命令固定4字节命令+4字节长度
+	// 同步命令固定4字节命令+4字节小端长度
 	message := make([]byte, 8)
 	copy(message[:4], cmd)
-	copy(message[4:], []byte(fmt.Sprintf("%04x", length)))
+	binary.LittleEndian.PutUint32(message[4:], uint32(length))
 	return message
 }
 
@@ -117,10 +122,7 @@ func (p *Protocol) ParseSyncResponse(response []byte) (string, int, error) {
 	}
 
 	cmd := string(response[:4])
-	length, err := p.DecodeLength(string(response[4:8]))
-	if err != nil {
-		return "", 0, fmt.Errorf("invalid sync response length: %v", err)
-	}
+	length := int(binary.LittleEndian.Uint32(response[4:8]))
 
 	return cmd, length, nil
 }
@@ -0,0 +1,236 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 词法单元类型
+const (
+	tokenEOF = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+// token 词法单元
+type token struct {
+	kind   int
+	text   string
+	offset int
+}
+
+// String 用于错误信息
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// lexer 词法分析器
+type lexer struct {
+	input  string
+	offset int
+}
+
+// isWordByte 检查字符是否可以出现在属性名或不带引号的值中
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' ||
+		strings.IndexByte("_.-:/+*@", b) >= 0
+}
+
+// next 返回下一个词法单元
+func (l *lexer) next() (token, error) {
+	for l.offset < len(l.input) && (l.input[l.offset] == ' ' || l.input[l.offset] == '\t') {
+		l.offset++
+	}
+	start := l.offset
+	if start >= len(l.input) {
+		return token{kind: tokenEOF, offset: start}, nil
+	}
+
+	rest := l.input[start:]
+	for _, fixed := range []struct {
+		text string
+		kind int
+	}{
+		{"&&", tokenAnd}, {"||", tokenOr},
+		{"==", tokenOp}, {"!=", tokenOp}, {"!~", tokenOp}, {"<=", tokenOp}, {">=", tokenOp},
+		{"=", tokenOp}, {"~", tokenOp}, {"<", tokenOp}, {">", tokenOp},
+		{"!", tokenNot}, {"(", tokenLParen}, {")", tokenRParen},
+	} {
+		if strings.HasPrefix(rest, fixed.text) {
+			l.offset += len(fixed.text)
+			return token{kind: fixed.kind, text: fixed.text, offset: start}, nil
+		}
+	}
+
+	switch c := rest[0]; {
+	case c == '"' || c == '\'':
+		end := 1
+		for end < len(rest) && rest[end] != c {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) {
+			return token{}, &SyntaxError{Query: l.input, Offset: start, Msg: "unterminated string"}
+		}
+		text := rest[1:end]
+		if c == '"' {
+			unquoted, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return token{}, &SyntaxError{Query: l.input, Offset: start, Msg: "invalid string"}
+			}
+			text = unquoted
+		}
+		l.offset += end + 1
+		return token{kind: tokenString, text: text, offset: start}, nil
+
+	case isWordByte(c):
+		end := 0
+		for end < len(rest) && isWordByte(rest[end]) {
+			end++
+		}
+		l.offset += end
+		return token{kind: tokenWord, text: rest[:end], offset: start}, nil
+	}
+
+	return token{}, &SyntaxError{Query: l.input, Offset: start, Msg: fmt.Sprintf("unexpected character %q", rest[0])}
+}
+
+// parser 递归下降语法分析器
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | "(" or ")" | compare
+//	compare = word [ op ( word | string ) ]
+type parser struct {
+	lexer lexer
+	token token
+}
+
+// advance 读取下一个词法单元
+func (p *parser) advance() error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = t
+	return nil
+}
+
+// errorf 返回当前位置的语法错误
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Query: p.lexer.input, Offset: p.token.offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.token.kind == tokenOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.token.kind == tokenAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch p.token.kind {
+	case tokenNot:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+
+	case tokenLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.token.kind != tokenRParen {
+			return nil, p.errorf("expected ')' but found %s", p.token)
+		}
+		return inner, p.advance()
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	if p.token.kind != tokenWord {
+		return nil, p.errorf("expected attribute name but found %s", p.token)
+	}
+	key := p.token.text
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenOp {
+		return &existsNode{key}, nil
+	}
+
+	op := p.token.text
+	if op == "==" {
+		op = "="
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenWord && p.token.kind != tokenString {
+		return nil, p.errorf("expected value after '%s' but found %s", op, p.token)
+	}
+
+	n := &compareNode{key: key, op: op, value: p.token.text}
+	if op == "~" || op == "!~" {
+		re, err := regexp.Compile(n.value)
+		if err != nil {
+			return nil, p.errorf("invalid pattern: %v", err)
+		}
+		n.re = re
+	}
+	return n, p.advance()
+}
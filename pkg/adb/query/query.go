@@ -0,0 +1,203 @@
+// Package query 实现按属性选择设备的查询语言
+//
+// 查询由比较表达式通过 &&、|| 和 ! 组合而成，可用括号分组：
+//
+//	ro.build.version.sdk>=30 && model~"Pixel"
+//	state=device && abi=arm64-v8a
+//	!(product=sdk_gphone64_x86_64 || usb)
+//
+// 比较运算符有 =（或==）、!=、<、<=、>、>=、~（正则匹配）和 !~。
+// 两边都是数字时按数值比较，否则按字符串比较；
+// 单独的属性名表示该属性存在且不为空，不存在的属性与任何值比较都不成立。
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Attributes 查询时使用的设备属性
+type Attributes interface {
+	// Lookup 返回属性值，属性不存在时返回false
+	Lookup(key string) (string, bool)
+}
+
+// Map 以映射表示的设备属性
+type Map map[string]string
+
+// Lookup 实现Attributes接口
+func (m Map) Lookup(key string) (string, bool) {
+	value, ok := m[key]
+	return value, ok
+}
+
+// Query 解析后的查询
+type Query struct {
+	source string
+	root   node
+}
+
+// Parse 解析查询，空字符串匹配所有设备
+func Parse(source string) (*Query, error) {
+	p := &parser{lexer: lexer{input: source}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	q := &Query{source: source}
+	if p.token.kind == tokenEOF {
+		return q, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.token)
+	}
+	q.root = root
+	return q, nil
+}
+
+// MustParse 解析查询，失败时panic，用于常量查询
+func MustParse(source string) *Query {
+	q, err := Parse(source)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// Match 检查属性是否满足查询
+func (q *Query) Match(attrs Attributes) bool {
+	if q.root == nil {
+		return true
+	}
+	return q.root.match(attrs)
+}
+
+// Keys 返回查询引用的属性名，按出现顺序去重
+// 调用方可据此判断是否需要读取系统属性等代价较高的数据
+func (q *Query) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	if q.root != nil {
+		q.root.keys(func(key string) {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		})
+	}
+	return keys
+}
+
+// String 返回原始查询
+func (q *Query) String() string {
+	return q.source
+}
+
+// SyntaxError 查询语法错误
+type SyntaxError struct {
+	Query  string
+	Offset int // 出错位置的字节偏移
+	Msg    string
+}
+
+// Error 实现error接口
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid device query %q at offset %d: %s", e.Query, e.Offset, e.Msg)
+}
+
+// node 查询语法树的节点
+type node interface {
+	match(attrs Attributes) bool
+	keys(fn func(key string))
+}
+
+// andNode 逻辑与
+type andNode struct{ left, right node }
+
+func (n *andNode) match(attrs Attributes) bool { return n.left.match(attrs) && n.right.match(attrs) }
+func (n *andNode) keys(fn func(string))        { n.left.keys(fn); n.right.keys(fn) }
+
+// orNode 逻辑或
+type orNode struct{ left, right node }
+
+func (n *orNode) match(attrs Attributes) bool { return n.left.match(attrs) || n.right.match(attrs) }
+func (n *orNode) keys(fn func(string))        { n.left.keys(fn); n.right.keys(fn) }
+
+// notNode 逻辑非
+type notNode struct{ operand node }
+
+func (n *notNode) match(attrs Attributes) bool { return !n.operand.match(attrs) }
+func (n *notNode) keys(fn func(string))        { n.operand.keys(fn) }
+
+// existsNode 属性存在且不为空
+type existsNode struct{ key string }
+
+func (n *existsNode) match(attrs Attributes) bool {
+	value, ok := attrs.Lookup(n.key)
+	return ok && value != ""
+}
+func (n *existsNode) keys(fn func(string)) { fn(n.key) }
+
+// compareNode 比较属性和值
+type compareNode struct {
+	key   string
+	op    string
+	value string
+	re    *regexp.Regexp // 仅用于 ~ 和 !~
+}
+
+func (n *compareNode) keys(fn func(string)) { fn(n.key) }
+
+func (n *compareNode) match(attrs Attributes) bool {
+	actual, ok := attrs.Lookup(n.key)
+	if !ok {
+		return false
+	}
+
+	switch n.op {
+	case "~":
+		return n.re.MatchString(actual)
+	case "!~":
+		return !n.re.MatchString(actual)
+	}
+
+	cmp := compare(actual, n.value)
+	switch n.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// compare 比较两个值，都是数字时按数值比较
+func compare(a, b string) int {
+	x, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,127 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	attrs := Map{
+		"serial":               "emulator-5554",
+		"state":                "device",
+		"model":                "Pixel_7",
+		"abi":                  "arm64-v8a",
+		"ro.build.version.sdk": "34",
+		"usb":                  "",
+		"version":              "9.0",
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"state=device", true},
+		{"state==device", true},
+		{"state!=device", false},
+		{"state=offline", false},
+		{"missing=x", false},
+		{"missing!=x", false},
+		{"ro.build.version.sdk>=30", true},
+		{"ro.build.version.sdk>=34", true},
+		{"ro.build.version.sdk>34", false},
+		{"ro.build.version.sdk<100", true},
+		{"ro.build.version.sdk<=33", false},
+		{"version<10", true}, // 数值比较，字符串比较时"9.0">"10"
+		{"model~Pixel", true},
+		{`model~"^pixel"`, false},
+		{`model~"(?i)^pixel"`, true},
+		{"model!~Pixel", false},
+		{"serial=emulator-5554", true},
+		{`serial="emulator-5554"`, true},
+		{"serial='emulator-5554'", true},
+		{"abi=arm64-v8a && state=device", true},
+		{"abi=x86 || state=device", true},
+		{"abi=x86 || state=offline", false},
+		{"!(abi=x86)", true},
+		{"!abi=x86 && state=device", true},
+		{"model", true},
+		{"usb", false}, // 属性存在但值为空
+		{"missing", false},
+		{"!missing", true},
+		{"(abi=x86 || abi=arm64-v8a) && ro.build.version.sdk>=30", true},
+		{"abi=x86 || abi=arm64-v8a && ro.build.version.sdk<30", false}, // && 优先于 ||
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := q.Match(attrs); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		query  string
+		offset int
+	}{
+		{"state=", 6},
+		{"=device", 0},
+		{"(state=device", 13},
+		{"state=device)", 12},
+		{"state=device &&", 15},
+		{`model="Pixel`, 6},
+		{"model~\"[\"", 6},
+		{"state=device # comment", 13},
+		{"!", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse error = %v, want *SyntaxError", err)
+			}
+			if syntaxErr.Query != tt.query || syntaxErr.Offset != tt.offset {
+				t.Errorf("SyntaxError = %+v, want offset %d", syntaxErr, tt.offset)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"state=device", []string{"state"}},
+		{"model~Pixel && (state=device || !model)", []string{"model", "state"}},
+		{"usb || ro.build.version.sdk>=30", []string{"usb", "ro.build.version.sdk"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := MustParse(tt.query).Keys(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Keys = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustParsePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustParse did not panic")
+		}
+	}()
+	MustParse("(")
+}
@@ -0,0 +1,255 @@
+package adb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"adb-kit-go/pkg/adb/retry"
+)
+
+// ReadinessOptions 设备就绪检查的选项
+type ReadinessOptions struct {
+	// RequireUnlocked 要求主用户已经解锁（sys.user.0.ce_available为true），
+	// 不使用文件级加密的设备没有该属性，视为已解锁
+	RequireUnlocked bool
+	// PollInterval 检查包管理器和解锁状态以及出错后重试的间隔，默认1秒
+	PollInterval time.Duration
+}
+
+// 就绪检查默认的轮询间隔
+const defaultReadinessInterval = time.Second
+
+// pollInterval 返回轮询间隔
+func (o *ReadinessOptions) pollInterval() time.Duration {
+	if o == nil || o.PollInterval <= 0 {
+		return defaultReadinessInterval
+	}
+	return o.PollInterval
+}
+
+// WaitReady 等待设备可以使用：启动完成、包管理器可用，按选项要求用户已解锁
+func (c *Client) WaitReady(serial string, options *ReadinessOptions) error {
+	return c.WaitReadyContext(context.Background(), serial, options)
+}
+
+// WaitReadyContext 等待设备可以使用，通常需要通过上下文设置超时
+// 设备重启过程中暂时消失或离线时继续等待，未授权等无法恢复的错误直接返回
+func (c *Client) WaitReadyContext(ctx context.Context, serial string, options *ReadinessOptions) error {
+	interval := options.pollInterval()
+
+	if err := pollReady(ctx, interval, func(ctx context.Context) (bool, error) {
+		return true, c.WaitBootCompleteContext(ctx, serial)
+	}); err != nil {
+		return err
+	}
+
+	// sys.boot_completed之后system_server可能还在启动，pm在服务注册之前会失败
+	if err := pollReady(ctx, interval, func(ctx context.Context) (bool, error) {
+		response, err := c.ShellContext(ctx, serial, "pm path android")
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(response.Output, "package:"), nil
+	}); err != nil {
+		return err
+	}
+
+	if options == nil || !options.RequireUnlocked {
+		return nil
+	}
+	return pollReady(ctx, interval, func(ctx context.Context) (bool, error) {
+		properties, err := c.GetPropertiesContext(ctx, serial)
+		if err != nil {
+			return false, err
+		}
+		if available, ok := properties["sys.user.0.ce_available"]; ok {
+			return available == "true", nil
+		}
+		return properties["ro.crypto.type"] != "file", nil
+	})
+}
+
+// pollReady 反复执行check直到满足条件，暂时性的错误和设备不存在时等待后重试
+func pollReady(ctx context.Context, interval time.Duration, check func(ctx context.Context) (bool, error)) error {
+	for {
+		ok, err := check(ctx)
+		if err == nil && ok {
+			return nil
+		}
+		if err = contextError(ctx, err); err != nil && !retry.IsRetryable(err) && !errors.Is(err, ErrDeviceNotFound) {
+			return err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// ReadinessTracker 在设备跟踪器的基础上发送DeviceReady事件
+// 设备出现或状态变为device后开始检查，就绪时在同一个事件通道中发送DeviceReady，
+// 检查结束前设备消失或状态变化时放弃检查，因此DeviceReady总是对应设备当前的连接
+type ReadinessTracker struct {
+	client  *Client
+	tracker *Tracker
+	options ReadinessOptions
+	events  chan DeviceEvent
+	ready   chan readinessResult
+	ctx     context.Context
+	cancel  context.CancelFunc
+	exited  chan struct{}
+
+	// checks 正在进行的检查，只在事件循环中访问
+	checks map[string]*readinessCheck
+	wg     sync.WaitGroup
+}
+
+// readinessCheck 一台设备的就绪检查
+type readinessCheck struct {
+	device Device
+	cancel context.CancelFunc
+}
+
+// readinessResult 检查结束的结果
+type readinessResult struct {
+	check *readinessCheck
+	err   error
+}
+
+// TrackReadiness 跟踪设备变化和就绪状态
+func (c *Client) TrackReadiness(options *ReadinessOptions) (*ReadinessTracker, error) {
+	return c.TrackReadinessContext(context.Background(), options)
+}
+
+// TrackReadinessContext 跟踪设备变化和就绪状态，上下文结束时停止跟踪
+func (c *Client) TrackReadinessContext(ctx context.Context, options *ReadinessOptions) (*ReadinessTracker, error) {
+	tracker, err := c.TrackDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ReadinessTracker{
+		client:  c,
+		tracker: tracker,
+		events:  make(chan DeviceEvent, trackerEventBuffer),
+		ready:   make(chan readinessResult),
+		exited:  make(chan struct{}),
+		checks:  make(map[string]*readinessCheck),
+	}
+	if options != nil {
+		r.options = *options
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+
+	go r.run()
+
+	return r, nil
+}
+
+// Events 返回按发生顺序传递设备事件的通道，包括Tracker的全部事件和DeviceReady，跟踪结束后通道关闭
+func (r *ReadinessTracker) Events() <-chan DeviceEvent {
+	return r.events
+}
+
+// Tracker 返回底层的设备跟踪器
+func (r *ReadinessTracker) Tracker() *Tracker {
+	return r.tracker
+}
+
+// Stop 结束跟踪，取消正在进行的检查并等待它们退出，返回后Events通道已关闭
+func (r *ReadinessTracker) Stop() error {
+	r.cancel()
+	err := r.tracker.Stop()
+	<-r.exited
+	return err
+}
+
+// run 转发设备事件并根据事件启动或取消就绪检查
+func (r *ReadinessTracker) run() {
+	defer close(r.exited)
+	defer close(r.events)
+	defer r.wg.Wait()
+	defer r.cancel()
+
+	source := r.tracker.Events()
+	for {
+		select {
+		case event, ok := <-source:
+			if !ok {
+				return
+			}
+			r.handle(event)
+			if !r.send(event) {
+				return
+			}
+
+		case result := <-r.ready:
+			serial := result.check.device.ID
+			if r.checks[serial] != result.check {
+				continue
+			}
+			delete(r.checks, serial)
+			if result.err != nil {
+				if logger := r.client.options.Logger; logger != nil {
+					logger.Warn("adb readiness check failed", "serial", serial, "error", result.err)
+				}
+				continue
+			}
+			if !r.send(DeviceEvent{Type: DeviceReady, Device: result.check.device}) {
+				return
+			}
+
+		case <-r.ctx.Done():
+			r.tracker.End()
+			return
+		}
+	}
+}
+
+// handle 设备消失或连接变化时取消旧的检查，设备在线时开始新的检查
+func (r *ReadinessTracker) handle(event DeviceEvent) {
+	serial := event.Device.ID
+	if check, ok := r.checks[serial]; ok {
+		check.cancel()
+		delete(r.checks, serial)
+	}
+	if event.Type == DeviceRemoved || event.Device.State != "device" {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	check := &readinessCheck{device: event.Device, cancel: cancel}
+	r.checks[serial] = check
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+
+		err := r.client.WaitReadyContext(ctx, serial, &r.options)
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case r.ready <- readinessResult{check: check, err: err}:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// send 发送事件，跟踪结束时返回false
+func (r *ReadinessTracker) send(event DeviceEvent) bool {
+	select {
+	case r.events <- event:
+		return true
+	case <-r.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,39 @@
+package adb
+
+import (
+	"io"
+	"net"
+
+	"adb-kit-go/pkg/adb/session"
+)
+
+// recordedStream 录制服务器返回的数据，读到流结束时记录服务器关闭了连接
+type recordedStream struct {
+	io.ReadCloser
+	reader   io.Reader
+	recorder *session.ConnRecorder
+}
+
+// newRecordedStream 基于DumpReader录制从stream读取的数据
+func newRecordedStream(stream io.ReadCloser, recorder *session.ConnRecorder) *recordedStream {
+	return &recordedStream{
+		ReadCloser: stream,
+		reader:     NewDumpReader(stream, recorder.Received()),
+		recorder:   recorder,
+	}
+}
+
+// Read 实现io.Reader接口
+func (s *recordedStream) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	if err == io.EOF {
+		s.recorder.EOF()
+	}
+	return n, err
+}
+
+// record 开始录制连接，返回录制后的读取流和写入目标
+func (c *Connection) record(stream io.ReadCloser, socket net.Conn) (io.ReadCloser, io.Writer) {
+	c.recorder = c.options.Recorder.Open()
+	return newRecordedStream(stream, c.recorder), NewDumpWriter(socket, c.recorder.Sent())
+}
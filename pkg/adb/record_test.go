@@ -0,0 +1,76 @@
+package adb_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"adb-kit-go/pkg/adb"
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+	"adb-kit-go/pkg/adb/session"
+)
+
+// TestRecordReplay 录制推送文件和shell命令的会话，回放时客户端发送的数据与录制时完全相同
+func TestRecordReplay(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(local, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 固定推送文件的修改时间和哨兵，两次运行发送的数据才相同
+	deterministic := func(options *adb.Options) *adb.Options {
+		options.Clock = func() time.Time { return time.Unix(1700000000, 0) }
+		options.Sentinel = hosttransport.NewSentinelSequence()
+		return options
+	}
+	run := func(client *adb.Client) string {
+		t.Helper()
+		if err := client.Push("a", local, "/data/local/tmp/data.txt"); err != nil {
+			t.Fatalf("Push: %v", err)
+		}
+		result, err := client.RunShell("a", "echo hi")
+		if err != nil {
+			t.Fatalf("RunShell: %v", err)
+		}
+		if result.ExitCode != 0 {
+			t.Fatalf("exit code = %d, want 0", result.ExitCode)
+		}
+		return result.Stdout
+	}
+
+	server := newServer(t)
+	device := server.AddDevice("a")
+	device.SetFeatures() // 不支持shell_v2，退出码通过哨兵得到
+	device.OnShell("echo hi", "hi\n")
+
+	var recording bytes.Buffer
+	options := deterministic(server.Options())
+	options.Recorder = session.NewRecorder(&recording)
+	recorded := run(adb.NewClient(options))
+	if data, ok := device.FS.ReadFile("/data/local/tmp/data.txt"); !ok || string(data) != "hello" {
+		t.Fatalf("pushed file = %q, %v", data, ok)
+	}
+
+	s, err := session.Read(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := session.NewServer(s)
+	if err := replay.Listen(""); err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	options = deterministic(&adb.Options{Host: "127.0.0.1", Port: replay.Port(), Bin: os.DevNull})
+	if got := run(adb.NewClient(options)); got != recorded {
+		t.Errorf("replayed output = %q, recorded %q", got, recorded)
+	}
+	if err := replay.Err(); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if n := replay.Remaining(); n != 0 {
+		t.Errorf("%d recorded connections were not replayed", n)
+	}
+}
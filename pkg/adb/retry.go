@@ -0,0 +1,18 @@
+package adb
+
+import (
+	"adb-kit-go/pkg/adb/retry"
+)
+
+// RetryPolicy 重试策略，详见 retry.Policy
+type RetryPolicy = retry.Policy
+
+// DefaultRetryPolicy 返回默认的重试策略：最多尝试3次，从100ms开始指数退避
+func DefaultRetryPolicy() *RetryPolicy {
+	return retry.Default()
+}
+
+// IsRetryable 判断错误是否是可以重试的暂时性错误
+func IsRetryable(err error) bool {
+	return retry.IsRetryable(err)
+}
@@ -0,0 +1,120 @@
+// Package retry 实现ADB操作的重试与退避策略
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"adb-kit-go/pkg/adb/adberr"
+)
+
+// Policy 重试策略
+type Policy struct {
+	MaxAttempts    int                                               // 最大尝试次数（包括第一次），小于2时不重试
+	InitialBackoff time.Duration                                     // 第一次重试前的等待时间
+	MaxBackoff     time.Duration                                     // 等待时间上限，0表示不限制
+	Multiplier     float64                                           // 每次重试等待时间的增长倍数，小于1时按1处理
+	Jitter         float64                                           // 随机抖动比例，0.2表示在等待时间上下浮动20%
+	Retryable      func(err error) bool                              // 判断错误是否可以重试，为空时使用IsRetryable
+	OnRetry        func(attempt int, err error, delay time.Duration) // 每次重试前调用，可用于记录日志
+}
+
+// Default 返回默认的重试策略：最多尝试3次，从100ms开始指数退避
+func Default() *Policy {
+	return &Policy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Backoff 返回第attempt次尝试失败后的等待时间，attempt从1开始
+func (p *Policy) Backoff(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+// retryingKey 标记上下文已处于重试中，避免嵌套操作的重试次数相乘
+type retryingKey struct{}
+
+// Do 按策略执行fn，直到成功、错误不可重试、达到最大次数或上下文结束
+// 在另一个Do内部调用时只执行一次，由最外层负责重试
+func (p *Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p == nil || p.MaxAttempts < 2 || ctx.Value(retryingKey{}) != nil {
+		return fn(ctx)
+	}
+	ctx = context.WithValue(ctx, retryingKey{}, true)
+
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		delay := p.Backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// IsRetryable 判断错误是否是暂时性的：服务器不可达、设备离线、连接被重置或超时
+// 设备不存在、未授权、权限不足等错误重试也不会成功
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	switch {
+	case errors.Is(err, adberr.ErrServerUnreachable),
+		errors.Is(err, adberr.ErrDeviceOffline),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,228 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Server 在本地端口上回放录制的会话，用于在没有设备的环境中运行测试
+// 每个新连接按第一个请求匹配一个尚未回放的录制连接，之后校验客户端发送的数据并按顺序返回录制的应答
+// 推送文件的修改时间和shell哨兵每次运行都不同，录制和回放时客户端应设置同样的adb.Options.Clock和Sentinel
+type Server struct {
+	session  *Session
+	Realtime bool // 按录制时的时间间隔返回数据，默认立即返回
+	listener net.Listener
+	mu       sync.Mutex
+	used     map[int]bool
+	errs     []error
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer 创建回放服务器
+func NewServer(session *Session) *Server {
+	return &Server{
+		session: session,
+		used:    make(map[int]bool),
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen 开始监听，address为空时监听127.0.0.1上的随机端口
+func (s *Server) Listen(address string) error {
+	if address == "" {
+		address = "127.0.0.1:0"
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("监听失败: %w", err)
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+	return nil
+}
+
+// Addr 返回监听地址
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Port 返回监听端口，可直接用作adb.Options.Port
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// Close 停止监听，关闭正在回放的连接并等待其结束
+// 关闭连接导致的回放错误不会记录到Err中
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// Err 返回回放过程中发现的所有不一致，如请求不匹配或客户端发送了不同的数据
+func (s *Server) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.errs...)
+}
+
+// Remaining 返回尚未被回放的录制连接数量
+func (s *Server) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.session.Conns) - len(s.used)
+}
+
+// acceptLoop 持续接受新连接
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			if err := s.replay(conn); err != nil {
+				s.fail(err)
+			}
+		}()
+	}
+}
+
+// fail 记录一个不一致，服务器关闭后不再记录
+func (s *Server) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.errs = append(s.errs, err)
+}
+
+// replay 在连接上回放匹配的录制连接
+func (s *Server) replay(conn net.Conn) error {
+	request, err := readRequest(conn)
+	if err != nil {
+		return fmt.Errorf("read request: %w", err)
+	}
+
+	recorded := s.match(request)
+	if recorded == nil {
+		message := fmt.Sprintf("no recorded connection for %q", request)
+		fmt.Fprintf(conn, "FAIL%04x%s", len(message), message)
+		return errors.New(message)
+	}
+
+	// 跳过已经匹配的第一个请求
+	events := recorded.Events
+	for len(events) > 0 && (events[0].Kind == EventOpen || events[0].Kind == EventSend) {
+		events = events[1:]
+	}
+
+	last := time.Duration(0)
+	if len(recorded.Events) > 0 {
+		last = recorded.Events[0].Time
+	}
+	for _, event := range events {
+		switch event.Kind {
+		case EventSend:
+			data := make([]byte, len(event.Data))
+			if _, err := io.ReadFull(conn, data); err != nil {
+				return fmt.Errorf("connection %d: expected %q: %w", recorded.ID, event.Data, err)
+			}
+			if !bytes.Equal(data, event.Data) {
+				return fmt.Errorf("connection %d: expected %q, got %q", recorded.ID, event.Data, data)
+			}
+
+		case EventRecv:
+			if s.Realtime && event.Time > last {
+				time.Sleep(event.Time - last)
+			}
+			if _, err := conn.Write(event.Data); err != nil {
+				return fmt.Errorf("connection %d: write: %w", recorded.ID, err)
+			}
+
+		case EventEOF:
+			return nil
+
+		case EventClose:
+			// 等待客户端关闭连接
+			io.Copy(io.Discard, conn)
+			return nil
+		}
+		last = event.Time
+	}
+
+	return nil
+}
+
+// match 找到第一个请求相同且尚未回放的录制连接
+func (s *Server) match(request []byte) *Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.session.Conns {
+		if !s.used[conn.ID] && bytes.Equal(conn.FirstRequest(), request) {
+			s.used[conn.ID] = true
+			return conn
+		}
+	}
+	return nil
+}
+
+// readRequest 读取带4字节十六进制长度前缀的请求
+func readRequest(reader io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	length, err := strconv.ParseUint(string(header), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid length %q", header)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	return append(header, payload...), nil
+}
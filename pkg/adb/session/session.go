@@ -0,0 +1,264 @@
+// Package session 录制与回放ADB主机协议会话
+//
+// 录制文件是逐行的文本格式，便于比较和提交到代码库:
+//
+//	# adbkit session v1
+//	1 0.000 open
+//	1 0.001 send "0012host:transport:abc"
+//	1 0.002 recv "OKAY"
+//	1 0.010 eof
+//
+// 每行依次是连接编号、相对会话开始的秒数、事件类型和Go语法引用的数据。
+// send是客户端发给服务器的数据，recv是服务器返回的数据，
+// eof表示服务器关闭了连接，close表示客户端关闭了连接。
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header 录制文件的第一行
+const Header = "# adbkit session v1"
+
+// 事件类型
+const (
+	EventOpen  = "open"
+	EventSend  = "send"
+	EventRecv  = "recv"
+	EventEOF   = "eof"
+	EventClose = "close"
+)
+
+// lineLength 单行记录的最大数据长度，较长的数据拆分为多行
+const lineLength = 64
+
+// Event 会话中的一个事件
+type Event struct {
+	Conn int
+	Time time.Duration // 相对会话开始的时间
+	Kind string
+	Data []byte
+}
+
+// Conn 一个连接上的全部事件
+type Conn struct {
+	ID     int
+	Events []Event
+}
+
+// FirstRequest 返回连接上客户端发送的第一个请求
+func (c *Conn) FirstRequest() []byte {
+	var data []byte
+	for _, event := range c.Events {
+		switch event.Kind {
+		case EventOpen:
+		case EventSend:
+			data = append(data, event.Data...)
+		default:
+			return data
+		}
+	}
+	return data
+}
+
+// Session 录制的会话
+type Session struct {
+	Conns []*Conn
+}
+
+// Recorder 将会话写入录制文件，可以被多个连接同时使用
+type Recorder struct {
+	mu     sync.Mutex
+	writer io.Writer
+	start  time.Time
+	next   int
+	err    error
+}
+
+// NewRecorder 创建录制器并写入文件头
+func NewRecorder(writer io.Writer) *Recorder {
+	r := &Recorder{
+		writer: writer,
+		start:  time.Now(),
+	}
+	_, r.err = fmt.Fprintln(writer, Header)
+	return r
+}
+
+// Open 开始记录一个新连接
+func (r *Recorder) Open() *ConnRecorder {
+	r.mu.Lock()
+	r.next++
+	id := r.next
+	r.mu.Unlock()
+
+	c := &ConnRecorder{recorder: r, id: id}
+	r.write(id, EventOpen, nil)
+	return c
+}
+
+// Err 返回写入录制文件时的第一个错误
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// write 写入一个事件，数据过长时拆分为多行
+func (r *Recorder) write(conn int, kind string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+
+	if data == nil {
+		_, r.err = fmt.Fprintf(r.writer, "%d %.3f %s\n", conn, elapsed, kind)
+		return
+	}
+	for len(data) > 0 && r.err == nil {
+		chunk := data
+		if len(chunk) > lineLength {
+			chunk = chunk[:lineLength]
+		}
+		data = data[len(chunk):]
+		_, r.err = fmt.Fprintf(r.writer, "%d %.3f %s %s\n", conn, elapsed, kind, strconv.Quote(string(chunk)))
+	}
+}
+
+// ConnRecorder 记录单个连接的事件
+type ConnRecorder struct {
+	recorder *Recorder
+	id       int
+	once     sync.Once
+}
+
+// ID 返回连接编号
+func (c *ConnRecorder) ID() int {
+	return c.id
+}
+
+// Sent 返回记录客户端发送数据的Writer
+func (c *ConnRecorder) Sent() io.Writer {
+	return &eventWriter{conn: c, kind: EventSend}
+}
+
+// Received 返回记录服务器返回数据的Writer
+func (c *ConnRecorder) Received() io.Writer {
+	return &eventWriter{conn: c, kind: EventRecv}
+}
+
+// EOF 记录服务器关闭了连接
+func (c *ConnRecorder) EOF() {
+	c.once.Do(func() { c.recorder.write(c.id, EventEOF, nil) })
+}
+
+// Close 记录客户端关闭了连接
+func (c *ConnRecorder) Close() {
+	c.once.Do(func() { c.recorder.write(c.id, EventClose, nil) })
+}
+
+// eventWriter 将写入的数据记录为指定类型的事件
+type eventWriter struct {
+	conn *ConnRecorder
+	kind string
+}
+
+// Write 实现io.Writer接口
+func (w *eventWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.conn.recorder.write(w.conn.id, w.kind, p)
+	}
+	return len(p), nil
+}
+
+// Read 读取录制文件
+func Read(reader io.Reader) (*Session, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	session := &Session{}
+	conns := make(map[int]*Conn)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			if text != Header {
+				return nil, fmt.Errorf("unsupported session format: %q", text)
+			}
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		event, err := parseEvent(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		conn, ok := conns[event.Conn]
+		if !ok {
+			conn = &Conn{ID: event.Conn}
+			conns[event.Conn] = conn
+			session.Conns = append(session.Conns, conn)
+		}
+		conn.Events = append(conn.Events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, fmt.Errorf("empty session")
+	}
+
+	return session, nil
+}
+
+// parseEvent 解析一行事件
+func parseEvent(text string) (Event, error) {
+	fields := strings.SplitN(text, " ", 4)
+	if len(fields) < 3 {
+		return Event{}, fmt.Errorf("invalid event %q", text)
+	}
+
+	conn, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Event{}, fmt.Errorf("invalid connection id %q", fields[0])
+	}
+	seconds, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return Event{}, fmt.Errorf("invalid time %q", fields[1])
+	}
+
+	event := Event{
+		Conn: conn,
+		Time: time.Duration(seconds * float64(time.Second)),
+		Kind: fields[2],
+	}
+	switch event.Kind {
+	case EventSend, EventRecv:
+		if len(fields) < 4 {
+			return Event{}, fmt.Errorf("missing data for %s", event.Kind)
+		}
+		data, err := strconv.Unquote(fields[3])
+		if err != nil {
+			return Event{}, fmt.Errorf("invalid data %s: %w", fields[3], err)
+		}
+		event.Data = []byte(data)
+	case EventOpen, EventEOF, EventClose:
+	default:
+		return Event{}, fmt.Errorf("unknown event %q", event.Kind)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,392 @@
+package adb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+	"adb-kit-go/pkg/adb/shellproto"
+)
+
+// ExitCodeUnknown 无法得到退出码时ShellResult.ExitCode的值
+const ExitCodeUnknown = -1
+
+// killTimeout 上下文结束后杀死设备上的命令的超时时间
+const killTimeout = 5 * time.Second
+
+// ShellResult 分别保存stdout和stderr的shell命令结果
+type ShellResult struct {
+	Stdout string
+	Stderr string
+	// ExitCode 命令的退出码，设备不支持shell_v2时stderr合并在Stdout中，退出码通过哨兵得到
+	ExitCode int
+}
+
+// RunShell 执行Shell命令并等待结束，返回输出和退出码，设备支持shell_v2时分别返回stdout和stderr
+func (c *Client) RunShell(serial string, command string) (*ShellResult, error) {
+	return c.RunShellContext(context.Background(), serial, command)
+}
+
+// RunShellContext 执行Shell命令并等待结束，上下文结束（如超时）时结束设备上的命令并关闭传输
+// 命令以非零退出码结束不是错误，由调用方检查ExitCode
+func (c *Client) RunShellContext(ctx context.Context, serial string, command string) (*ShellResult, error) {
+	stream, err := c.OpenShellContext(ctx, serial, command)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var stdout, stderr bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		io.Copy(&stderr, stream.Stderr())
+	}()
+	_, copyErr := io.Copy(&stdout, stream.Stdout())
+	wg.Wait()
+
+	exitCode, err := stream.Wait()
+	if err == nil {
+		err = copyErr
+	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	return &ShellResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: exitCode,
+	}, nil
+}
+
+// OpenShell 执行Shell命令并返回其输入输出流
+func (c *Client) OpenShell(serial string, command string) (*ShellStream, error) {
+	return c.OpenShellContext(context.Background(), serial, command)
+}
+
+// OpenShellContext 执行Shell命令并返回其输入输出流，上下文结束时结束设备上的命令，关闭流时断开传输
+// 服务器和设备都支持shell_v2时使用shell,v2,raw:；否则退回到shell:，
+// 命令被包装为先输出进程ID、退出时输出退出码的哨兵行，哨兵行不会出现在Stdout中
+func (c *Client) OpenShellContext(ctx context.Context, serial string, command string) (*ShellStream, error) {
+	return c.openShell(ctx, serial, false, func(conn *Connection, v2 bool) (*hosttransport.ExitCodeReader, error) {
+		if v2 {
+			return nil, hosttransport.NewShellV2Command(conn.Send, conn.ReadString).Execute(command)
+		}
+		return hosttransport.NewShellCommand(conn.Send, conn.ReadString).WithStream(conn).WithSentinel(conn.options.sentinel()).ExecuteWithExitCode(command)
+	})
+}
+
+// PtyOptions 交互式shell的选项
+type PtyOptions struct {
+	// Term 设备上的TERM环境变量，如xterm-256color，为空时使用设备的默认值
+	Term string
+	// Rows、Cols、XPixels、YPixels 初始的窗口大小，Rows或Cols为0时不设置
+	Rows, Cols       int
+	XPixels, YPixels int
+}
+
+// OpenPtyShell 在设备的PTY中执行命令，command为空时启动交互式shell
+func (c *Client) OpenPtyShell(serial string, command string, options *PtyOptions) (*ShellStream, error) {
+	return c.OpenPtyShellContext(context.Background(), serial, command, options)
+}
+
+// OpenPtyShellContext 在设备的PTY中执行命令，上下文结束或关闭流时断开传输
+// 服务器和设备都支持shell_v2时使用shell,v2,pty:，可以调整窗口大小并得到退出码；
+// 否则退回到shell:，旧设备只在command为空时分配PTY
+// PTY的输出全部通过Stdout读取，Stderr总是为空
+func (c *Client) OpenPtyShellContext(ctx context.Context, serial string, command string, options *PtyOptions) (*ShellStream, error) {
+	if options == nil {
+		options = &PtyOptions{}
+	}
+
+	stream, err := c.openShell(ctx, serial, true, func(conn *Connection, v2 bool) (*hosttransport.ExitCodeReader, error) {
+		if v2 {
+			return nil, hosttransport.NewShellV2Command(conn.Send, conn.ReadString).ExecutePty(command, options.Term)
+		}
+		return nil, sendShellV1(conn, command)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if stream.v2 && options.Rows > 0 && options.Cols > 0 {
+		if err := stream.Resize(options.Rows, options.Cols, options.XPixels, options.YPixels); err != nil {
+			stream.Close()
+			return nil, contextError(ctx, err)
+		}
+	}
+	return stream, nil
+}
+
+// openShell 打开设备传输并通过open启动shell，v2表示服务器和设备是否都支持shell_v2
+// 通过哨兵执行命令时open返回读取器，用于得到退出码和进程ID
+func (c *Client) openShell(ctx context.Context, serial string, pty bool, open func(conn *Connection, v2 bool) (*hosttransport.ExitCodeReader, error)) (*ShellStream, error) {
+	v2 := c.supports(ctx, serial, FeatureShell2)
+
+	transport, err := c.TransportContext(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := transport.conn.Watch(ctx)
+	exitReader, err := open(transport.conn, v2)
+	if err != nil {
+		stop()
+		transport.Close()
+		return nil, contextError(ctx, err)
+	}
+
+	stream := newShellStream(transport.conn, stop, v2, exitReader)
+	stream.pty = pty
+	if stream.pid > 0 {
+		stream.kill = func() error {
+			// 原来的传输可能已经关闭，使用新的传输
+			ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+			defer cancel()
+			_, err := c.ShellContext(ctx, serial, killCommand(stream.pid))
+			return err
+		}
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-stream.done:
+			}
+			// 上下文结束时连接被关闭，流也会随之结束，需要再次检查上下文
+			if ctx.Err() != nil {
+				stream.Kill()
+			}
+		}()
+	}
+	return stream, nil
+}
+
+// killCommand 返回杀死shell进程及其子进程的命令
+// shell是进程组长时杀死整个进程组；否则先暂停shell以免它继续执行后续命令，再杀死子进程和shell本身
+func killCommand(pid int) string {
+	return fmt.Sprintf("kill -9 -%[1]d 2>/dev/null || { kill -STOP %[1]d; pkill -9 -P %[1]d; kill -9 %[1]d; } 2>/dev/null", pid)
+}
+
+// sendShellV1 发送shell:服务并读取应答
+func sendShellV1(conn *Connection, command string) error {
+	if err := conn.Send("shell:" + command); err != nil {
+		return err
+	}
+	return readStatus(conn)
+}
+
+// ShellStream 正在执行的Shell命令
+// Stdout和Stderr需要同时读取（或只读取不再关心的一方直到EOF），否则其中一方的数据积压会阻塞另一方
+type ShellStream struct {
+	conn    *Connection
+	stop    func()
+	v2      bool
+	pty     bool
+	pid     int          // shell协议v1时命令的进程ID，未知时为0
+	kill    func() error // 杀死设备上的命令，pid未知时为空
+	stdout  *io.PipeReader
+	stderr  *io.PipeReader
+	writeMu sync.Mutex
+	done    chan struct{}
+	exit    int
+	err     error
+	once    sync.Once
+}
+
+// newShellStream 创建Shell流并开始读取连接，exitReader不为空时通过它读取输出和退出码
+func newShellStream(conn *Connection, stop func(), v2 bool, exitReader *hosttransport.ExitCodeReader) *ShellStream {
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	s := &ShellStream{
+		conn:   conn,
+		stop:   stop,
+		v2:     v2,
+		stdout: stdoutR,
+		stderr: stderrR,
+		done:   make(chan struct{}),
+		exit:   ExitCodeUnknown,
+	}
+	if exitReader != nil {
+		s.pid = exitReader.Pid()
+	}
+
+	go func() {
+		defer close(s.done)
+		switch {
+		case v2:
+			s.exit, s.err = s.demux(stdoutW, stderrW)
+		case exitReader != nil:
+			if _, s.err = io.Copy(stdoutW, exitReader); s.err == nil {
+				s.exit, s.err = exitReader.ExitCode()
+			}
+		default:
+			_, s.err = io.Copy(stdoutW, conn)
+		}
+		if s.err != nil {
+			if ctxErr := conn.Err(); ctxErr != nil {
+				s.err = ctxErr
+			}
+		}
+		stdoutW.CloseWithError(s.err)
+		stderrW.CloseWithError(s.err)
+	}()
+
+	return s
+}
+
+// demux 将数据包分发到stdout和stderr，返回Exit包中的退出码
+func (s *ShellStream) demux(stdout, stderr io.Writer) (int, error) {
+	for {
+		packet, err := shellproto.ReadPacket(s.conn)
+		if err != nil {
+			if err == io.EOF {
+				// 设备在发送Exit之前关闭了连接，如命令被杀死或设备断开
+				err = io.ErrUnexpectedEOF
+			}
+			return ExitCodeUnknown, err
+		}
+
+		switch packet.ID {
+		case shellproto.IDStdout:
+			if _, err := stdout.Write(packet.Data); err != nil {
+				return ExitCodeUnknown, err
+			}
+		case shellproto.IDStderr:
+			if _, err := stderr.Write(packet.Data); err != nil {
+				return ExitCodeUnknown, err
+			}
+		case shellproto.IDExit:
+			return shellproto.ExitCode(packet)
+		}
+	}
+}
+
+// V2 返回是否使用shell协议v2，为false时Stderr总是为空，PTY的退出码未知
+func (s *ShellStream) V2() bool {
+	return s.v2
+}
+
+// Stdout 返回命令的标准输出，v1和PTY时包含标准错误
+func (s *ShellStream) Stdout() io.Reader {
+	return s.stdout
+}
+
+// Stderr 返回命令的标准错误
+func (s *ShellStream) Stderr() io.Reader {
+	return s.stderr
+}
+
+// Write 写入命令的标准输入
+func (s *ShellStream) Write(p []byte) (int, error) {
+	if !s.v2 {
+		return s.conn.Write(p)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := shellproto.WritePacket(s.conn, shellproto.IDStdin, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// CloseStdin 关闭命令的标准输入，命令读到EOF，只有shell协议v2支持
+func (s *ShellStream) CloseStdin() error {
+	if !s.v2 {
+		return errors.ErrUnsupported
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return shellproto.WritePacket(s.conn, shellproto.IDCloseStdin, nil)
+}
+
+// Resize 通知设备终端窗口大小变化，只有shell协议v2的PTY支持
+func (s *ShellStream) Resize(rows, cols, xpixels, ypixels int) error {
+	if !s.v2 || !s.pty {
+		return errors.ErrUnsupported
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return shellproto.WritePacket(s.conn, shellproto.IDWindowSize, shellproto.WindowSize(rows, cols, xpixels, ypixels))
+}
+
+// Signal 将信号转发给设备上的命令
+// shell协议没有信号数据包：PTY中SIGINT和SIGQUIT通过写入对应的控制字符由设备的终端产生，
+// SIGHUP和SIGTERM通过关闭连接使设备向命令发送SIGHUP，SIGKILL见Kill，其他信号不支持
+func (s *ShellStream) Signal(sig os.Signal) error {
+	switch sig {
+	case syscall.SIGKILL:
+		return s.Kill()
+	case syscall.SIGHUP, syscall.SIGTERM:
+		return s.Close()
+	}
+	if !s.pty {
+		return errors.ErrUnsupported
+	}
+
+	switch sig {
+	case os.Interrupt:
+		_, err := s.Write([]byte{0x03}) // Ctrl-C
+		return err
+	case syscall.SIGQUIT:
+		_, err := s.Write([]byte{0x1c}) // Ctrl-\
+		return err
+	}
+	return errors.ErrUnsupported
+}
+
+// Kill 杀死设备上的命令及其子进程并关闭流
+// shell协议v1关闭连接后命令会继续在设备上运行，因此通过新的传输按记录的进程ID杀死命令；
+// shell协议v2关闭连接时设备会向命令发送SIGHUP，只需要关闭流
+func (s *ShellStream) Kill() error {
+	exited := false
+	select {
+	case <-s.done:
+		// 读取到退出码说明命令已经结束，进程ID可能已被重用；连接断开时命令可能仍在运行
+		exited = s.err == nil
+	default:
+	}
+
+	var err error
+	if !exited && s.kill != nil {
+		err = s.kill()
+	}
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Pid 返回通过哨兵得到的命令进程ID，shell协议v2和PTY时返回0
+func (s *ShellStream) Pid() int {
+	return s.pid
+}
+
+// Wait 等待命令结束并返回退出码，退出码未知时返回ExitCodeUnknown
+// 输出没有被读取完时会一直等待
+func (s *ShellStream) Wait() (int, error) {
+	<-s.done
+	return s.exit, s.err
+}
+
+// Close 关闭流及其传输连接，设备上的命令收到SIGHUP
+func (s *ShellStream) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.stop()
+		err = s.conn.Close()
+		s.stdout.Close()
+		s.stderr.Close()
+	})
+	return err
+}
@@ -0,0 +1,272 @@
+// Package shellcmd 构建在设备shell上执行的命令行
+//
+// 参数、环境变量和重定向目标都按设备shell的规则引用，包名、文件路径中的空格、引号和$等字符
+// 不会被shell解释。只包含安全字符的参数保持原样，以便命令行易于阅读；
+// 命令名中含有=时仍然引用，否则shell会把它当作变量赋值。
+//
+//	cmd := shellcmd.New("pm", "install", "-r", path).Redirect("2>", "/dev/null")
+//	line, err := cmd.Build()
+package shellcmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrNulByte 参数中包含NUL字节，shell命令行无法表示
+var ErrNulByte = errors.New("shell argument contains NUL byte")
+
+// Dialect 设备shell的引用方式
+type Dialect int
+
+const (
+	// Modern Android 4.0及以上的mksh和toybox sh：使用单引号，参数中的单引号写作'\''
+	Modern Dialect = iota
+	// Legacy Android 4.0之前的sh：使用双引号，转义$、`、\和"
+	Legacy
+)
+
+// legacySDK 开始使用mksh的API级别（Android 4.0）
+const legacySDK = 14
+
+// DialectForSDK 返回API级别对应的引用方式，sdk未知（0）时返回Modern
+func DialectForSDK(sdk int) Dialect {
+	if sdk > 0 && sdk < legacySDK {
+		return Legacy
+	}
+	return Modern
+}
+
+// safeWord 不需要引用的参数
+var safeWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// envName 合法的环境变量名
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Quote 按引用方式引用一个参数，空字符串引用为空参数
+func (d Dialect) Quote(arg string) (string, error) {
+	return d.quote(arg, false)
+}
+
+// quote 引用一个参数，command为true时参数位于命令名的位置，含有=时也要引用
+func (d Dialect) quote(arg string, command bool) (string, error) {
+	if strings.IndexByte(arg, 0) >= 0 {
+		return "", fmt.Errorf("%w: %q", ErrNulByte, arg)
+	}
+	if safeWord.MatchString(arg) && !(command && strings.Contains(arg, "=")) {
+		return arg, nil
+	}
+
+	if d == Legacy {
+		return `"` + legacyReplacer.Replace(arg) + `"`, nil
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'", nil
+}
+
+// legacyReplacer 双引号中需要转义的字符
+// 按POSIX shell规范（XCU 2.2.3 Double-Quotes），双引号中只有$、`、\和"保留特殊含义，
+// 反斜杠也只在这几个字符和换行之前起转义作用，因此转义这四个字符之后其余内容都按字面解释
+var legacyReplacer = strings.NewReplacer(`\`, `\\`, `$`, `\$`, "`", "\\`", `"`, `\"`)
+
+// Quote 按Modern方式引用一个参数
+func Quote(arg string) (string, error) {
+	return Modern.Quote(arg)
+}
+
+// redirectOps 支持的重定向，值表示是否需要目标
+var redirectOps = map[string]bool{
+	"<":    true,
+	">":    true,
+	">>":   true,
+	"2>":   true,
+	"2>>":  true,
+	"2>&1": false,
+	">&2":  false,
+}
+
+// Command 一条shell命令，可以通过And、Pipe等与其他命令组合
+// 构建过程中的错误（如NUL字节、非法的环境变量名）在Build时返回
+type Command struct {
+	env       []string // 已引用的KEY=value
+	args      []string // 未引用的参数
+	raw       string   // Raw命令的脚本片段
+	redirects []redirect
+	next      []link
+	dialect   Dialect
+	err       error
+}
+
+// redirect 一个重定向
+type redirect struct {
+	op     string
+	target string
+}
+
+// link 与下一条命令的连接
+type link struct {
+	op  string
+	cmd *Command
+}
+
+// New 创建命令，name和args都会被引用
+func New(name string, args ...string) *Command {
+	return &Command{args: append([]string{name}, args...)}
+}
+
+// Raw 创建原样使用的脚本片段，如循环和条件语句，调用方负责其中的引用
+// 只应用于常量或已经构建好的命令行，不能包含来自外部的数据
+func Raw(script string) *Command {
+	c := &Command{raw: script}
+	if strings.IndexByte(script, 0) >= 0 {
+		c.err = fmt.Errorf("%w: %q", ErrNulByte, script)
+	}
+	return c
+}
+
+// Arg 追加参数
+func (c *Command) Arg(args ...string) *Command {
+	if c.raw != "" {
+		c.setErr(fmt.Errorf("cannot add arguments to raw script"))
+		return c
+	}
+	c.args = append(c.args, args...)
+	return c
+}
+
+// Env 为命令设置环境变量，写作KEY=value cmd
+func (c *Command) Env(key, value string) *Command {
+	if !envName.MatchString(key) {
+		c.setErr(fmt.Errorf("invalid environment variable name: %q", key))
+		return c
+	}
+	c.env = append(c.env, key+"="+value)
+	return c
+}
+
+// Redirect 添加重定向，op为<、>、>>、2>、2>>（需要target）或2>&1、>&2（target为空）
+func (c *Command) Redirect(op string, target string) *Command {
+	needTarget, ok := redirectOps[op]
+	switch {
+	case !ok:
+		c.setErr(fmt.Errorf("unsupported redirect: %q", op))
+	case needTarget && target == "":
+		c.setErr(fmt.Errorf("redirect %s needs a target", op))
+	case !needTarget && target != "":
+		c.setErr(fmt.Errorf("redirect %s takes no target", op))
+	default:
+		c.redirects = append(c.redirects, redirect{op: op, target: target})
+	}
+	return c
+}
+
+// And 在命令成功后执行next，写作cmd && next
+func (c *Command) And(next *Command) *Command {
+	return c.link("&&", next)
+}
+
+// Or 在命令失败后执行next，写作cmd || next
+func (c *Command) Or(next *Command) *Command {
+	return c.link("||", next)
+}
+
+// Then 在命令结束后执行next，写作cmd; next
+func (c *Command) Then(next *Command) *Command {
+	return c.link(";", next)
+}
+
+// Pipe 将命令的输出作为next的输入，写作cmd | next
+func (c *Command) Pipe(next *Command) *Command {
+	return c.link("|", next)
+}
+
+// link 连接下一条命令
+func (c *Command) link(op string, next *Command) *Command {
+	c.next = append(c.next, link{op: op, cmd: next})
+	return c
+}
+
+// Dialect 设置引用方式，默认为Modern，连接的命令使用相同的方式
+func (c *Command) Dialect(dialect Dialect) *Command {
+	c.dialect = dialect
+	return c
+}
+
+// setErr 记录第一个错误
+func (c *Command) setErr(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// Build 构建命令行
+func (c *Command) Build() (string, error) {
+	var b strings.Builder
+	if err := c.build(&b, c.dialect); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// build 按引用方式写入命令及其连接的命令
+func (c *Command) build(b *strings.Builder, dialect Dialect) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	var words []string
+	for _, env := range c.env {
+		key, value, _ := strings.Cut(env, "=")
+		quoted, err := dialect.Quote(value)
+		if err != nil {
+			return err
+		}
+		words = append(words, key+"="+quoted)
+	}
+	if c.raw != "" {
+		words = append(words, c.raw)
+	} else {
+		for i, arg := range c.args {
+			// 环境变量之后的第一个参数是命令名
+			quoted, err := dialect.quote(arg, i == 0)
+			if err != nil {
+				return err
+			}
+			words = append(words, quoted)
+		}
+	}
+	for _, r := range c.redirects {
+		if r.target == "" {
+			words = append(words, r.op)
+			continue
+		}
+		quoted, err := dialect.Quote(r.target)
+		if err != nil {
+			return err
+		}
+		words = append(words, r.op+quoted)
+	}
+	b.WriteString(strings.Join(words, " "))
+
+	for _, l := range c.next {
+		if l.op == ";" {
+			b.WriteString("; ")
+		} else {
+			b.WriteString(" " + l.op + " ")
+		}
+		if err := l.cmd.build(b, dialect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String 返回命令行，构建失败时返回错误信息，只用于日志和调试
+func (c *Command) String() string {
+	line, err := c.Build()
+	if err != nil {
+		return "<invalid command: " + err.Error() + ">"
+	}
+	return line
+}
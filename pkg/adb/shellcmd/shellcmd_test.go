@@ -0,0 +1,170 @@
+package shellcmd
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		arg    string
+		modern string
+		legacy string
+	}{
+		{"", "''", `""`},
+		{"com.example.app", "com.example.app", "com.example.app"},
+		{"/sdcard/Download/a-b_c.apk", "/sdcard/Download/a-b_c.apk", "/sdcard/Download/a-b_c.apk"},
+		{"*:I", "'*:I'", `"*:I"`},
+		{"a b", "'a b'", `"a b"`},
+		{"it's", `'it'\''s'`, `"it's"`},
+		{`$HOME`, `'$HOME'`, `"\$HOME"`},
+		{"`id`", "'`id`'", "\"\\`id\\`\""},
+		{`say "hi"`, `'say "hi"'`, `"say \"hi\""`},
+		{`back\slash`, `'back\slash'`, `"back\\slash"`},
+		{"a;b|c&d", "'a;b|c&d'", `"a;b|c&d"`},
+		{"line\nbreak", "'line\nbreak'", "\"line\nbreak\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			if got, err := Modern.Quote(tt.arg); err != nil || got != tt.modern {
+				t.Errorf("Modern.Quote = %s, %v, want %s", got, err, tt.modern)
+			}
+			if got, err := Legacy.Quote(tt.arg); err != nil || got != tt.legacy {
+				t.Errorf("Legacy.Quote = %s, %v, want %s", got, err, tt.legacy)
+			}
+			if got, err := Quote(tt.arg); err != nil || got != tt.modern {
+				t.Errorf("Quote = %s, %v, want %s", got, err, tt.modern)
+			}
+		})
+	}
+}
+
+func TestQuoteNulByte(t *testing.T) {
+	for _, dialect := range []Dialect{Modern, Legacy} {
+		if _, err := dialect.Quote("a\x00b"); !errors.Is(err, ErrNulByte) {
+			t.Errorf("Quote error = %v, want ErrNulByte", err)
+		}
+	}
+}
+
+// TestQuoteShell 通过本机的sh验证引用后的参数还原为原始字符串
+func TestQuoteShell(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	args := []string{"", "plain", "a b", "it's", `$HOME`, "`id`", `say "hi"`, `back\slash`, "a;b|c&d", "tab\there", "'", `"`, `\`, "$(id)"}
+	for _, dialect := range []Dialect{Modern, Legacy} {
+		for _, arg := range args {
+			line, err := New("printf", "%s", arg).Dialect(dialect).Build()
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			output, err := exec.Command(sh, "-c", line).Output()
+			if err != nil {
+				t.Fatalf("sh -c %s: %v", line, err)
+			}
+			if string(output) != arg {
+				t.Errorf("dialect %d: sh -c %s printed %q, want %q", dialect, line, output, arg)
+			}
+		}
+	}
+}
+
+func TestDialectForSDK(t *testing.T) {
+	tests := []struct {
+		sdk  int
+		want Dialect
+	}{
+		{0, Modern},
+		{-1, Modern},
+		{1, Legacy},
+		{10, Legacy},
+		{13, Legacy},
+		{14, Modern},
+		{34, Modern},
+	}
+
+	for _, tt := range tests {
+		if got := DialectForSDK(tt.sdk); got != tt.want {
+			t.Errorf("DialectForSDK(%d) = %d, want %d", tt.sdk, got, tt.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  *Command
+		want string
+	}{
+		{"args", New("pm", "clear", "com.example"), "pm clear com.example"},
+		{"quoted args", New("rm", "-f", "/data/local/tmp/my app.apk"), "rm -f '/data/local/tmp/my app.apk'"},
+		{"arg", New("am", "start").Arg("-a", "android.intent.action.VIEW"), "am start -a android.intent.action.VIEW"},
+		{"env", New("monkey", "-v").Env("EXTERNAL_STORAGE", "/data/local/tmp"), "EXTERNAL_STORAGE=/data/local/tmp monkey -v"},
+		{"quoted env", New("sh").Env("PS1", "$ "), "PS1='$ ' sh"},
+		{"redirect", New("logcat", "-B").Redirect("2>", "/dev/null"), "logcat -B 2>/dev/null"},
+		{"redirect without target", New("ls").Redirect("2>&1", ""), "ls 2>&1"},
+		{"quoted redirect", New("cat").Redirect("<", "/sdcard/a b"), "cat <'/sdcard/a b'"},
+		{"and", New("echo").And(New("logcat", "-c")), "echo && logcat -c"},
+		{"or", New("test", "-e", "/x").Or(New("echo", "missing")), "test -e /x || echo missing"},
+		{"then", New("cd", "/sdcard").Then(New("ls")), "cd /sdcard; ls"},
+		{"pipe", New("mount").Pipe(New("grep", "system")), "mount | grep system"},
+		{"raw", Raw("while true; do sleep 1; done"), "while true; do sleep 1; done"},
+		{"raw redirect", Raw("getprop").Redirect(">", "/dev/null"), "getprop >/dev/null"},
+		{"assignment as command", New("FOO=bar", "x"), "'FOO=bar' x"},
+		{"assignment after env", New("a=b").Env("X", "1"), "X=1 'a=b'"},
+		{"assignment as argument", New("am", "start", "--es", "k=v"), "am start --es k=v"},
+		{"legacy assignment as command", New("FOO=bar").Dialect(Legacy), `"FOO=bar"`},
+		{"legacy links", New("echo", "a b").And(New("echo", "$x")).Dialect(Legacy), `echo "a b" && echo "\$x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cmd.Build()
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Build = %s, want %s", got, tt.want)
+			}
+			if s := tt.cmd.String(); s != tt.want {
+				t.Errorf("String = %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildError(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     *Command
+		wantNul bool
+	}{
+		{"nul arg", New("cat", "a\x00b"), true},
+		{"nul env", New("sh").Env("X", "\x00"), true},
+		{"nul redirect", New("cat").Redirect(">", "\x00"), true},
+		{"nul raw", Raw("echo \x00"), true},
+		{"nul in linked", New("echo").And(New("cat", "\x00")), true},
+		{"invalid env", New("sh").Env("1X", "v"), false},
+		{"unsupported redirect", New("cat").Redirect("&>", "/dev/null"), false},
+		{"missing target", New("cat").Redirect(">", ""), false},
+		{"unexpected target", New("cat").Redirect("2>&1", "/dev/null"), false},
+		{"raw arg", Raw("ls").Arg("-l"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := tt.cmd.Build()
+			if err == nil {
+				t.Fatalf("Build = %q, want error", line)
+			}
+			if errors.Is(err, ErrNulByte) != tt.wantNul {
+				t.Errorf("Build error = %v, ErrNulByte %v", err, tt.wantNul)
+			}
+		})
+	}
+}
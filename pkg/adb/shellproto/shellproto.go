@@ -0,0 +1,78 @@
+// Package shellproto 实现shell协议v2（shell,v2:服务）的数据包编解码
+//
+// 每个数据包由1字节的类型、4字节小端长度和数据组成。
+// 与shell:服务不同，stdout和stderr分别传输，命令结束时设备发送包含退出码的Exit包。
+package shellproto
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 数据包类型
+const (
+	IDStdin      byte = 0 // 主机发送到命令标准输入的数据
+	IDStdout     byte = 1 // 命令的标准输出
+	IDStderr     byte = 2 // 命令的标准错误
+	IDExit       byte = 3 // 命令结束，数据为1字节的退出码
+	IDCloseStdin byte = 4 // 主机关闭命令的标准输入
+	IDWindowSize byte = 5 // 主机的终端窗口大小变化，只对PTY有效
+	IDInvalid    byte = 255
+)
+
+const (
+	headerLength  = 5
+	maxPacketData = 256 * 1024 // 单个数据包的最大长度，防止读取错误的长度时分配过多内存
+)
+
+// Packet 一个shell协议数据包
+type Packet struct {
+	ID   byte
+	Data []byte
+}
+
+// ReadPacket 读取一个数据包，连接在两个数据包之间关闭时返回io.EOF
+func ReadPacket(r io.Reader) (Packet, error) {
+	var header [headerLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Packet{}, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[1:])
+	if length > maxPacketData {
+		return Packet{}, fmt.Errorf("shell packet too large: %d bytes", length)
+	}
+
+	packet := Packet{ID: header[0], Data: make([]byte, length)}
+	if _, err := io.ReadFull(r, packet.Data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Packet{}, err
+	}
+	return packet, nil
+}
+
+// WritePacket 写入一个数据包
+func WritePacket(w io.Writer, id byte, data []byte) error {
+	buf := make([]byte, headerLength+len(data))
+	buf[0] = id
+	binary.LittleEndian.PutUint32(buf[1:], uint32(len(data)))
+	copy(buf[headerLength:], data)
+	_, err := w.Write(buf)
+	return err
+}
+
+// WindowSize 编码窗口大小数据包的数据，格式与adb相同：rowsxcols,xpixelsxypixels
+func WindowSize(rows, cols, xpixels, ypixels int) []byte {
+	return []byte(fmt.Sprintf("%dx%d,%dx%d\x00", rows, cols, xpixels, ypixels))
+}
+
+// ExitCode 解析Exit包中的退出码
+func ExitCode(packet Packet) (int, error) {
+	if packet.ID != IDExit || len(packet.Data) != 1 {
+		return 0, fmt.Errorf("invalid shell exit packet: id %d, %d bytes", packet.ID, len(packet.Data))
+	}
+	return int(packet.Data[0]), nil
+}
@@ -0,0 +1,131 @@
+package shellproto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestWritePacket(t *testing.T) {
+	tests := []struct {
+		name string
+		id   byte
+		data []byte
+		want []byte
+	}{
+		{"empty", IDCloseStdin, nil, []byte{4, 0, 0, 0, 0}},
+		{"stdin", IDStdin, []byte("ls\n"), []byte{0, 3, 0, 0, 0, 'l', 's', '\n'}},
+		{"exit", IDExit, []byte{42}, []byte{3, 1, 0, 0, 0, 42}},
+		{"large", IDStdout, make([]byte, 0x10203), append([]byte{1, 0x03, 0x02, 0x01, 0}, make([]byte, 0x10203)...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WritePacket(&buf, tt.id, tt.data); err != nil {
+				t.Fatalf("WritePacket: %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("WritePacket wrote % x, want % x", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    Packet
+		wantErr error
+	}{
+		{"stdout", []byte{1, 2, 0, 0, 0, 'o', 'k'}, Packet{ID: IDStdout, Data: []byte("ok")}, nil},
+		{"stderr", []byte{2, 3, 0, 0, 0, 'e', 'r', 'r'}, Packet{ID: IDStderr, Data: []byte("err")}, nil},
+		{"empty data", []byte{3, 0, 0, 0, 0}, Packet{ID: IDExit, Data: []byte{}}, nil},
+		{"eof between packets", nil, Packet{}, io.EOF},
+		{"truncated header", []byte{1, 2, 0}, Packet{}, io.ErrUnexpectedEOF},
+		{"truncated data", []byte{1, 4, 0, 0, 0, 'a'}, Packet{}, io.ErrUnexpectedEOF},
+		{"missing data", []byte{1, 4, 0, 0, 0}, Packet{}, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadPacket(bytes.NewReader(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadPacket error = %v, want %v", err, tt.wantErr)
+			}
+			if got.ID != tt.want.ID || !bytes.Equal(got.Data, tt.want.Data) {
+				t.Errorf("ReadPacket = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPacketTooLarge(t *testing.T) {
+	input := []byte{1, 0x01, 0x00, 0x04, 0x00} // 256KiB + 1
+	if _, err := ReadPacket(bytes.NewReader(input)); err == nil {
+		t.Fatal("ReadPacket accepted an oversized packet")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	packets := []Packet{
+		{ID: IDStdout, Data: []byte("hello\n")},
+		{ID: IDStderr, Data: []byte("warning\n")},
+		{ID: IDExit, Data: []byte{0}},
+	}
+	for _, packet := range packets {
+		if err := WritePacket(&buf, packet.ID, packet.Data); err != nil {
+			t.Fatalf("WritePacket: %v", err)
+		}
+	}
+
+	for _, want := range packets {
+		got, err := ReadPacket(&buf)
+		if err != nil {
+			t.Fatalf("ReadPacket: %v", err)
+		}
+		if got.ID != want.ID || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("ReadPacket = %+v, want %+v", got, want)
+		}
+	}
+	if _, err := ReadPacket(&buf); err != io.EOF {
+		t.Errorf("ReadPacket at end = %v, want io.EOF", err)
+	}
+}
+
+func TestWindowSize(t *testing.T) {
+	if got, want := string(WindowSize(24, 80, 640, 480)), "24x80,640x480\x00"; got != want {
+		t.Errorf("WindowSize = %q, want %q", got, want)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  Packet
+		want    int
+		wantErr bool
+	}{
+		{"success", Packet{ID: IDExit, Data: []byte{0}}, 0, false},
+		{"failure", Packet{ID: IDExit, Data: []byte{127}}, 127, false},
+		{"signal", Packet{ID: IDExit, Data: []byte{137}}, 137, false},
+		{"wrong id", Packet{ID: IDStdout, Data: []byte{0}}, 0, true},
+		{"empty", Packet{ID: IDExit}, 0, true},
+		{"too long", Packet{ID: IDExit, Data: []byte{0, 0}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExitCode(tt.packet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExitCode error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExitCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"adb-kit-go/pkg/adb/shellcmd"
+)
+
+// ShellSession 保持一个shell:传输的长时间运行的shell，依次执行多个命令，避免每个命令都建立传输
+// 会话中的cd、export等状态在命令之间保留；同一时刻只能执行一个操作。
+// shell:运行在PTY上，打开会话时关闭回显和提示符，之后的输出只有命令本身的输出
+type ShellSession struct {
+	conn   *Connection
+	parser *Parser
+	mu     sync.Mutex
+}
+
+// OpenShellSession 打开shell会话
+func (c *Client) OpenShellSession(serial string) (*ShellSession, error) {
+	return c.OpenShellSessionContext(context.Background(), serial)
+}
+
+// OpenShellSessionContext 打开shell会话，上下文只用于建立过程，会话需要调用Close关闭
+func (c *Client) OpenShellSessionContext(ctx context.Context, serial string) (*ShellSession, error) {
+	transport, err := c.TransportContext(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := transport.conn.Watch(ctx)
+	defer stop()
+	if err := sendShellV1(transport.conn, "sh"); err != nil {
+		transport.Close()
+		return nil, contextError(ctx, err)
+	}
+
+	// Parser逐字节读取行，加上缓冲以免每个字节都读取一次连接
+	stream := struct {
+		io.Reader
+		io.Closer
+	}{bufio.NewReader(transport.conn), transport.conn}
+
+	session := &ShellSession{
+		conn:   transport.conn,
+		parser: NewParser(stream),
+	}
+	if err := session.setup(); err != nil {
+		session.Close()
+		return nil, contextError(ctx, sessionError(err))
+	}
+	return session, nil
+}
+
+// setup 关闭PTY的回显和shell的提示符，并丢弃此前的提示符和回显，直到读到哨兵行
+func (s *ShellSession) setup() error {
+	sentinel := s.conn.options.sentinel() + ":"
+	line := fmt.Sprintf("stty -echo 2>/dev/null; PS1=; PS2=; echo %s\n", splitSentinel(sentinel))
+	if _, err := s.conn.Write([]byte(line)); err != nil {
+		return err
+	}
+
+	for {
+		line, err := s.parser.ReadLine()
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(line, []byte(sentinel)) {
+			return nil
+		}
+	}
+}
+
+// splitSentinel 在哨兵中间插入空的引号，shell输出的仍是原来的哨兵，回显的命令行中不会出现哨兵
+func splitSentinel(sentinel string) string {
+	return sentinel[:len(sentinel)/2] + "''" + sentinel[len(sentinel)/2:]
+}
+
+// Run 执行命令并等待结束，返回输出和退出码
+func (s *ShellSession) Run(command string) (*ShellResult, error) {
+	return s.RunContext(context.Background(), command)
+}
+
+// RunContext 执行命令并等待结束，stderr合并在Stdout中，行尾的CR被去掉
+// 命令的标准输入为/dev/null；命令中的exit或语法错误会结束会话，之后的操作返回错误。
+// 设备上的命令无法单独中止，上下文结束时关闭会话
+func (s *ShellSession) RunContext(ctx context.Context, command string) (*ShellResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stop := s.conn.Watch(ctx)
+	defer stop()
+
+	quoted, err := shellcmd.Quote(command)
+	if err != nil {
+		return nil, err
+	}
+	sentinel := s.conn.options.sentinel() + ":"
+	line := fmt.Sprintf("eval %s </dev/null; echo %s$?\n", quoted, splitSentinel(sentinel))
+	if _, err := s.conn.Write([]byte(line)); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	// SendLine之后没有读取的输出也会出现在结果中
+	var output bytes.Buffer
+	for {
+		line, err := s.parser.ReadLine()
+		if err != nil {
+			return nil, contextError(ctx, sessionError(err))
+		}
+
+		if i := bytes.Index(line, []byte(sentinel)); i >= 0 {
+			// 没有以换行结束的输出和哨兵在同一行
+			output.Write(line[:i])
+			code, err := strconv.Atoi(string(line[i+len(sentinel):]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid exit code: %q", line[i+len(sentinel):])
+			}
+			return &ShellResult{Stdout: output.String(), ExitCode: code}, nil
+		}
+		output.Write(line)
+		output.WriteByte('\n')
+	}
+}
+
+// SendLine 向shell写入一行，用于交互式命令；输出通过Expect或ReadLine读取
+func (s *ShellSession) SendLine(line string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.conn.Write([]byte(line + "\n"))
+	return err
+}
+
+// Expect 读取输出直到某一行匹配re，返回该行的子匹配，之前不匹配的行被丢弃
+// timeout大于0时超时返回满足 errors.Is(err, os.ErrDeadlineExceeded) 的错误，超时时读到一半的行被丢弃
+func (s *ShellSession) Expect(re *regexp.Regexp, timeout time.Duration) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timeout > 0 {
+		if err := s.conn.SetTimeout(timeout); err != nil {
+			return nil, err
+		}
+		defer s.conn.ClearTimeout()
+	}
+
+	matches, err := s.parser.SearchLine(re)
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, fmt.Errorf("等待匹配 %s 超时: %w", re, os.ErrDeadlineExceeded)
+		}
+		return nil, sessionError(err)
+	}
+	return matches, nil
+}
+
+// ReadLine 读取一行输出，行尾的CR和LF被去掉
+func (s *ShellSession) ReadLine() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := s.parser.ReadLine()
+	if err != nil {
+		return "", sessionError(err)
+	}
+	return string(line), nil
+}
+
+// Close 关闭会话，设备上的shell收到SIGHUP
+func (s *ShellSession) Close() error {
+	return s.parser.End()
+}
+
+// sessionError 将读到流结束的错误转换为会话结束的错误
+func sessionError(err error) error {
+	var eofErr *PrematureEOFError
+	if errors.As(err, &eofErr) {
+		return fmt.Errorf("shell会话已结束: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
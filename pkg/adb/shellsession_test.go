@@ -0,0 +1,60 @@
+package adb_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestShellSessionRun(t *testing.T) {
+	server := newServer(t)
+	device := server.AddDevice("a")
+	device.OnShellResult("foo bar", "out\n", "", 3)
+	device.OnShell("echo hi", "hi\n")
+
+	session, err := server.Client().OpenShellSession("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	// 会话在命令之间保持，回显和提示符不出现在输出中
+	for i := 0; i < 2; i++ {
+		result, err := session.Run("foo bar")
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if result.Stdout != "out\n" || result.ExitCode != 3 {
+			t.Errorf("Run = %q exit %d, want %q exit 3", result.Stdout, result.ExitCode, "out\n")
+		}
+	}
+	result, err := session.Run("echo hi")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Stdout != "hi\n" || result.ExitCode != 0 {
+		t.Errorf("Run = %q exit %d, want %q exit 0", result.Stdout, result.ExitCode, "hi\n")
+	}
+}
+
+func TestShellSessionExpect(t *testing.T) {
+	server := newServer(t)
+	server.AddDevice("a").OnShell("echo ready", "ready\n")
+
+	session, err := server.Client().OpenShellSession("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	if err := session.SendLine("echo ready"); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := session.Expect(regexp.MustCompile(`^(ready)$`), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expect: %v", err)
+	}
+	if matches[1] != "ready" {
+		t.Errorf("Expect = %q, want ready", matches)
+	}
+}
@@ -1,12 +1,18 @@
 package adb
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
+
+	"adb-kit-go/pkg/adb/metrics"
+	adbsync "adb-kit-go/pkg/adb/sync"
 )
 
 // Sync ADB同步管理器
@@ -14,6 +20,8 @@ type Sync struct {
 	conn     *Connection
 	parser   *Parser
 	protocol *Protocol
+	statV2   bool // 设备支持stat_v2时Stat使用LST2
+	lsV2     bool // 设备支持ls_v2时ReadDir使用LIS2
 }
 
 // 常量定义
@@ -21,6 +29,7 @@ const (
 	TEMP_PATH       = "/data/local/tmp"
 	DEFAULT_CHMOD   = 0644
 	DATA_MAX_LENGTH = 65536
+	syncNameMax     = 4096 // 目录项名称的最大长度，防止读取错误的长度时分配过多内存
 )
 
 // NewSync 创建新的同步管理器
@@ -37,8 +46,13 @@ func (s *Sync) TempFile(path string) string {
 	return filepath.Join(TEMP_PATH, filepath.Base(path))
 }
 
-// Stat 获取文件状态
-func (s *Sync) Stat(path string) (*Stats, error) {
+// Stat 获取文件状态，与lstat相同，符号链接返回链接本身的状态
+// 通过Client.SyncService打开且设备支持stat_v2时使用LST2，可以得到超过4GB的文件大小；否则使用STAT
+func (s *Sync) Stat(path string) (*adbsync.Stats, error) {
+	if s.statV2 {
+		return s.statV2Command(LST2, path)
+	}
+
 	// 发送STAT命令
 	err := s.sendCommandWithArg(STAT, path)
 	if err != nil {
@@ -67,7 +81,7 @@ func (s *Sync) Stat(path string) (*Stats, error) {
 			return nil, s.enoent(path)
 		}
 
-		return NewStats(mode, size, mtime), nil
+		return adbsync.NewStats(mode, int64(size), time.Unix(int64(mtime), 0)), nil
 
 	case FAIL:
 		return nil, s.readError()
@@ -77,36 +91,218 @@ func (s *Sync) Stat(path string) (*Stats, error) {
 	}
 }
 
+// StatFollow 获取文件状态，与stat相同，符号链接返回链接目标的状态
+// 设备支持stat_v2时使用STA2；否则STAT只能得到链接本身的状态，此时对以/结尾的路径再次STAT，
+// 链接指向目录时得到目录的状态，指向其他文件时仍返回链接本身的状态
+func (s *Sync) StatFollow(path string) (*adbsync.Stats, error) {
+	if s.statV2 {
+		return s.statV2Command(STA2, path)
+	}
+
+	stats, err := s.Stat(path)
+	if err != nil || !stats.IsSymlink() {
+		return stats, err
+	}
+	if target, err := s.Stat(strings.TrimSuffix(path, "/") + "/"); err == nil && target.IsDir() {
+		return target, nil
+	}
+	return stats, nil
+}
+
+// statV2Command 通过LST2或STA2获取文件状态
+func (s *Sync) statV2Command(id string, path string) (*adbsync.Stats, error) {
+	if err := s.sendCommandWithArg(id, path); err != nil {
+		return nil, err
+	}
+
+	reply, err := s.parser.ReadAscii(4)
+	if err != nil {
+		return nil, err
+	}
+
+	switch reply {
+	case id:
+		// error、dev、ino、mode、nlink、uid、gid、size、atime、mtime、ctime
+		statData, err := s.parser.ReadBytes(68)
+		if err != nil {
+			return nil, err
+		}
+
+		if errno := binary.LittleEndian.Uint32(statData[0:4]); errno != 0 {
+			if syscall.Errno(errno) == syscall.ENOENT {
+				return nil, s.enoent(path)
+			}
+			return nil, &os.PathError{Op: "stat", Path: path, Err: syscall.Errno(errno)}
+		}
+
+		mode := binary.LittleEndian.Uint32(statData[20:24])
+		size := binary.LittleEndian.Uint64(statData[36:44])
+		mtime := int64(binary.LittleEndian.Uint64(statData[52:60]))
+
+		return adbsync.NewStats(mode, int64(size), time.Unix(mtime, 0)), nil
+
+	case FAIL:
+		return nil, s.readError()
+
+	default:
+		return nil, s.parser.Unexpected([]byte(reply), id+" or FAIL")
+	}
+}
+
+// ReadDir 列出目录中的条目，不包含.和..，顺序与设备返回的相同
+// 通过Client.SyncService打开且设备支持ls_v2时使用LIS2，可以得到超过4GB的文件大小，
+// 设备无法获取状态的条目被跳过；否则使用LIST。
+// 与adb相同，目录不存在或不是目录时设备返回空列表，需要区分时使用Stat
+func (s *Sync) ReadDir(path string) ([]*adbsync.Entry, error) {
+	request, entryID := LIST, DENT
+	if s.lsV2 {
+		request, entryID = LIS2, DNT2
+	}
+	if err := s.sendCommandWithArg(request, path); err != nil {
+		return nil, err
+	}
+
+	var entries []*adbsync.Entry
+	for {
+		reply, err := s.parser.ReadAscii(4)
+		if err != nil {
+			return nil, err
+		}
+
+		switch reply {
+		case entryID:
+			entry, err := s.readEntry(reply == DNT2)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil && entry.Name() != "." && entry.Name() != ".." {
+				entries = append(entries, entry)
+			}
+
+		case DONE:
+			// DONE与目录项的长度相同，其余字段为0
+			length := 16
+			if s.lsV2 {
+				length = 72
+			}
+			if _, err := s.parser.ReadBytes(length); err != nil {
+				return nil, err
+			}
+			return entries, nil
+
+		case FAIL:
+			return nil, s.readError()
+
+		default:
+			return nil, s.parser.Unexpected([]byte(reply), entryID+", DONE or FAIL")
+		}
+	}
+}
+
+// readEntry 读取DENT或DNT2目录项，DNT2的错误码不为0时返回nil
+func (s *Sync) readEntry(v2 bool) (*adbsync.Entry, error) {
+	var (
+		mode, nameLength uint32
+		size             int64
+		mtime            int64
+		errno            uint32
+	)
+	if v2 {
+		// error、dev、ino、mode、nlink、uid、gid、size、atime、mtime、ctime、namelen
+		data, err := s.parser.ReadBytes(72)
+		if err != nil {
+			return nil, err
+		}
+		errno = binary.LittleEndian.Uint32(data[0:4])
+		mode = binary.LittleEndian.Uint32(data[20:24])
+		size = int64(binary.LittleEndian.Uint64(data[36:44]))
+		mtime = int64(binary.LittleEndian.Uint64(data[52:60]))
+		nameLength = binary.LittleEndian.Uint32(data[68:72])
+	} else {
+		// mode、size、mtime、namelen
+		data, err := s.parser.ReadBytes(16)
+		if err != nil {
+			return nil, err
+		}
+		mode = binary.LittleEndian.Uint32(data[0:4])
+		size = int64(binary.LittleEndian.Uint32(data[4:8]))
+		mtime = int64(binary.LittleEndian.Uint32(data[8:12]))
+		nameLength = binary.LittleEndian.Uint32(data[12:16])
+	}
+
+	if nameLength > syncNameMax {
+		return nil, fmt.Errorf("sync entry name too long: %d bytes", nameLength)
+	}
+	name, err := s.parser.ReadBytes(int(nameLength))
+	if err != nil {
+		return nil, err
+	}
+	if errno != 0 {
+		return nil, nil
+	}
+	return adbsync.NewEntry(string(name), mode, size, time.Unix(mtime, 0)), nil
+}
+
 // Push 推送文件或流到设备
-func (s *Sync) Push(src interface{}, destPath string, mode os.FileMode) (*PushTransfer, error) {
+func (s *Sync) Push(src interface{}, destPath string, mode os.FileMode) (*adbsync.PushTransfer, error) {
+	return s.PushContext(context.Background(), src, destPath, mode)
+}
+
+// PushContext 推送文件或流到设备，上下文结束时中止传输
+func (s *Sync) PushContext(ctx context.Context, src interface{}, destPath string, mode os.FileMode) (*adbsync.PushTransfer, error) {
 	if mode == 0 {
 		mode = DEFAULT_CHMOD
 	}
 
 	switch v := src.(type) {
 	case string:
-		return s.PushFile(v, destPath, mode)
+		return s.PushFileContext(ctx, v, destPath, mode)
 	case io.Reader:
-		return s.PushStream(v, destPath, mode)
+		return s.PushStreamContext(ctx, v, destPath, mode)
 	default:
 		return nil, fmt.Errorf("unsupported source type")
 	}
 }
 
 // PushFile 推送文件到设备
-func (s *Sync) PushFile(srcPath, destPath string, mode os.FileMode) (*PushTransfer, error) {
+func (s *Sync) PushFile(srcPath, destPath string, mode os.FileMode) (*adbsync.PushTransfer, error) {
+	return s.PushFileContext(context.Background(), srcPath, destPath, mode)
+}
+
+// PushFileContext 推送文件到设备，上下文结束时中止传输
+func (s *Sync) PushFileContext(ctx context.Context, srcPath, destPath string, mode os.FileMode) (*adbsync.PushTransfer, error) {
 	file, err := os.Open(srcPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.PushStream(file, destPath, mode)
+	transfer, err := s.PushStreamContext(ctx, file, destPath, mode)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	go func() {
+		<-transfer.Done()
+		file.Close()
+	}()
+
+	return transfer, nil
 }
 
 // PushStream 推送数据流到设备
-func (s *Sync) PushStream(stream io.Reader, destPath string, mode os.FileMode) (*PushTransfer, error) {
+func (s *Sync) PushStream(stream io.Reader, destPath string, mode os.FileMode) (*adbsync.PushTransfer, error) {
+	return s.PushStreamContext(context.Background(), stream, destPath, mode)
+}
+
+// PushStreamContext 推送数据流到设备，上下文结束时中止传输
+func (s *Sync) PushStreamContext(ctx context.Context, stream io.Reader, destPath string, mode os.FileMode) (*adbsync.PushTransfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// 设置文件模式
-	mode |= Stats.S_IFREG
+	mode |= adbsync.S_IFREG
 
 	// 发送SEND命令
 	err := s.sendCommandWithArg(SEND, fmt.Sprintf("%s,%d", destPath, mode))
@@ -115,16 +311,29 @@ func (s *Sync) PushStream(stream io.Reader, destPath string, mode os.FileMode) (
 	}
 
 	// 创建传输对象
-	transfer := NewPushTransfer()
+	transfer := adbsync.NewPushTransfer()
 
 	// 开始数据传输
-	go s.writeData(stream, time.Now().Unix(), transfer)
+	stop := s.watch(ctx, transfer.Done())
+	go func() {
+		defer stop()
+		s.writeData(ctx, stream, s.conn.options.now().Unix(), transfer)
+	}()
 
 	return transfer, nil
 }
 
 // Pull 从设备拉取文件
-func (s *Sync) Pull(path string) (*PullTransfer, error) {
+func (s *Sync) Pull(path string) (*adbsync.PullTransfer, error) {
+	return s.PullContext(context.Background(), path)
+}
+
+// PullContext 从设备拉取文件，上下文结束时中止传输
+func (s *Sync) PullContext(ctx context.Context, path string) (*adbsync.PullTransfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// 发送RECV命令
 	err := s.sendCommandWithArg(RECV, path)
 	if err != nil {
@@ -132,19 +341,48 @@ func (s *Sync) Pull(path string) (*PullTransfer, error) {
 	}
 
 	// 创建传输对象
-	transfer := NewPullTransfer()
+	transfer := adbsync.NewPullTransfer()
 
 	// 开始数据传输
-	go s.readData(transfer)
+	stop := s.watch(ctx, transfer.Done())
+	go func() {
+		defer stop()
+		s.readData(ctx, transfer)
+	}()
 
 	return transfer, nil
 }
 
+// watch 在上下文结束且传输未完成时关闭连接，中断阻塞的读写
+func (s *Sync) watch(ctx context.Context, done <-chan struct{}) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.conn.Close()
+		case <-done:
+		case <-stop:
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
 // writeData 写入数据到设备
-func (s *Sync) writeData(stream io.Reader, timestamp int64, transfer *PushTransfer) {
+func (s *Sync) writeData(ctx context.Context, stream io.Reader, timestamp int64, transfer *adbsync.PushTransfer) {
+	defer s.observeTransfer(metrics.Push, time.Now(), transfer)
 	buffer := make([]byte, DATA_MAX_LENGTH)
 
 	for {
+		if err := ctx.Err(); err != nil {
+			transfer.EmitError(err)
+			return
+		}
+
 		// 读取数据块
 		n, err := stream.Read(buffer)
 		if err != nil && err != io.EOF {
@@ -154,17 +392,15 @@ func (s *Sync) writeData(stream io.Reader, timestamp int64, transfer *PushTransf
 
 		if n > 0 {
 			// 发送DATA命令
-			err = s.sendCommandWithLength(DATA, n)
-			if err != nil {
-				transfer.EmitError(err)
+			if err := s.sendCommandWithLength(DATA, n); err != nil {
+				transfer.EmitError(contextError(ctx, err))
 				return
 			}
 
 			// 发送数据
 			transfer.Push(n)
-			_, err = s.conn.Write(buffer[:n])
-			if err != nil {
-				transfer.EmitError(err)
+			if _, err := s.conn.Write(buffer[:n]); err != nil {
+				transfer.EmitError(contextError(ctx, err))
 				return
 			}
 		}
@@ -177,19 +413,29 @@ func (s *Sync) writeData(stream io.Reader, timestamp int64, transfer *PushTransf
 	// 发送DONE命令
 	err := s.sendCommandWithLength(DONE, int(timestamp))
 	if err != nil {
-		transfer.EmitError(err)
+		transfer.EmitError(contextError(ctx, err))
 		return
 	}
 
 	// 等待确认
 	reply, err := s.parser.ReadAscii(4)
 	if err != nil {
-		transfer.EmitError(err)
+		transfer.EmitError(contextError(ctx, err))
 		return
 	}
 
-	if reply != OKAY {
-		transfer.EmitError(fmt.Errorf("unexpected reply: %s", reply))
+	switch reply {
+	case OKAY:
+		// 读取并忽略4字节的零长度
+		if _, err := s.parser.ReadBytes(4); err != nil {
+			transfer.EmitError(contextError(ctx, err))
+			return
+		}
+	case FAIL:
+		transfer.EmitError(s.readError())
+		return
+	default:
+		transfer.EmitError(s.parser.Unexpected([]byte(reply), "OKAY or FAIL"))
 		return
 	}
 
@@ -197,12 +443,13 @@ func (s *Sync) writeData(stream io.Reader, timestamp int64, transfer *PushTransf
 }
 
 // readData 从设备读取数据
-func (s *Sync) readData(transfer *PullTransfer) {
+func (s *Sync) readData(ctx context.Context, transfer *adbsync.PullTransfer) {
+	defer s.observeTransfer(metrics.Pull, time.Now(), transfer)
 	for {
 		// 读取命令
 		cmd, err := s.parser.ReadAscii(4)
 		if err != nil {
-			transfer.EmitError(err)
+			transfer.EmitError(contextError(ctx, err))
 			return
 		}
 
@@ -211,7 +458,7 @@ func (s *Sync) readData(transfer *PullTransfer) {
 			// 读取数据长度
 			lenData, err := s.parser.ReadBytes(4)
 			if err != nil {
-				transfer.EmitError(err)
+				transfer.EmitError(contextError(ctx, err))
 				return
 			}
 			length := binary.LittleEndian.Uint32(lenData)
@@ -219,7 +466,7 @@ func (s *Sync) readData(transfer *PullTransfer) {
 			// 读取数据
 			err = s.parser.ReadByteFlow(int(length), transfer)
 			if err != nil {
-				transfer.EmitError(err)
+				transfer.EmitError(contextError(ctx, err))
 				return
 			}
 
@@ -227,7 +474,7 @@ func (s *Sync) readData(transfer *PullTransfer) {
 			// 读取时间戳
 			_, err := s.parser.ReadBytes(4)
 			if err != nil {
-				transfer.EmitError(err)
+				transfer.EmitError(contextError(ctx, err))
 				return
 			}
 			transfer.End()
@@ -258,7 +505,22 @@ func (s *Sync) sendCommandWithArg(cmd, arg string) error {
 }
 
 func (s *Sync) readError() error {
-	return s.parser.ReadError()
+	// 同步协议的FAIL使用4字节小端长度前缀
+	lenData, err := s.parser.ReadBytes(4)
+	if err != nil {
+		return err
+	}
+	message, err := s.parser.ReadBytes(int(binary.LittleEndian.Uint32(lenData)))
+	if err != nil {
+		return err
+	}
+	return &FailError{Message: string(message)}
+}
+
+// End 结束同步会话并关闭连接
+func (s *Sync) End() error {
+	s.sendCommandWithLength(QUIT, 0)
+	return s.conn.Close()
 }
 
 func (s *Sync) enoent(path string) error {
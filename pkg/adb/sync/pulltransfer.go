@@ -2,6 +2,7 @@ package sync
 
 import (
 	"io"
+	"sync"
 )
 
 // PullTransfer 实现文件拉取传输
@@ -12,22 +13,33 @@ type PullTransfer struct {
 	reader   io.Reader
 	writer   io.Writer
 	handlers map[string][]func(interface{})
+	done     chan struct{}
+	err      error
+	once     sync.Once
+	mu       sync.Mutex
 }
 
 // NewPullTransfer 创建新的拉取传输实例
 func NewPullTransfer() *PullTransfer {
+	r, w := io.Pipe()
 	return &PullTransfer{
+		reader:   r,
+		writer:   w,
 		handlers: make(map[string][]func(interface{})),
+		done:     make(chan struct{}),
 	}
 }
 
 // Write 实现io.Writer接口
 func (t *PullTransfer) Write(p []byte) (n int, err error) {
 	// 更新传输字节数
+	t.mu.Lock()
 	t.stats.BytesTransferred += int64(len(p))
+	stats := t.stats
+	t.mu.Unlock()
 
 	// 触发进度事件
-	t.emit("progress", t.stats)
+	t.emit("progress", stats)
 
 	// 如果设置了writer，写入数据
 	if t.writer != nil {
@@ -51,6 +63,9 @@ func (t *PullTransfer) Cancel() {
 
 // On 注册事件处理器
 func (t *PullTransfer) On(event string, handler func(interface{})) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if t.handlers[event] == nil {
 		t.handlers[event] = make([]func(interface{}), 0)
 	}
@@ -59,10 +74,13 @@ func (t *PullTransfer) On(event string, handler func(interface{})) {
 
 // emit 触发事件
 func (t *PullTransfer) emit(event string, data interface{}) {
-	if handlers, ok := t.handlers[event]; ok {
-		for _, handler := range handlers {
-			handler(data)
-		}
+	t.mu.Lock()
+	handlers := make([]func(interface{}), len(t.handlers[event]))
+	copy(handlers, t.handlers[event])
+	t.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(data)
 	}
 }
 
@@ -78,10 +96,48 @@ func (t *PullTransfer) SetWriter(writer io.Writer) {
 
 // Stats 获取传输统计信息
 func (t *PullTransfer) Stats() interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.stats
 }
 
 // BytesTransferred 获取已传输字节数
 func (t *PullTransfer) BytesTransferred() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.stats.BytesTransferred
 }
+
+// End 标记传输完成，读取端将收到io.EOF
+func (t *PullTransfer) End() {
+	t.once.Do(func() {
+		if w, ok := t.writer.(*io.PipeWriter); ok {
+			w.Close()
+		}
+		close(t.done)
+		t.emit("end", nil)
+	})
+}
+
+// EmitError 以错误结束传输，读取端将收到该错误
+func (t *PullTransfer) EmitError(err error) {
+	t.once.Do(func() {
+		t.err = err
+		if w, ok := t.writer.(*io.PipeWriter); ok {
+			w.CloseWithError(err)
+		}
+		close(t.done)
+		t.emit("error", err)
+	})
+}
+
+// Done 返回传输结束时关闭的通道
+func (t *PullTransfer) Done() <-chan struct{} {
+	return t.done
+}
+
+// Wait 等待传输结束并返回传输错误
+func (t *PullTransfer) Wait() error {
+	<-t.done
+	return t.err
+}
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"io"
+	"sync"
 )
 
 // PushTransfer 实现文件推送传输
@@ -12,22 +13,30 @@ type PushTransfer struct {
 	reader   io.Reader
 	writer   io.Writer
 	handlers map[string][]func(interface{})
+	done     chan struct{}
+	err      error
+	once     sync.Once
+	mu       sync.Mutex
 }
 
 // NewPushTransfer 创建新的推送传输实例
 func NewPushTransfer() *PushTransfer {
 	return &PushTransfer{
 		handlers: make(map[string][]func(interface{})),
+		done:     make(chan struct{}),
 	}
 }
 
 // Write 实现io.Writer接口
 func (t *PushTransfer) Write(p []byte) (n int, err error) {
 	// 更新传输字节数
+	t.mu.Lock()
 	t.stats.BytesTransferred += int64(len(p))
+	stats := t.stats
+	t.mu.Unlock()
 
 	// 触发进度事件
-	t.emit("progress", t.stats)
+	t.emit("progress", stats)
 
 	// 如果设置了writer，写入数据
 	if t.writer != nil {
@@ -51,6 +60,9 @@ func (t *PushTransfer) Cancel() {
 
 // On 注册事件处理器
 func (t *PushTransfer) On(event string, handler func(interface{})) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if t.handlers[event] == nil {
 		t.handlers[event] = make([]func(interface{}), 0)
 	}
@@ -59,10 +71,13 @@ func (t *PushTransfer) On(event string, handler func(interface{})) {
 
 // emit 触发事件
 func (t *PushTransfer) emit(event string, data interface{}) {
-	if handlers, ok := t.handlers[event]; ok {
-		for _, handler := range handlers {
-			handler(data)
-		}
+	t.mu.Lock()
+	handlers := make([]func(interface{}), len(t.handlers[event]))
+	copy(handlers, t.handlers[event])
+	t.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(data)
 	}
 }
 
@@ -78,10 +93,52 @@ func (t *PushTransfer) SetWriter(writer io.Writer) {
 
 // Stats 获取传输统计信息
 func (t *PushTransfer) Stats() interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.stats
 }
 
 // BytesTransferred 获取已传输字节数
 func (t *PushTransfer) BytesTransferred() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.stats.BytesTransferred
 }
+
+// Push 记录已推送的字节数
+func (t *PushTransfer) Push(n int) {
+	t.mu.Lock()
+	t.stats.BytesTransferred += int64(n)
+	stats := t.stats
+	t.mu.Unlock()
+
+	t.emit("progress", stats)
+}
+
+// End 标记传输完成
+func (t *PushTransfer) End() {
+	t.once.Do(func() {
+		close(t.done)
+		t.emit("end", nil)
+	})
+}
+
+// EmitError 以错误结束传输
+func (t *PushTransfer) EmitError(err error) {
+	t.once.Do(func() {
+		t.err = err
+		close(t.done)
+		t.emit("error", err)
+	})
+}
+
+// Done 返回传输结束时关闭的通道
+func (t *PushTransfer) Done() <-chan struct{} {
+	return t.done
+}
+
+// Wait 等待传输结束并返回传输错误
+func (t *PushTransfer) Wait() error {
+	<-t.done
+	return t.err
+}
@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"io/fs"
 	"time"
 )
 
@@ -150,3 +151,32 @@ func (s *Stats) IsFile() bool {
 func (s *Stats) Permissions() uint32 {
 	return s.mode & 0x1FF
 }
+
+// FileMode 将文件模式转换为fs.FileMode，类型位和setuid、setgid、sticky位转换为对应的标志
+func (s *Stats) FileMode() fs.FileMode {
+	mode := fs.FileMode(s.mode & 0o777)
+	switch s.mode & S_IFMT {
+	case S_IFDIR:
+		mode |= fs.ModeDir
+	case S_IFLNK:
+		mode |= fs.ModeSymlink
+	case S_IFIFO:
+		mode |= fs.ModeNamedPipe
+	case S_IFSOCK:
+		mode |= fs.ModeSocket
+	case S_IFBLK:
+		mode |= fs.ModeDevice
+	case S_IFCHR:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	}
+	if s.mode&S_ISUID != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if s.mode&S_ISGID != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if s.mode&S_ISVTX != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
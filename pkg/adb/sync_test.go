@@ -0,0 +1,180 @@
+package adb_test
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"adb-kit-go/pkg/adb/adbtest"
+)
+
+// dent 构造LIST返回的DENT目录项
+func dent(name string, mode, size, mtime uint32) []byte {
+	buf := make([]byte, 20, 20+len(name))
+	copy(buf, "DENT")
+	binary.LittleEndian.PutUint32(buf[4:], mode)
+	binary.LittleEndian.PutUint32(buf[8:], size)
+	binary.LittleEndian.PutUint32(buf[12:], mtime)
+	binary.LittleEndian.PutUint32(buf[16:], uint32(len(name)))
+	return append(buf, name...)
+}
+
+// dnt2 构造LIS2返回的DNT2目录项
+func dnt2(name string, errno, mode uint32, size uint64, mtime int64) []byte {
+	buf := make([]byte, 76, 76+len(name))
+	copy(buf, "DNT2")
+	binary.LittleEndian.PutUint32(buf[4:], errno)
+	binary.LittleEndian.PutUint32(buf[24:], mode)
+	binary.LittleEndian.PutUint64(buf[40:], size)
+	binary.LittleEndian.PutUint64(buf[56:], uint64(mtime))
+	binary.LittleEndian.PutUint32(buf[72:], uint32(len(name)))
+	return append(buf, name...)
+}
+
+// syncDone 构造与目录项等长的DONE
+func syncDone(v2 bool) []byte {
+	buf := make([]byte, 20)
+	if v2 {
+		buf = make([]byte, 76)
+	}
+	copy(buf, "DONE")
+	return buf
+}
+
+func syncFail(message string) []byte {
+	buf := make([]byte, 8, 8+len(message))
+	copy(buf, "FAIL")
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(message)))
+	return append(buf, message...)
+}
+
+func join(parts ...[]byte) []byte {
+	var buf []byte
+	for _, part := range parts {
+		buf = append(buf, part...)
+	}
+	return buf
+}
+
+type wantEntry struct {
+	name string
+	mode uint32
+	size int64
+}
+
+func TestReadDirEntries(t *testing.T) {
+	const (
+		dir  = 0o040755
+		file = 0o100644
+	)
+
+	tests := []struct {
+		name    string
+		v2      bool
+		reply   []byte
+		want    []wantEntry
+		wantErr string
+	}{
+		{
+			name:  "v1",
+			reply: join(dent(".", dir, 0, 0), dent("..", dir, 0, 0), dent("a.txt", file, 5, 1), dent("sub", dir, 4096, 2), syncDone(false)),
+			want:  []wantEntry{{"a.txt", file, 5}, {"sub", dir, 4096}},
+		},
+		{
+			name:  "v1 empty",
+			reply: syncDone(false),
+		},
+		{
+			name:    "v1 fail",
+			reply:   syncFail("permission denied"),
+			wantErr: "permission denied",
+		},
+		{
+			name:    "v1 name too long",
+			reply:   dent(strings.Repeat("x", 4097), file, 0, 0),
+			wantErr: "name too long",
+		},
+		{
+			name:  "v2",
+			v2:    true,
+			reply: join(dnt2(".", 0, dir, 0, 0), dnt2("big.img", 0, file, 5<<30, 1), syncDone(true)),
+			want:  []wantEntry{{"big.img", file, 5 << 30}},
+		},
+		{
+			name:  "v2 errno skipped",
+			v2:    true,
+			reply: join(dnt2("gone", 13, 0, 0, 0), dnt2("ok", 0, file, 1, 0), syncDone(true)),
+			want:  []wantEntry{{"ok", file, 1}},
+		},
+		{
+			name:  "v2 empty",
+			v2:    true,
+			reply: syncDone(true),
+		},
+		{
+			name:    "v2 unexpected",
+			v2:      true,
+			reply:   dent("a", file, 0, 0),
+			wantErr: "DNT2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t)
+			device := server.AddDevice("a")
+			if tt.v2 {
+				device.SetFeatures("ls_v2")
+			} else {
+				device.SetFeatures()
+			}
+
+			wantRequest := "LIST"
+			if tt.v2 {
+				wantRequest = "LIS2"
+			}
+			device.Handle("sync:", func(conn *adbtest.Conn, service string) error {
+				if err := conn.Okay(); err != nil {
+					return err
+				}
+				header := make([]byte, 8)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return err
+				}
+				if id := string(header[:4]); id != wantRequest {
+					t.Errorf("request = %s, want %s", id, wantRequest)
+				}
+				path := make([]byte, binary.LittleEndian.Uint32(header[4:]))
+				if _, err := io.ReadFull(conn, path); err != nil {
+					return err
+				}
+				if _, err := conn.Write(tt.reply); err != nil {
+					return err
+				}
+				_, err := io.Copy(io.Discard, conn)
+				return err
+			})
+
+			entries, err := server.Client().ReadDir("a", "/sdcard")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadDir error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadDir: %v", err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("ReadDir returned %d entries, want %d", len(entries), len(tt.want))
+			}
+			for i, want := range tt.want {
+				entry := entries[i]
+				if entry.Name() != want.name || entry.Mode() != want.mode || entry.Size() != want.size {
+					t.Errorf("entry %d = %s %o %d, want %s %o %d", i, entry.Name(), entry.Mode(), entry.Size(), want.name, want.mode, want.size)
+				}
+			}
+		})
+	}
+}
@@ -33,7 +33,7 @@ func (s *Server) Listen(address string) error {
 	var err error
 	s.server, err = net.Listen("tcp", address)
 	if err != nil {
-		return fmt.Errorf("监听失败: %v", err)
+		return fmt.Errorf("监听失败: %w", err)
 	}
 
 	// 触发监听事件
@@ -88,7 +88,7 @@ func (s *Server) Close() error {
 	if s.server != nil {
 		err := s.server.Close()
 		if err != nil {
-			return fmt.Errorf("关闭服务器失败: %v", err)
+			return fmt.Errorf("关闭服务器失败: %w", err)
 		}
 	}
 
@@ -196,14 +196,12 @@ func (s *Service) readLoop() {
 }
 
 // establishTransport 建立传输连接
-func (s *Service) establishTransport(data []byte) (interface{}, error) {
+func (s *Service) establishTransport(data []byte) (io.ReadWriteCloser, error) {
 	if len(data) < nullByteLen {
 		return nil, fmt.Errorf("empty service name")
 	}
 
-	// 移除末尾的空字节
-	serviceName := string(data[:len(data)-nullByteLen])
-
+	// 服务名称为移除末尾空字节后的数据
 	// 这里需要根据服务名称创建对应的传输接口
 	// 例如: "sync:", "shell:", "reverse:", 等
 
@@ -175,7 +175,7 @@ func (s *Socket) createToken() ([]byte, error) {
 	token := make([]byte, TOKEN_LENGTH)
 	_, err := rand.Read(token)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to generate token: %v", err)
+		return nil, fmt.Errorf("Failed to generate token: %w", err)
 	}
 	return token, nil
 }
@@ -245,14 +245,14 @@ func (s *Service) forwardServicePacket(packet *Packet) error {
 		// 处理写入包
 		if packet.Data != nil {
 			if _, err := s.transport.Write(packet.Data); err != nil {
-				return fmt.Errorf("failed to write data: %v", err)
+				return fmt.Errorf("failed to write data: %w", err)
 			}
 		}
 
 		// 发送确认
 		ack := Assemble(A_OKAY, s.localId, s.remoteId, nil)
 		if _, err := s.socket.Write(ack); err != nil {
-			return fmt.Errorf("failed to send ACK: %v", err)
+			return fmt.Errorf("failed to send ACK: %w", err)
 		}
 
 	case A_CLSE:
@@ -283,7 +283,7 @@ func (s *Service) dataTransferLoop(transport io.ReadWriter) {
 		n, err := transport.Read(buffer)
 		if err != nil {
 			if err != io.EOF {
-				s.emit("error", fmt.Errorf("transport read error: %v", err))
+				s.emit("error", fmt.Errorf("transport read error: %w", err))
 			}
 			return
 		}
@@ -298,7 +298,7 @@ func (s *Service) dataTransferLoop(transport io.ReadWriter) {
 			// 发送数据包
 			packet := Assemble(A_WRTE, s.localId, s.remoteId, buffer[:n])
 			if _, err := s.socket.Write(packet); err != nil {
-				s.emit("error", fmt.Errorf("failed to write packet: %v", err))
+				s.emit("error", fmt.Errorf("failed to write packet: %w", err))
 				s.mu.Unlock()
 				return
 			}
@@ -323,7 +323,7 @@ func readError(r io.Reader) (string, error) {
 	// 读取错误消息长度
 	lenBuf := make([]byte, 4)
 	if _, err := r.Read(lenBuf); err != nil {
-		return "", fmt.Errorf("failed to read error length: %v", err)
+		return "", fmt.Errorf("failed to read error length: %w", err)
 	}
 
 	length := int(lenBuf[0]) | int(lenBuf[1])<<8 | int(lenBuf[2])<<16 | int(lenBuf[3])<<24
@@ -331,7 +331,7 @@ func readError(r io.Reader) (string, error) {
 	// 读取错误消息
 	errBuf := make([]byte, length)
 	if _, err := r.Read(errBuf); err != nil {
-		return "", fmt.Errorf("failed to read error message: %v", err)
+		return "", fmt.Errorf("failed to read error message: %w", err)
 	}
 
 	return string(errBuf), nil
@@ -352,7 +352,7 @@ func (s *Socket) handleOpenPacket(packet *Packet) error {
 	}
 
 	// 创建新服务
-	service := NewService(s.client, s.serial, uint32(localId), remoteId, s)
+	service := NewService(s.client, s.serial, uint32(localId), remoteId, s.conn)
 
 	// 注册错误处理
 	service.On("error", func(data interface{}) {
@@ -368,7 +368,7 @@ func (s *Socket) handleOpenPacket(packet *Packet) error {
 
 	// 将服务添加到服务映射
 	if err := s.services.Insert(uint32(localId), service); err != nil {
-		return fmt.Errorf("failed to insert service: %v", err)
+		return fmt.Errorf("failed to insert service: %w", err)
 	}
 
 	// 处理数据包
@@ -1,16 +1,39 @@
 package adb
 
 import (
+	"context"
+	"strings"
 	"sync"
+	"time"
+
+	"adb-kit-go/pkg/adb/command/host"
+	"adb-kit-go/pkg/adb/retry"
 )
 
 // Tracker 设备跟踪器
+// 设备变化既可以通过Events通道按顺序接收，也可以通过On注册的回调接收。
+// 回调在读取循环中按与事件通道相同的顺序同步调用，不能阻塞，也不能在回调中调用Stop
 type Tracker struct {
-	command    *Command
+	conn       *Connection
 	deviceList []*Device
 	deviceMap  map[string]*Device
 	listeners  map[string][]func(interface{})
+	ended      bool
 	mu         sync.RWMutex
+	synced     chan struct{} // 收到第一份设备列表时关闭
+	syncOnce   sync.Once
+	current    bool             // 设备列表来自当前的连接，连接断开后到重新收到列表之前为false
+	done       chan struct{}    // 跟踪结束时关闭
+	exited     chan struct{}    // 读取循环退出时关闭
+	events     chan DeviceEvent // 第一次调用Events时创建，之前的变化不进入通道
+	runExited  bool             // 读取循环已退出，之后创建的事件通道直接关闭
+	ctx        context.Context  // 跟踪结束时取消，用于中断重连和事件发送
+	cancel     context.CancelFunc
+	stopAfter  func() bool // 取消生命周期上下文的监听
+
+	// reconnect 连接断开后重新建立跟踪连接，为空时连接断开即结束跟踪
+	reconnect func(ctx context.Context) (*Connection, error)
+	backoff   *retry.Policy
 }
 
 // ChangeSet 设备变更集
@@ -20,25 +43,83 @@ type ChangeSet struct {
 	Added   []Device
 }
 
+// DeviceEventType 设备事件类型
+type DeviceEventType int
+
+// 设备事件类型
+const (
+	DeviceAdded        DeviceEventType = iota + 1 // 设备出现
+	DeviceRemoved                                 // 设备消失
+	DeviceStateChanged                            // 设备状态或传输ID变化
+	DeviceReady                                   // 设备启动完成且可以使用，只由ReadinessTracker发送
+)
+
+// String 返回事件类型名称
+func (t DeviceEventType) String() string {
+	switch t {
+	case DeviceAdded:
+		return "added"
+	case DeviceRemoved:
+		return "removed"
+	case DeviceStateChanged:
+		return "state-changed"
+	case DeviceReady:
+		return "ready"
+	}
+	return "unknown"
+}
+
+// DeviceEvent 设备事件
+type DeviceEvent struct {
+	Type     DeviceEventType
+	Device   Device // 变化后的设备，DeviceRemoved时为最后已知的设备
+	OldState string // 仅DeviceStateChanged有效
+}
+
+// 事件通道的最小缓冲大小，接收方处理过慢时读取循环会等待
+const trackerEventBuffer = 64
+
+// trackerMinReconnectDelay 重新连接的最小间隔，避免退避策略的等待时间为0时不停地拨号
+const trackerMinReconnectDelay = 100 * time.Millisecond
+
 // NewTracker 创建新的设备跟踪器
-func NewTracker(command *Command) *Tracker {
+// conn 必须已经成功发送 host:track-devices-l 或 host:track-devices 命令，连接断开时跟踪结束
+func NewTracker(conn *Connection) *Tracker {
+	return newTracker(context.Background(), conn, nil, nil)
+}
+
+// newTracker 创建设备跟踪器，lifetime结束时结束跟踪，
+// reconnect不为空时连接断开后按backoff退避并重新连接
+func newTracker(lifetime context.Context, conn *Connection, reconnect func(ctx context.Context) (*Connection, error), backoff *retry.Policy) *Tracker {
+	ctx, cancel := context.WithCancel(context.Background())
 	t := &Tracker{
-		command:   command,
+		conn:      conn,
 		deviceMap: make(map[string]*Device),
 		listeners: make(map[string][]func(interface{})),
+		synced:    make(chan struct{}),
+		done:      make(chan struct{}),
+		exited:    make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+		reconnect: reconnect,
+		backoff:   backoff,
+	}
+
+	if m := conn.options.Metrics; m != nil {
+		m.AddTrackers(1)
 	}
 
+	// 在启动读取循环之前关联上下文，读取循环看到的跟踪器状态总是完整的
+	t.mu.Lock()
+	t.stopAfter = context.AfterFunc(lifetime, func() { t.End() })
+	t.mu.Unlock()
+
 	// 启动读取循环
-	go t.read()
+	go t.run()
 
 	return t
 }
 
-// Track 开始跟踪设备变化
-func (t *Tracker) Track() error {
-	return t.command.Execute("track-devices")
-}
-
 // On 注册事件监听器
 func (t *Tracker) On(event string, handler func(interface{})) {
 	t.mu.Lock()
@@ -47,22 +128,71 @@ func (t *Tracker) On(event string, handler func(interface{})) {
 	t.listeners[event] = append(t.listeners[event], handler)
 }
 
-// End 结束跟踪
-func (t *Tracker) End() error {
+// Events 返回按发生顺序传递设备事件的通道，跟踪结束后通道关闭
+// 第一次调用时通道中先放入当前列表中每个设备的DeviceAdded事件，之后是相对该列表的变化，
+// 调用之前发生的变化不会补发；重新连接后会根据新的完整列表补发断开期间的变化。
+// 调用Events之后接收方处理过慢时读取循环会等待，设备列表和回调也随之暂停，因此必须持续读取通道
+func (t *Tracker) Events() <-chan DeviceEvent {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	if t.events == nil {
+		// update在同一把锁下读取列表和通道，当前列表与之后的变化正好衔接
+		t.events = make(chan DeviceEvent, max(trackerEventBuffer, len(t.deviceList)))
+		for _, device := range t.deviceList {
+			t.events <- DeviceEvent{Type: DeviceAdded, Device: device.clone()}
+		}
+		if t.runExited {
+			close(t.events)
+		}
+	}
+	return t.events
+}
+
+// End 结束跟踪，不等待读取循环退出
+func (t *Tracker) End() error {
+	t.mu.Lock()
+	if t.ended {
+		t.mu.Unlock()
+		return nil
+	}
+	t.ended = true
+	t.cancel()
+	t.stopAfter()
+	close(t.done)
+	if m := t.conn.options.Metrics; m != nil {
+		m.AddTrackers(-1)
+	}
+
 	// 清理资源
 	t.deviceList = nil
 	t.deviceMap = make(map[string]*Device)
+	conn := t.conn
+	t.mu.Unlock()
+
+	// end事件由读取循环退出时发送
+	return conn.Close()
+}
 
-	// 发送结束事件
-	t.emit("end", nil)
+// Stop 结束跟踪并等待读取循环退出，返回后Events通道已关闭，不会再有任何事件
+func (t *Tracker) Stop() error {
+	err := t.End()
+	<-t.exited
+	return err
+}
+
+// Synced 返回在收到第一份设备列表后关闭的通道，此后GetDevices的结果才有意义
+func (t *Tracker) Synced() <-chan struct{} {
+	return t.synced
+}
 
-	return nil
+// Done 返回在跟踪结束后关闭的通道
+func (t *Tracker) Done() <-chan struct{} {
+	return t.done
 }
 
 // GetDevices 获取当前设备列表
+// 连接断开后到重新连接并收到新的列表之前，返回的是断开前最后一份列表
 func (t *Tracker) GetDevices() []*Device {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -72,55 +202,168 @@ func (t *Tracker) GetDevices() []*Device {
 	return devices
 }
 
-// read 持续读取设备列表
-func (t *Tracker) read() {
+// currentDevices 返回设备列表，列表不是来自当前连接时第二个返回值为false
+func (t *Tracker) currentDevices() ([]*Device, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.current {
+		return nil, false
+	}
+	devices := make([]*Device, len(t.deviceList))
+	copy(devices, t.deviceList)
+	return devices, true
+}
+
+// run 读取设备列表，连接断开时重新连接，直到跟踪结束
+func (t *Tracker) run() {
+	defer close(t.exited)
+	defer t.closeEvents()
+	defer t.emit("end", nil)
+
+	for {
+		t.mu.RLock()
+		conn := t.conn
+		t.mu.RUnlock()
+
+		err := t.read(conn)
+		if t.ctx.Err() != nil {
+			return
+		}
+		t.mu.Lock()
+		t.current = false
+		t.mu.Unlock()
+		t.emit("error", err)
+
+		if t.reconnect == nil || !t.redial() {
+			t.End()
+			return
+		}
+	}
+}
+
+// closeEvents 读取循环退出时关闭事件通道
+func (t *Tracker) closeEvents() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.runExited = true
+	if t.events != nil {
+		close(t.events)
+	}
+}
+
+// read 持续读取设备列表，直到连接关闭或出错
+func (t *Tracker) read(conn *Connection) error {
 	for {
-		devices, err := t.command.GetDevices()
+		value, err := conn.ReadString(0)
+		if err != nil {
+			return err
+		}
+
+		t.update(parseTrackedDevices(value))
+	}
+}
+
+// redial 按退避策略重新建立跟踪连接，跟踪结束时返回false
+func (t *Tracker) redial() bool {
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(max(t.backoff.Backoff(attempt), trackerMinReconnectDelay))
+		select {
+		case <-t.ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		conn, err := t.reconnect(t.ctx)
 		if err != nil {
+			if t.ctx.Err() != nil {
+				return false
+			}
 			t.emit("error", err)
 			continue
 		}
 
-		t.update(devices)
+		t.mu.Lock()
+		if t.ended {
+			t.mu.Unlock()
+			conn.Close()
+			return false
+		}
+		t.conn = conn
+		t.mu.Unlock()
+		t.emit("reconnect", nil)
+		return true
+	}
+}
+
+// parseTrackedDevices 解析 host:track-devices-l 或 host:track-devices 返回的设备列表
+func parseTrackedDevices(value string) []*Device {
+	devices := make([]*Device, 0)
+	for _, line := range strings.Split(value, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		d, err := host.ParseDeviceLong(line)
+		if err != nil {
+			continue
+		}
+		devices = append(devices, newDeviceFromHost(d))
 	}
+	return devices
 }
 
-// update 更新设备列表
+// update 更新设备列表，先发送事件通道上的事件，再按同样的顺序调用回调
+// 跟踪结束时不再等待事件通道的接收方，但回调仍会被调用
 func (t *Tracker) update(newList []*Device) {
 	t.mu.Lock()
-	defer t.mu.Unlock()
 
 	changes := ChangeSet{}
-	newMap := make(map[string]*Device)
-
-	// 检查新增和变更的设备
+	var events []DeviceEvent
+	newMap := make(map[string]*Device, len(newList))
 	for _, device := range newList {
 		newMap[device.ID] = device
+	}
+
+	// 检查移除的设备
+	for _, device := range t.deviceList {
+		if _, exists := newMap[device.ID]; !exists {
+			changes.Removed = append(changes.Removed, *device)
+			events = append(events, DeviceEvent{Type: DeviceRemoved, Device: device.clone()})
+		}
+	}
 
+	// 检查新增和变更的设备
+	for _, device := range newList {
 		if oldDevice, exists := t.deviceMap[device.ID]; exists {
-			// 检查设备状态是否变更
-			if oldDevice.State != device.State {
+			// 检查设备状态是否变更，重新连接后传输ID也会变化
+			if oldDevice.State != device.State || oldDevice.TransportID != device.TransportID {
 				changes.Changed = append(changes.Changed, *device)
-				t.emit("change", device)
+				events = append(events, DeviceEvent{Type: DeviceStateChanged, Device: device.clone(), OldState: oldDevice.State})
 			}
 		} else {
 			// 新增设备
 			changes.Added = append(changes.Added, *device)
-			t.emit("add", device)
-		}
-	}
-
-	// 检查移除的设备
-	for _, device := range t.deviceList {
-		if _, exists := newMap[device.ID]; !exists {
-			changes.Removed = append(changes.Removed, *device)
-			t.emit("remove", device)
+			events = append(events, DeviceEvent{Type: DeviceAdded, Device: device.clone()})
 		}
 	}
 
 	// 更新设备列表和映射
 	t.deviceList = newList
 	t.deviceMap = newMap
+	t.current = true
+	ch := t.events
+	t.mu.Unlock()
+	t.syncOnce.Do(func() { close(t.synced) })
+
+	t.send(ch, events)
+
+	// 在释放锁之后调用回调
+	for _, event := range events {
+		device := event.Device.clone()
+		t.emit(trackerCallbacks[event.Type], &device)
+	}
 
 	// 发送变更集事件
 	if len(changes.Added) > 0 || len(changes.Changed) > 0 || len(changes.Removed) > 0 {
@@ -128,7 +371,28 @@ func (t *Tracker) update(newList []*Device) {
 	}
 }
 
-// emit 发送事件
+// trackerCallbacks 设备事件对应的On事件名
+var trackerCallbacks = map[DeviceEventType]string{
+	DeviceAdded:        "add",
+	DeviceRemoved:      "remove",
+	DeviceStateChanged: "change",
+}
+
+// send 将事件写入事件通道并等待接收方，还没有调用Events时通道为空，直接返回
+func (t *Tracker) send(ch chan DeviceEvent, events []DeviceEvent) {
+	if ch == nil {
+		return
+	}
+	for _, event := range events {
+		select {
+		case ch <- event:
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// emit 按注册顺序同步调用事件的回调
 func (t *Tracker) emit(event string, data interface{}) {
 	t.mu.RLock()
 	handlers := make([]func(interface{}), len(t.listeners[event]))
@@ -136,15 +400,36 @@ func (t *Tracker) emit(event string, data interface{}) {
 	t.mu.RUnlock()
 
 	for _, handler := range handlers {
-		go handler(data)
+		handler(data)
 	}
 }
 
 // Device 设备信息
+// Path、Product、Model、DeviceName和TransportID来自 devices -l，
+// ListDevices、SelectDevices和跟踪器的结果中都有，只支持 devices 的旧服务器上为空
 type Device struct {
-	ID    string
-	State string
-	Props map[string]string
+	ID          string
+	State       string
+	Path        string // USB路径，如 usb:1-1
+	Product     string
+	Model       string
+	DeviceName  string // 设备代号，devices -l 中的device字段
+	TransportID uint64
+	Props       map[string]string // devices -l 中的其他字段，SelectDevices还会加入读取过的系统属性
+}
+
+// newDeviceFromHost 从devices -l的解析结果创建设备
+func newDeviceFromHost(d host.Device) *Device {
+	device := NewDevice(d.ID, d.Type)
+	device.Path = d.Path
+	device.Product = d.Product
+	device.Model = d.Model
+	device.DeviceName = d.DeviceName
+	device.TransportID = d.TransportID
+	for key, value := range d.Extras {
+		device.SetProperty(key, value)
+	}
+	return device
 }
 
 // NewDevice 创建新的设备对象
@@ -166,6 +451,16 @@ func (d *Device) GetProperty(key string) string {
 	return d.Props[key]
 }
 
+// clone 复制设备，Props不与原设备共享
+func (d *Device) clone() Device {
+	c := *d
+	c.Props = make(map[string]string, len(d.Props))
+	for key, value := range d.Props {
+		c.Props[key] = value
+	}
+	return c
+}
+
 // IsOnline 检查设备是否在线
 func (d *Device) IsOnline() bool {
 	return d.State == "device"
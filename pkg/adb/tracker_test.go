@@ -0,0 +1,249 @@
+package adb_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"adb-kit-go/pkg/adb"
+	"adb-kit-go/pkg/adb/adbtest"
+)
+
+// newServer 启动假服务器，测试结束时关闭
+func newServer(t *testing.T) *adbtest.Server {
+	t.Helper()
+	server, err := adbtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+// eventually 在超时之前反复检查条件
+func eventually(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// nextEvent 从事件通道读取下一个事件
+func nextEvent(t *testing.T, events <-chan adb.DeviceEvent) adb.DeviceEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for device event")
+	}
+	return adb.DeviceEvent{}
+}
+
+func TestTrackerEvents(t *testing.T) {
+	server := newServer(t)
+	tracker, err := server.Client().TrackDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Stop()
+	events := tracker.Events()
+
+	// 假服务器会合并推送之间的变化，每一步都等到事件之后再继续
+	steps := []struct {
+		action func()
+		typ    adb.DeviceEventType
+		serial string
+	}{
+		{func() { server.AddDevice("a") }, adb.DeviceAdded, "a"},
+		{func() { server.AddDevice("b") }, adb.DeviceAdded, "b"},
+		{func() { server.Device("a").SetState("offline") }, adb.DeviceStateChanged, "a"},
+		{func() { server.RemoveDevice("b") }, adb.DeviceRemoved, "b"},
+	}
+	for _, step := range steps {
+		step.action()
+		event := nextEvent(t, events)
+		if event.Type != step.typ || event.Device.ID != step.serial {
+			t.Fatalf("event = %v %s, want %v %s", event.Type, event.Device.ID, step.typ, step.serial)
+		}
+	}
+}
+
+// TestTrackerCallbacksWithoutEvents 只使用On时不读取事件通道，跟踪器也不能停止更新
+func TestTrackerCallbacksWithoutEvents(t *testing.T) {
+	server := newServer(t)
+	tracker, err := server.Client().TrackDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Stop()
+
+	var mu sync.Mutex
+	var added []string
+	tracker.On("add", func(data interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		added = append(added, data.(*adb.Device).ID)
+	})
+
+	const count = 100
+	for i := 0; i < count; i++ {
+		server.AddDevice(fmt.Sprintf("d%03d", i))
+	}
+
+	if !eventually(t, 5*time.Second, func() bool { return len(tracker.GetDevices()) == count }) {
+		t.Fatalf("tracker has %d devices, want %d", len(tracker.GetDevices()), count)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(added) != count {
+		t.Fatalf("add callback called %d times, want %d", len(added), count)
+	}
+	for i, serial := range added {
+		if want := fmt.Sprintf("d%03d", i); serial != want {
+			t.Fatalf("add callback %d for %s, want %s", i, serial, want)
+		}
+	}
+}
+
+func TestTrackerReconnect(t *testing.T) {
+	server := newServer(t)
+	server.AddDevice("a")
+	tracker, err := server.Client().TrackDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Stop()
+	events := tracker.Events()
+
+	if event := nextEvent(t, events); event.Type != adb.DeviceAdded || event.Device.ID != "a" {
+		t.Fatalf("event = %v %s, want added a", event.Type, event.Device.ID)
+	}
+
+	// 模拟服务器重启：断开期间的变化在重新连接后补发
+	server.DropConnections()
+	server.RemoveDevice("a")
+	server.AddDevice("b")
+
+	seen := make(map[string]adb.DeviceEventType)
+	for len(seen) < 2 {
+		event := nextEvent(t, events)
+		seen[event.Device.ID] = event.Type
+	}
+	if seen["a"] != adb.DeviceRemoved || seen["b"] != adb.DeviceAdded {
+		t.Fatalf("events after reconnect = %v, want a removed and b added", seen)
+	}
+}
+
+func TestTrackerContext(t *testing.T) {
+	server := newServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker, err := server.Client().TrackDevicesContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := tracker.Events()
+
+	ended := make(chan struct{})
+	tracker.On("end", func(interface{}) { close(ended) })
+	cancel()
+
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("end callback not called after context was cancelled")
+	}
+	for range events {
+	}
+}
+
+// TestTrackerLateSubscriber 订阅之前的变化不进入通道，订阅时先收到当前设备
+func TestTrackerLateSubscriber(t *testing.T) {
+	server := newServer(t)
+	server.AddDevice("a")
+	server.AddDevice("b")
+	tracker, err := server.Client().TrackDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Stop()
+
+	<-tracker.Synced()
+	server.RemoveDevice("b")
+	if !eventually(t, 5*time.Second, func() bool { return len(tracker.GetDevices()) == 1 }) {
+		t.Fatalf("tracker has %d devices, want 1", len(tracker.GetDevices()))
+	}
+
+	events := tracker.Events()
+	if event := nextEvent(t, events); event.Type != adb.DeviceAdded || event.Device.ID != "a" {
+		t.Fatalf("first event = %v %s, want added a", event.Type, event.Device.ID)
+	}
+	server.AddDevice("c")
+	if event := nextEvent(t, events); event.Type != adb.DeviceAdded || event.Device.ID != "c" {
+		t.Fatalf("event = %v %s, want added c", event.Type, event.Device.ID)
+	}
+}
+
+// TestTrackerCallbackOrder 回调与事件通道的顺序一致
+func TestTrackerCallbackOrder(t *testing.T) {
+	server := newServer(t)
+	server.AddDevice("a")
+	server.AddDevice("b")
+	tracker, err := server.Client().TrackDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Stop()
+
+	var mu sync.Mutex
+	var callbacks []string
+	for _, name := range []string{"add", "remove", "change"} {
+		name := name
+		tracker.On(name, func(data interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			callbacks = append(callbacks, name+" "+data.(*adb.Device).ID)
+		})
+	}
+	events := tracker.Events()
+	nextEvent(t, events)
+	nextEvent(t, events)
+
+	// 断开期间的变化在重新连接后的同一份列表中到达
+	mu.Lock()
+	callbacks = nil
+	mu.Unlock()
+	server.DropConnections()
+	server.RemoveDevice("a")
+	server.Device("b").SetState("offline")
+	server.AddDevice("c")
+
+	names := map[adb.DeviceEventType]string{adb.DeviceAdded: "add", adb.DeviceRemoved: "remove", adb.DeviceStateChanged: "change"}
+	var want []string
+	for len(want) < 3 {
+		event := nextEvent(t, events)
+		want = append(want, names[event.Type]+" "+event.Device.ID)
+	}
+	if !eventually(t, 5*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(callbacks) == len(want)
+	}) {
+		t.Fatalf("callbacks = %v, want %v", callbacks, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(callbacks) != fmt.Sprint(want) {
+		t.Fatalf("callbacks = %v, want channel order %v", callbacks, want)
+	}
+}
@@ -0,0 +1,424 @@
+package adb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"adb-kit-go/pkg/adb/command/host"
+	hostserial "adb-kit-go/pkg/adb/command/host-serial"
+	hosttransport "adb-kit-go/pkg/adb/command/host-transport"
+)
+
+// 本文件中的方法在设备传输上执行host-transport和host-serial命令
+// 每个方法都会打开一个新的传输，命令完成或上下文结束时关闭
+
+// Screencap 截取设备屏幕，返回PNG图像数据流
+func (c *Client) Screencap(serial string) (io.ReadCloser, error) {
+	return c.ScreencapContext(context.Background(), serial)
+}
+
+// ScreencapContext 截取设备屏幕，上下文结束或关闭流时断开传输
+// 设备支持exec:时直接读取PNG数据，否则通过shell:读取并还原旧设备转换的CRLF
+func (c *Client) ScreencapContext(ctx context.Context, serial string) (io.ReadCloser, error) {
+	dialect := c.dialect(ctx, serial)
+	return c.openExec(ctx, serial,
+		func(conn *Connection) (io.Reader, error) {
+			return withDialect(hosttransport.NewScreencapCommand(conn.Send, conn.ReadString), dialect).ExecuteRaw()
+		},
+		func(conn *Connection) (io.Reader, error) {
+			return withDialect(hosttransport.NewScreencapCommand(conn.Send, conn.ReadString), dialect).Execute()
+		},
+	)
+}
+
+// FrameBuffer 读取设备帧缓冲区，format为"raw"时返回原始像素数据，否则通过gm转换为指定格式
+func (c *Client) FrameBuffer(serial string, format string) (io.ReadCloser, *hosttransport.FrameBufferMeta, error) {
+	return c.FrameBufferContext(context.Background(), serial, format)
+}
+
+// FrameBufferContext 读取设备帧缓冲区，上下文结束或关闭流时断开传输
+func (c *Client) FrameBufferContext(ctx context.Context, serial string, format string) (io.ReadCloser, *hosttransport.FrameBufferMeta, error) {
+	dialect := c.dialect(ctx, serial)
+	if format == "" {
+		format = "raw"
+	}
+
+	var meta *hosttransport.FrameBufferMeta
+	stream, err := c.openStream(ctx, serial, func(conn *Connection) (io.Reader, error) {
+		reader, m, err := withDialect(hosttransport.NewFrameBufferCommand(conn.Send, conn.ReadString), dialect).Execute(format)
+		meta = m
+		return reader, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stream, meta, nil
+}
+
+// Reboot 重启设备
+func (c *Client) Reboot(serial string) error {
+	return c.RebootContext(context.Background(), serial)
+}
+
+// RebootContext 重启设备
+func (c *Client) RebootContext(ctx context.Context, serial string) error {
+	return c.RebootIntoContext(ctx, serial, hosttransport.RebootNormal)
+}
+
+// RebootInto 重启设备到指定模式
+func (c *Client) RebootInto(serial string, mode hosttransport.RebootMode) error {
+	return c.RebootIntoContext(context.Background(), serial, mode)
+}
+
+// RebootIntoContext 重启设备到指定模式
+func (c *Client) RebootIntoContext(ctx context.Context, serial string, mode hosttransport.RebootMode) error {
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		return hosttransport.NewRebootCommand(conn.Send, conn.ReadString).ExecuteWithMode(mode)
+	})
+}
+
+// Remount 以读写模式重新挂载system分区
+func (c *Client) Remount(serial string) error {
+	return c.RemountContext(context.Background(), serial)
+}
+
+// RemountContext 以读写模式重新挂载system分区
+func (c *Client) RemountContext(ctx context.Context, serial string) error {
+	dialect := c.dialect(ctx, serial)
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		return withDialect(hosttransport.NewRemountCommand(conn.Send, conn.ReadString), dialect).Execute()
+	})
+}
+
+// Root 以root权限重启设备上的adbd
+func (c *Client) Root(serial string) error {
+	return c.RootContext(context.Background(), serial)
+}
+
+// RootContext 以root权限重启设备上的adbd
+func (c *Client) RootContext(ctx context.Context, serial string) error {
+	dialect := c.dialect(ctx, serial)
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		return withDialect(hosttransport.NewRootCommand(conn.Send, conn.ReadString), dialect).Execute()
+	})
+}
+
+// TcpIp 让设备上的adbd在指定TCP端口上监听，返回实际使用的端口
+func (c *Client) TcpIp(serial string, port int) (int, error) {
+	return c.TcpIpContext(context.Background(), serial, port)
+}
+
+// TcpIpContext 让设备上的adbd在指定TCP端口上监听，返回实际使用的端口
+func (c *Client) TcpIpContext(ctx context.Context, serial string, port int) (int, error) {
+	if port == 0 {
+		port = 5555
+	}
+
+	var result int
+	err := c.withTransport(ctx, serial, func(conn *Connection) error {
+		var err error
+		result, err = hosttransport.NewTcpIpCommand(conn.Send, conn.ReadString).Execute(port)
+		return err
+	})
+	return result, err
+}
+
+// Usb 让设备上的adbd切换回USB模式
+func (c *Client) Usb(serial string) error {
+	return c.UsbContext(context.Background(), serial)
+}
+
+// UsbContext 让设备上的adbd切换回USB模式
+func (c *Client) UsbContext(ctx context.Context, serial string) error {
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		_, err := hosttransport.NewUsbCommand(conn.Send, conn.ReadString).Execute()
+		return err
+	})
+}
+
+// StartActivity 启动活动
+func (c *Client) StartActivity(serial string, options *hosttransport.IntentOptions) error {
+	return c.StartActivityContext(context.Background(), serial, options)
+}
+
+// StartActivityContext 启动活动
+func (c *Client) StartActivityContext(ctx context.Context, serial string, options *hosttransport.IntentOptions) error {
+	dialect := c.dialect(ctx, serial)
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		return withDialect(hosttransport.NewStartActivityCommand(conn.Send, conn.ReadString), dialect).Execute(options)
+	})
+}
+
+// StartService 启动服务，options与StartActivity相同
+func (c *Client) StartService(serial string, options *hosttransport.IntentOptions) error {
+	return c.StartServiceContext(context.Background(), serial, options)
+}
+
+// StartServiceContext 启动服务
+func (c *Client) StartServiceContext(ctx context.Context, serial string, options *hosttransport.IntentOptions) error {
+	dialect := c.dialect(ctx, serial)
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		return withDialect(hosttransport.NewStartServiceCommand(conn.Send, conn.ReadString), dialect).Execute(options)
+	})
+}
+
+// GetProperties 获取设备的系统属性
+func (c *Client) GetProperties(serial string) (map[string]string, error) {
+	return c.GetPropertiesContext(context.Background(), serial)
+}
+
+// GetPropertiesContext 获取设备的系统属性
+func (c *Client) GetPropertiesContext(ctx context.Context, serial string) (map[string]string, error) {
+	dialect := c.dialect(ctx, serial)
+	var properties map[string]string
+	err := c.retryTransport(ctx, serial, func(conn *Connection) error {
+		var err error
+		properties, err = withDialect(hosttransport.NewGetPropertiesCommand(conn.Send, conn.ReadString), dialect).Execute()
+		return err
+	})
+	return properties, err
+}
+
+// GetFeatures 获取设备的系统特性（pm list features）
+func (c *Client) GetFeatures(serial string) (hosttransport.SystemFeatures, error) {
+	return c.GetFeaturesContext(context.Background(), serial)
+}
+
+// GetFeaturesContext 获取设备支持的特性
+func (c *Client) GetFeaturesContext(ctx context.Context, serial string) (hosttransport.SystemFeatures, error) {
+	dialect := c.dialect(ctx, serial)
+	var features hosttransport.SystemFeatures
+	err := c.retryTransport(ctx, serial, func(conn *Connection) error {
+		var err error
+		features, err = withDialect(hosttransport.NewGetFeaturesCommand(conn.Send, conn.ReadString), dialect).Execute()
+		return err
+	})
+	return features, err
+}
+
+// GetPackages 获取设备上已安装的包名列表
+func (c *Client) GetPackages(serial string) ([]string, error) {
+	return c.GetPackagesContext(context.Background(), serial)
+}
+
+// GetPackagesContext 获取设备上已安装的包名列表
+func (c *Client) GetPackagesContext(ctx context.Context, serial string) ([]string, error) {
+	dialect := c.dialect(ctx, serial)
+	var packages []string
+	err := c.retryTransport(ctx, serial, func(conn *Connection) error {
+		var err error
+		packages, err = withDialect(hosttransport.NewGetPackagesCommand(conn.Send, conn.ReadString), dialect).Execute()
+		return err
+	})
+	return packages, err
+}
+
+// Clear 清除应用数据
+func (c *Client) Clear(serial string, pkg string) error {
+	return c.ClearContext(context.Background(), serial, pkg)
+}
+
+// ClearContext 清除应用数据
+func (c *Client) ClearContext(ctx context.Context, serial string, pkg string) error {
+	dialect := c.dialect(ctx, serial)
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		_, err := withDialect(hosttransport.NewClearCommand(conn.Send, conn.ReadString), dialect).Execute(pkg)
+		return err
+	})
+}
+
+// IsInstalled 检查包是否已安装
+func (c *Client) IsInstalled(serial string, pkg string) (bool, error) {
+	return c.IsInstalledContext(context.Background(), serial, pkg)
+}
+
+// IsInstalledContext 检查包是否已安装
+func (c *Client) IsInstalledContext(ctx context.Context, serial string, pkg string) (bool, error) {
+	dialect := c.dialect(ctx, serial)
+	var installed bool
+	err := c.retryTransport(ctx, serial, func(conn *Connection) error {
+		var err error
+		installed, err = withDialect(hosttransport.NewIsInstalledCommand(conn.Send, conn.ReadString), dialect).Execute(pkg)
+		return err
+	})
+	return installed, err
+}
+
+// WaitBootComplete 等待设备启动完成
+func (c *Client) WaitBootComplete(serial string) error {
+	return c.WaitBootCompleteContext(context.Background(), serial)
+}
+
+// WaitBootCompleteContext 等待设备启动完成，通常需要通过上下文设置超时
+func (c *Client) WaitBootCompleteContext(ctx context.Context, serial string) error {
+	dialect := c.dialect(ctx, serial)
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		return withDialect(hosttransport.NewWaitBootCompleteCommand(conn.Send, conn.ReadString), dialect).Execute()
+	})
+}
+
+// Reverse 建立从设备到主机的反向端口转发
+func (c *Client) Reverse(serial string, remote string, local string) error {
+	return c.ReverseContext(context.Background(), serial, remote, local)
+}
+
+// ReverseContext 建立从设备到主机的反向端口转发
+func (c *Client) ReverseContext(ctx context.Context, serial string, remote string, local string) error {
+	return c.withTransport(ctx, serial, func(conn *Connection) error {
+		return hosttransport.NewReverseCommand(conn.Send, conn.ReadString).Execute(remote, local)
+	})
+}
+
+// ListReverses 列出设备上的反向端口转发
+func (c *Client) ListReverses(serial string) ([]hosttransport.Reverse, error) {
+	return c.ListReversesContext(context.Background(), serial)
+}
+
+// ListReversesContext 列出设备上的反向端口转发
+func (c *Client) ListReversesContext(ctx context.Context, serial string) ([]hosttransport.Reverse, error) {
+	var reverses []hosttransport.Reverse
+	err := c.retryTransport(ctx, serial, func(conn *Connection) error {
+		var err error
+		reverses, err = hosttransport.NewListReversesCommand(conn.Send, conn.ReadString).Execute()
+		return err
+	})
+	return reverses, err
+}
+
+// OpenLog 打开设备上指定名称的日志流，如main、system、events
+func (c *Client) OpenLog(serial string, name string) (io.ReadCloser, error) {
+	return c.OpenLogContext(context.Background(), serial, name)
+}
+
+// OpenLogContext 打开设备上指定名称的日志流，上下文结束或关闭流时断开传输
+func (c *Client) OpenLogContext(ctx context.Context, serial string, name string) (io.ReadCloser, error) {
+	return c.openStream(ctx, serial, func(conn *Connection) (io.Reader, error) {
+		if _, err := hosttransport.NewLogCommand(conn.Send, conn.ReadString).Execute(name); err != nil {
+			return nil, err
+		}
+		return conn, nil
+	})
+}
+
+// OpenTcp 连接设备上的TCP端口，host为空时连接设备本机
+func (c *Client) OpenTcp(serial string, port int, host string) (io.ReadWriteCloser, error) {
+	return c.OpenTcpContext(context.Background(), serial, port, host)
+}
+
+// OpenTcpContext 连接设备上的TCP端口，上下文结束或关闭连接时断开传输
+func (c *Client) OpenTcpContext(ctx context.Context, serial string, port int, host string) (io.ReadWriteCloser, error) {
+	return c.openStream(ctx, serial, func(conn *Connection) (io.Reader, error) {
+		if _, err := hosttransport.NewTcpCommand(conn.Send, conn.ReadString).Execute(port, host); err != nil {
+			return nil, err
+		}
+		return conn, nil
+	})
+}
+
+// OpenLocal 连接设备上的本地socket，path可以带有localabstract:等前缀，否则视为localfilesystem路径
+func (c *Client) OpenLocal(serial string, path string) (io.ReadWriteCloser, error) {
+	return c.OpenLocalContext(context.Background(), serial, path)
+}
+
+// OpenLocalContext 连接设备上的本地socket，上下文结束或关闭连接时断开传输
+func (c *Client) OpenLocalContext(ctx context.Context, serial string, path string) (io.ReadWriteCloser, error) {
+	service := path
+	if !strings.Contains(path, ":") {
+		service = fmt.Sprintf("localfilesystem:%s", path)
+	}
+
+	return c.openStream(ctx, serial, func(conn *Connection) (io.Reader, error) {
+		if err := conn.Send(service); err != nil {
+			return nil, err
+		}
+		if err := readStatus(conn); err != nil {
+			return nil, err
+		}
+		return conn, nil
+	})
+}
+
+// GetSerialNo 获取设备序列号
+func (c *Client) GetSerialNo(serial string) (string, error) {
+	return c.GetSerialNoContext(context.Background(), serial)
+}
+
+// GetSerialNoContext 获取设备序列号
+func (c *Client) GetSerialNoContext(ctx context.Context, serial string) (string, error) {
+	selector, err := c.selectorOf(ctx, serial)
+	if err != nil {
+		return "", err
+	}
+
+	var result string
+	err = c.retryConnection(ctx, func(conn *Connection) error {
+		var err error
+		result, err = hostserial.NewGetSerialNoCommand(conn.Send, conn.ReadString).ExecuteSelector(selector)
+		return err
+	})
+	return result, err
+}
+
+// GetDevicePath 获取设备路径，如usb:1-1
+func (c *Client) GetDevicePath(serial string) (string, error) {
+	return c.GetDevicePathContext(context.Background(), serial)
+}
+
+// GetDevicePathContext 获取设备路径
+func (c *Client) GetDevicePathContext(ctx context.Context, serial string) (string, error) {
+	selector, err := c.selectorOf(ctx, serial)
+	if err != nil {
+		return "", err
+	}
+
+	var path string
+	err = c.retryConnection(ctx, func(conn *Connection) error {
+		var err error
+		path, err = hostserial.NewGetDevicePathCommand(conn.Send, conn.ReadString).ExecuteSelector(selector)
+		return err
+	})
+	return path, err
+}
+
+// ListForwards 列出设备的端口转发
+func (c *Client) ListForwards(serial string) ([]hostserial.Forward, error) {
+	return c.ListForwardsContext(context.Background(), serial)
+}
+
+// ListForwardsContext 列出设备的端口转发
+func (c *Client) ListForwardsContext(ctx context.Context, serial string) ([]hostserial.Forward, error) {
+	selector, err := c.selectorOf(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+
+	var forwards []hostserial.Forward
+	err = c.retryConnection(ctx, func(conn *Connection) error {
+		var err error
+		forwards, err = hostserial.NewListForwardsCommand(conn.Send, conn.ReadString).ExecuteSelector(selector)
+		return err
+	})
+	return forwards, err
+}
+
+// WaitForDevice 等待设备上线
+func (c *Client) WaitForDevice(serial string) error {
+	return c.WaitForDeviceContext(context.Background(), serial)
+}
+
+// WaitForDeviceContext 等待设备上线，通常需要通过上下文设置超时
+func (c *Client) WaitForDeviceContext(ctx context.Context, serial string) error {
+	// 设备可能尚未连接，不能先解析设备
+	selector := host.SelectSerial(serial)
+	if c.device != nil {
+		selector = c.device.current()
+	}
+
+	return c.withConnection(ctx, func(conn *Connection) error {
+		_, err := hostserial.NewWaitForDeviceCommand(conn.Send, conn.ReadString).ExecuteSelector(selector)
+		return err
+	})
+}
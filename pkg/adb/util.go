@@ -1,6 +1,7 @@
 package adb
 
 import (
+	"crypto/rsa"
 	"fmt"
 	"io"
 	"os"
@@ -22,13 +23,13 @@ func NewUtil() *Util {
 
 // ReadAll 读取所有数据
 func (u *Util) ReadAll(stream io.Reader) ([]byte, error) {
-	parser := NewParser(stream)
+	parser := NewParser(io.NopCloser(stream))
 	return parser.ReadAll()
 }
 
 // ParsePublicKey 解析公钥
-func (u *Util) ParsePublicKey(keyString string) ([]byte, error) {
-	return u.auth.ParsePublicKey(keyString), nil
+func (u *Util) ParsePublicKey(keyString string) (*rsa.PublicKey, error) {
+	return ParsePublicKey([]byte(keyString))
 }
 
 // WriteFile 写入文件